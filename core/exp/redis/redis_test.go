@@ -0,0 +1,26 @@
+package redis
+
+import (
+	"bufio"
+	"slack-wails/lib/structs"
+	"strings"
+	"testing"
+)
+
+func TestExploitPlan(t *testing.T) {
+	// 未确认时不连接目标，只返回将要执行的操作
+	result := Exploit(structs.RedisExpOption{Host: "127.0.0.1:1", Mode: ModeSSHKey, Content: "ssh-ed25519 AAAA"})
+	if !strings.Contains(result, "CONFIG SET dir /root/.ssh") || !strings.Contains(result, "dbfilename authorized_keys") {
+		t.Fatalf("unexpected plan: %s", result)
+	}
+}
+
+func TestReadCommand(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("*3\r\n$8\r\nREPLCONF\r\n$14\r\nlistening-port\r\n$4\r\n6379\r\nPING\r\n"))
+	for _, want := range []string{"REPLCONF listening-port 6379", "PING"} {
+		args, err := readCommand(reader)
+		if err != nil || strings.Join(args, " ") != want {
+			t.Fatalf("got %v: %v", args, err)
+		}
+	}
+}
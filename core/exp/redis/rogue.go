@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 伪造的主节点，从节点发送 PSYNC 或 SYNC 后把模块文件作为 RDB 数据返回
+type rogueMaster struct {
+	listener net.Listener
+	module   []byte
+	synced   chan struct{}
+}
+
+func listenRogueMaster(port int, moduleFile string) (*rogueMaster, error) {
+	module, err := os.ReadFile(moduleFile)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+	m := &rogueMaster{listener: listener, module: module, synced: make(chan struct{})}
+	go m.serve()
+	return m, nil
+}
+
+func (m *rogueMaster) serve() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+		if m.handle(conn) {
+			close(m.synced)
+			return
+		}
+	}
+}
+
+// 处理一个从节点连接，返回是否完成了同步
+func (m *rogueMaster) handle(conn net.Conn) bool {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return false
+		}
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			conn.Write([]byte("+PONG\r\n"))
+		case "PSYNC", "SYNC":
+			fmt.Fprintf(conn, "+FULLRESYNC %s 1\r\n$%d\r\n", strings.Repeat("0", 40), len(m.module))
+			conn.Write(m.module)
+			// 等待从节点读取完数据后再断开
+			time.Sleep(2 * time.Second)
+			return true
+		default:
+			conn.Write([]byte("+OK\r\n"))
+		}
+	}
+}
+
+// Wait 等待同步完成，超时返回 false
+func (m *rogueMaster) Wait(timeout time.Duration) bool {
+	select {
+	case <-m.synced:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (m *rogueMaster) Close() error {
+	return m.listener.Close()
+}
+
+// 读取 RESP 数组格式的命令，兼容 inline 命令
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "*") {
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return args, nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid command: %s", line)
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		header = strings.TrimRight(header, "\r\n")
+		if !strings.HasPrefix(header, "$") {
+			return nil, fmt.Errorf("invalid bulk string: %s", header)
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid bulk string: %s", header)
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
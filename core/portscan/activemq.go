@@ -6,39 +6,17 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
 	"strings"
 	"time"
-
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func ActiveMQScan(ctx, ctrlCtx context.Context, taskId, address string, usernames, passwords []string) {
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[activemq] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", user, -1)
-			flag, err := ActiveMQConn(address, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "activemq weak password",
-					Name:     "activemq weak password",
-					URL:      address,
-					Type:     "ActiveMQ",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("activemq://%s %s:%s is login failed", address, user, pass))
-			}
-		}
-	}
+var activemqModule = &BruteModule{
+	Name: "activemq",
+	Type: "ActiveMQ",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := ActiveMQConn(host, user, pass)
+		return flag, "", err
+	}),
 }
 
 // ActiveMQConn 尝试ActiveMQ连接
@@ -0,0 +1,89 @@
+package portscan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/panjf2000/ants/v2"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// CrackTargets 按协议对端口扫描结果分组，只保留存在暴破模块的协议，同一端口只保留一次
+func CrackTargets(results []structs.InfoResult) map[string][]string {
+	groups := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, result := range results {
+		scheme := crackScheme(result.Scheme)
+		_, brute := bruteModules[scheme]
+		_, scanner := crackScanners[scheme]
+		if !brute && !scanner || result.Host == "" || result.Port == 0 {
+			continue
+		}
+		target := scheme + "://" + net.JoinHostPort(result.Host, strconv.Itoa(result.Port))
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		groups[scheme] = append(groups[scheme], target)
+	}
+	return groups
+}
+
+// BatchRunner 对多个主机并发暴破，workers 为同时暴破的主机数，dict 返回协议对应的用户名和密码字典
+func BatchRunner(ctx, ctrlCtx context.Context, taskId string, results []structs.InfoResult, workers int, dict func(scheme string) ([]string, []string), option structs.BruteOption) {
+	groups := CrackTargets(results)
+	schemes := make([]string, 0, len(groups))
+	total := 0
+	for scheme, targets := range groups {
+		schemes = append(schemes, scheme)
+		total += len(targets)
+	}
+	sort.Strings(schemes)
+	runtime.EventsEmit(ctx, "CrackCounts", total)
+	if total == 0 {
+		gologger.Warning(ctx, "[crack] no target can be cracked")
+		return
+	}
+	for _, scheme := range schemes {
+		gologger.Info(ctx, fmt.Sprintf("[crack] %s: %d targets", scheme, len(groups[scheme])))
+	}
+
+	var id int32
+	var wg sync.WaitGroup
+	keys := newSshKeySet(option)
+	// 域账号在所有主机上的尝试次数累计计算
+	attempts := NewAttemptCounter()
+	threadPool, _ := ants.NewPoolWithFunc(max(workers, 1), func(target interface{}) {
+		defer wg.Done()
+		defer func() {
+			atomic.AddInt32(&id, 1)
+			runtime.EventsEmit(ctx, "CrackProgressID", id)
+		}()
+		if ctrlCtx.Err() != nil {
+			return
+		}
+		host := target.(string)
+		usernames, passwords := dict(strings.SplitN(host, "://", 2)[0])
+		runner(ctx, ctrlCtx, taskId, host, usernames, passwords, option, keys, attempts)
+	})
+	defer threadPool.Release()
+	for _, scheme := range schemes {
+		for _, target := range groups[scheme] {
+			if ctrlCtx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			threadPool.Invoke(target)
+		}
+	}
+	wg.Wait()
+	gologger.Info(ctx, "[crack] batch crack finished")
+}
@@ -0,0 +1,19 @@
+package portscan
+
+import (
+	"slack-wails/lib/structs"
+	"testing"
+)
+
+func TestCrackTargets(t *testing.T) {
+	groups := CrackTargets([]structs.InfoResult{
+		{Scheme: "ssh", Host: "10.0.0.1", Port: 22},
+		{Scheme: "SSH", Host: "10.0.0.1", Port: 22},
+		{Scheme: "ssh", Host: "10.0.0.2", Port: 2222},
+		{Scheme: "redis", Host: "::1", Port: 6379},
+		{Scheme: "http", Host: "10.0.0.1", Port: 80},
+	})
+	if len(groups) != 2 || len(groups["ssh"]) != 2 || groups["redis"][0] != "redis://[::1]:6379" {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+}
@@ -0,0 +1,356 @@
+package portscan
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"net"
+	"slack-wails/core/webscan"
+	"slack-wails/lib/clients"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"slack-wails/lib/util"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// 暴破成功后的停止策略
+const (
+	BruteStopHost = "host" // 主机上任意账号成功后停止，默认策略
+	BruteStopUser = "user" // 账号成功后不再尝试该账号的其他密码
+	BruteStopNone = "none" // 尝试全部组合
+)
+
+// Authenticator 协议认证接口，登录成功时可以返回登录后获取的信息
+type Authenticator interface {
+	Auth(host, user, pass string) (bool, string, error)
+}
+
+// AuthFunc 将普通函数适配为 Authenticator
+type AuthFunc func(host, user, pass string) (bool, string, error)
+
+func (f AuthFunc) Auth(host, user, pass string) (bool, string, error) {
+	return f(host, user, pass)
+}
+
+// BruteModule 描述一种协议的暴破方式，循环、并发控制和结果输出由 BruteForce 统一处理
+type BruteModule struct {
+	Name        string // 协议名称，用于日志和结果名称
+	Kind        string // 登录成功的结果类型，为空时为 weak password
+	Type        string
+	Severity    string   // 为空时为 HIGH
+	Threads     int      // 未指定并发数时使用的默认值
+	DefaultUser string   // 只需要密码的协议使用的固定用户名，结果中只记录密码
+	Request     string   // 登录成功后执行的操作，记录到结果中
+	Passwords   []string // 协议特有的默认口令，如 SNMP 团体字，排在字典之前尝试
+	// 未授权访问检测，存在未授权访问时不再暴破
+	Unauth func(host string) (bool, string)
+	Auth   Authenticator // 为空时只检测未授权访问
+	// 登录成功后收集只读的证据信息，返回执行的操作和结果，为空时使用 Auth 返回的信息
+	Evidence func(host, user, pass string) (string, string)
+}
+
+// AttemptCounter 记录账号的尝试次数，批量暴破时由所有主机共用，
+// 域账号在多台主机上的尝试累计计算，避免对 SMB、RDP、LDAP 等协议喷洒时触发域账号锁定
+type AttemptCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func NewAttemptCounter() *AttemptCounter {
+	return &AttemptCounter{counts: make(map[string]int)}
+}
+
+// Take 未达到上限时增加一次尝试次数并返回 true，limit 小于等于 0 时不限制
+func (c *AttemptCounter) Take(host, user string, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	key := attemptKey(host, user)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts[key] >= limit {
+		return false
+	}
+	c.counts[key]++
+	return true
+}
+
+// 域账号 user@corp.local、CORP\user 和 corp.local\user 统一为小写的 corp\user，在所有主机上共用次数；
+// 本地账号按主机分别计算
+func attemptKey(host, user string) string {
+	user = strings.ToLower(user)
+	domain, name, found := strings.Cut(user, "\\")
+	if !found {
+		name, domain, found = strings.Cut(user, "@")
+	}
+	if !found || name == "" || domain == "" {
+		return host + "|" + user
+	}
+	domain, _, _ = strings.Cut(domain, ".")
+	return domain + "\\" + name
+}
+
+type task struct {
+	User string
+	Pass string
+}
+
+// BruteForce 使用字典对单个主机进行暴破，密码中的 {user} 会替换为用户名。
+// option.Credentials 不为空时只尝试其中的账号密码对，不再组合用户名和密码字典。
+// attempts 为 nil 时只在当前主机内限制账号的尝试次数。存在未授权访问或暴破成功时返回 true
+func BruteForce(ctx, ctrlCtx context.Context, taskId, host string, module *BruteModule, usernames, passwords []string, option structs.BruteOption, attempts *AttemptCounter) bool {
+	if module.Unauth != nil {
+		if ok, response := module.Unauth(host); ok {
+			request := module.Request
+			if !collectEvidence(module, option) {
+				request, response = "", ""
+			}
+			module.emit(ctx, taskId, host, "unauthorized", "", request, response)
+			gologger.Success(ctx, fmt.Sprintf("%s://%s is unauthorized access", module.Name, host))
+			return true
+		}
+		gologger.Info(ctx, fmt.Sprintf("%s://%s is no unauthorized access", module.Name, host))
+	}
+	if module.Auth == nil {
+		return false
+	}
+	if len(module.Passwords) > 0 {
+		passwords = util.RemoveDuplicates(append(append([]string{}, module.Passwords...), passwords...))
+	}
+	if module.DefaultUser != "" {
+		usernames = []string{module.DefaultUser}
+	}
+	threads := option.Threads
+	if threads <= 0 {
+		threads = max(module.Threads, 1)
+	}
+	hostCtx, cancel := context.WithCancel(ctrlCtx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	found := make(map[string]bool)
+	succeeded := func(user string) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return found[user]
+	}
+
+	var wg sync.WaitGroup
+	tasks := make(chan task)
+	for range threads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				if hostCtx.Err() != nil || option.StopOnSuccess == BruteStopUser && succeeded(t.User) {
+					continue
+				}
+				pass := strings.ReplaceAll(t.Pass, "{user}", t.User)
+				ok, response, err := module.Auth.Auth(host, t.User, pass)
+				if ok && err == nil {
+					mutex.Lock()
+					found[t.User] = true
+					mutex.Unlock()
+					extract := t.User + "/" + pass
+					if module.DefaultUser != "" {
+						extract = pass
+					}
+					request := module.Request
+					switch {
+					case !collectEvidence(module, option):
+						request, response = "", ""
+					case module.Evidence != nil:
+						request, response = module.Evidence(host, t.User, pass)
+					}
+					kind := module.Kind
+					if kind == "" {
+						kind = "weak password"
+					}
+					module.emit(ctx, taskId, host, kind, extract, request, response)
+					if stopOnHost(option) {
+						cancel()
+					}
+				} else if module.DefaultUser != "" {
+					gologger.Info(ctx, fmt.Sprintf("%s://%s %s is login failed", module.Name, host, pass))
+				} else {
+					gologger.Info(ctx, fmt.Sprintf("%s://%s %s:%s is login failed", module.Name, host, t.User, pass))
+				}
+				bruteDelay(hostCtx, option)
+			}
+		}()
+	}
+
+	// 单个账号的尝试次数达到上限后跳过，避免触发域账号锁定策略
+	if attempts == nil {
+		attempts = NewAttemptCounter()
+	}
+	send := func(t task) bool {
+		if option.StopOnSuccess == BruteStopUser && succeeded(t.User) ||
+			!attempts.Take(host, t.User, option.MaxAttempts) {
+			return true
+		}
+		select {
+		case tasks <- t:
+			return true
+		case <-hostCtx.Done():
+			return false
+		}
+	}
+	if len(option.Credentials) > 0 {
+		// 账号组合模式只尝试给定的账号密码对
+		for _, t := range parseCredentials(option.Credentials, module.DefaultUser) {
+			if !send(t) {
+				break
+			}
+		}
+	} else {
+	dispatch:
+		for _, user := range usernames {
+			for _, pass := range passwords {
+				if !send(task{User: user, Pass: pass}) {
+					break dispatch
+				}
+			}
+		}
+	}
+	close(tasks)
+	wg.Wait()
+	if ctrlCtx.Err() != nil {
+		gologger.Warning(ctx, fmt.Sprintf("[%s] User exits crack scanning", module.Name))
+	}
+	return len(found) > 0
+}
+
+func stopOnHost(option structs.BruteOption) bool {
+	return option.StopOnSuccess == "" || option.StopOnSuccess == BruteStopHost
+}
+
+// 解析 user:pass 格式的账号组合，以第一个冒号分隔，没有冒号时密码为空。
+// 只需要密码的协议忽略用户名，没有冒号时整行作为密码
+func parseCredentials(lines []string, defaultUser string) []task {
+	var tasks []task
+	seen := make(map[task]bool)
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		user, pass, found := strings.Cut(line, ":")
+		if defaultUser != "" {
+			if !found {
+				pass = user
+			}
+			user = defaultUser
+		}
+		t := task{User: user, Pass: pass}
+		if !seen[t] {
+			seen[t] = true
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// 模块名称与选择协议时使用的名称可能不同，例如 postgres 和 postgresql，比较前统一转换为暴破模块的名称
+func collectEvidence(module *BruteModule, option structs.BruteOption) bool {
+	name := crackScheme(module.Name)
+	for _, skip := range option.SkipEvidence {
+		if crackScheme(skip) == name {
+			return false
+		}
+	}
+	return true
+}
+
+// 每次尝试后等待 Delay 加上随机的 Jitter 毫秒
+func bruteDelay(ctx context.Context, option structs.BruteOption) {
+	delay := time.Duration(option.Delay) * time.Millisecond
+	if option.Jitter > 0 {
+		delay += time.Duration(rand.Intn(option.Jitter)) * time.Millisecond
+	}
+	if delay <= 0 {
+		return
+	}
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+}
+
+func (m *BruteModule) emit(ctx context.Context, taskId, host, kind, extract, request, response string) {
+	severity := m.Severity
+	if severity == "" {
+		severity = "HIGH"
+	}
+	vuln := structs.VulnerabilityInfo{
+		TaskId:   taskId,
+		ID:       m.Name + " " + kind,
+		Name:     m.Name + " " + kind,
+		URL:      host,
+		Type:     m.Type,
+		Severity: severity,
+		Extract:  extract,
+		Request:  request,
+		Response: response,
+		Status:   webscan.FindingStatusNew,
+	}
+	vuln.Fingerprint = webscan.VulnerabilityFingerprint(vuln)
+	runtime.EventsEmit(ctx, "nucleiResult", vuln)
+}
+
+// 连接服务，implicitTLS 为真时直接进行 TLS 握手，用于 smtps、pop3s 等端口
+func dialService(host string, implicitTLS bool, timeout time.Duration) (net.Conn, error) {
+	conn, err := WrapperTcpWithTimeout("tcp", host, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if !implicitTLS {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	if err = tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func portOf(host string) int {
+	_, port, _ := util.SplitHostPort(host)
+	return port
+}
+
+var httpBaseURLs sync.Map
+
+// HttpBaseURL 返回 HTTP 类协议的访问地址，首次访问时识别 http 或 https 并缓存
+func HttpBaseURL(host string) (string, error) {
+	if base, ok := httpBaseURLs.Load(host); ok {
+		return base.(string), nil
+	}
+	base, err := clients.CheckProtocol(host, clients.NewRestyClient(nil, false))
+	if err != nil {
+		return "", err
+	}
+	httpBaseURLs.Store(host, base)
+	return base, nil
+}
+
+// 使用 Basic 认证请求 HTTP 类协议的接口，user 为空时不携带认证信息
+func httpBasicGet(host, path, user, pass string, headers map[string]string) (*resty.Response, error) {
+	base, err := HttpBaseURL(host)
+	if err != nil {
+		return nil, err
+	}
+	req := clients.NewRestyClient(nil, false).R().SetHeaders(headers)
+	if user != "" {
+		req.SetBasicAuth(user, pass)
+	}
+	return req.Get(base + path)
+}
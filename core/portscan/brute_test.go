@@ -0,0 +1,208 @@
+package portscan
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slack-wails/lib/structs"
+	"strings"
+	"testing"
+)
+
+// 启动只处理一个连接的本地服务
+func serveOnce(t *testing.T, handle func(net.Conn)) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}()
+	return ln.Addr().String()
+}
+
+func TestPop3Conn(t *testing.T) {
+	for _, c := range []struct {
+		pass string
+		ok   bool
+	}{{"secret", true}, {"wrong", false}} {
+		host := serveOnce(t, func(conn net.Conn) {
+			reader := bufio.NewReader(conn)
+			conn.Write([]byte("+OK POP3 ready\r\n"))
+			reader.ReadString('\n')
+			conn.Write([]byte("+OK\r\n"))
+			line, _ := reader.ReadString('\n')
+			if strings.TrimSpace(line) == "PASS secret" {
+				conn.Write([]byte("+OK logged in\r\n"))
+				reader.ReadString('\n')
+			} else {
+				conn.Write([]byte("-ERR authentication failed\r\n"))
+			}
+		})
+		if ok, _ := Pop3Conn(host, "admin", c.pass); ok != c.ok {
+			t.Fatalf("pass %s: got %v", c.pass, ok)
+		}
+	}
+}
+
+func TestCassandraConn(t *testing.T) {
+	host := serveOnce(t, func(conn net.Conn) {
+		for _, opcode := range []byte{cqlAuthenticate, cqlAuthSuccess} {
+			header := make([]byte, 9)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			io.CopyN(io.Discard, conn, int64(binary.BigEndian.Uint32(header[5:])))
+			conn.Write([]byte{0x84, 0, 0, 0, opcode, 0, 0, 0, 0})
+		}
+	})
+	if ok, err := CassandraConn(host, "cassandra", "cassandra"); !ok {
+		t.Fatalf("login failed: %v", err)
+	}
+}
+
+func TestRedisCommand(t *testing.T) {
+	host := serveOnce(t, func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		// AUTH 命令为 5 行，CONFIG GET dir 为 7 行
+		for i, reply := range []string{"+OK\r\n", "*2\r\n$3\r\ndir\r\n$14\r\n/var/lib/redis\r\n"} {
+			for n := 0; n < 5+2*i; n++ {
+				reader.ReadString('\n')
+			}
+			conn.Write([]byte(reply))
+		}
+	})
+	result, err := RedisCommand(host, "foobared", "CONFIG GET dir")
+	if err != nil || result != "dir\n/var/lib/redis" {
+		t.Fatalf("unexpected result %q: %v", result, err)
+	}
+}
+
+func TestReadRedisList(t *testing.T) {
+	// SCAN 返回游标和键名组成的嵌套数组，键名中可以包含换行
+	reply := "*2\r\n$1\r\n0\r\n*2\r\n$4\r\nuser\r\n$4\r\na\r\nb\r\n"
+	items, err := readRedisList(bufio.NewReader(strings.NewReader(reply)))
+	if err != nil || len(items) != 3 || items[0] != "0" || items[2] != "a\r\nb" {
+		t.Fatalf("unexpected items %q: %v", items, err)
+	}
+}
+
+func TestParseCredentials(t *testing.T) {
+	lines := []string{"root:toor", "admin:p@ss:word", "guest", "root:toor", ""}
+	got := parseCredentials(lines, "")
+	want := []task{{"root", "toor"}, {"admin", "p@ss:word"}, {"guest", ""}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got[i], want[i])
+		}
+	}
+	// 只需要密码的协议使用固定用户名
+	if got := parseCredentials([]string{"foobared", "default:redis"}, "redis"); got[0].Pass != "foobared" || got[1] != (task{"redis", "redis"}) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestVerifyResult(t *testing.T) {
+	// 认证失败视为漏洞已修复
+	if ok, err := verifyResult(false, errors.New("ssh: unable to authenticate")); ok || !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+	// 连接失败无法得出结论
+	_, err := net.Dial("tcp", "127.0.0.1:1")
+	if err == nil {
+		t.Skip("port 1 is open")
+	}
+	if _, err = verifyResult(false, err); err == nil || errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("dial error should not be treated as authentication failure: %v", err)
+	}
+}
+
+func TestWinrmRequiresChallenge(t *testing.T) {
+	// 不要求认证的 HTTP 服务不能被当作 WinRM 弱口令
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	if ok, err := WinrmConn(server.Listener.Addr().String(), "administrator", "123456"); ok || err == nil {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+}
+
+func TestDatabaseDSN(t *testing.T) {
+	for _, dsn := range []string{postgresDSN("10.0.0.1:5432", "postgres", "p@ss/w:rd#1"), oracleDSN("10.0.0.1:1521", "orcl", "system", "p@ss/w:rd#1")} {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pass, _ := u.User.Password(); pass != "p@ss/w:rd#1" || u.Host != "10.0.0.1:5432" && u.Host != "10.0.0.1:1521" {
+			t.Fatalf("unexpected dsn: %s", dsn)
+		}
+	}
+}
+
+func TestFindingModule(t *testing.T) {
+	// 每个暴破模块产生的结果都可以复测
+	for scheme, module := range bruteModules {
+		if module.Auth != nil {
+			if found, unauth := findingModule(module.Name + " weak password"); found != module || unauth {
+				t.Errorf("%s weak password has no verify module", scheme)
+			}
+		}
+		if module.Unauth != nil {
+			if found, unauth := findingModule(module.Name + " unauthorized"); found != module || !unauth {
+				t.Errorf("%s unauthorized has no verify module", scheme)
+			}
+		}
+	}
+	if found, _ := findingModule("jdwp unauthorized"); found != nil {
+		t.Fatal("jdwp has no brute module")
+	}
+}
+
+func TestCollectEvidence(t *testing.T) {
+	for _, skip := range []string{"postgresql", "postgres", "PostgreSQL"} {
+		if collectEvidence(postgresModule, structs.BruteOption{SkipEvidence: []string{skip}}) {
+			t.Errorf("evidence should be skipped for %s", skip)
+		}
+	}
+	if !collectEvidence(postgresModule, structs.BruteOption{SkipEvidence: []string{"mysql"}}) {
+		t.Error("evidence should be collected")
+	}
+}
+
+func TestAttemptCounter(t *testing.T) {
+	attempts := NewAttemptCounter()
+	// 域账号在不同主机上的尝试累计计算
+	for i, target := range []struct{ host, user string }{
+		{"10.0.0.1:445", `CORP\admin`},
+		{"10.0.0.2:3389", "admin@corp.local"},
+		{"10.0.0.3:389", `corp.local\Admin`},
+	} {
+		if ok := attempts.Take(target.host, target.user, 2); ok != (i < 2) {
+			t.Fatalf("attempt %d on %s: got %v", i, target.host, ok)
+		}
+	}
+	// 本地账号按主机分别计算
+	for _, host := range []string{"10.0.0.1:22", "10.0.0.2:22"} {
+		if !attempts.Take(host, "root", 1) {
+			t.Fatalf("root on %s should be attempted", host)
+		}
+	}
+	if attempts.Take("10.0.0.1:22", "root", 1) {
+		t.Fatal("root on 10.0.0.1:22 exceeded the limit")
+	}
+}
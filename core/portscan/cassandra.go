@@ -0,0 +1,104 @@
+package portscan
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// CQL 原生协议 v4 的操作码
+const (
+	cqlError        = 0x00
+	cqlStartup      = 0x01
+	cqlReady        = 0x02
+	cqlAuthenticate = 0x03
+	cqlAuthResponse = 0x0F
+	cqlAuthSuccess  = 0x10
+)
+
+var cassandraModule = &BruteModule{
+	Name: "cassandra",
+	Type: "Cassandra",
+	Unauth: func(host string) (bool, string) {
+		flag, err := CassandraConn(host, "", "")
+		return flag && err == nil, ""
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := CassandraConn(host, user, pass)
+		return flag, "", err
+	}),
+}
+
+// CassandraConn 发送 STARTUP 后服务端直接返回 READY 表示未开启认证，
+// user 为空时只检测未授权访问，否则使用 SASL PLAIN 认证
+func CassandraConn(host, user, pass string) (bool, error) {
+	conn, err := dialService(host, false, 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	var startup bytes.Buffer
+	binary.Write(&startup, binary.BigEndian, uint16(1))
+	for _, s := range []string{"CQL_VERSION", "3.0.0"} {
+		binary.Write(&startup, binary.BigEndian, uint16(len(s)))
+		startup.WriteString(s)
+	}
+	opcode, body, err := cqlRequest(conn, cqlStartup, startup.Bytes())
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case opcode == cqlReady:
+		return user == "", nil
+	case opcode != cqlAuthenticate:
+		return false, cqlErrorOf(opcode, body)
+	case user == "":
+		return false, errors.New("authentication required")
+	}
+	token := []byte("\x00" + user + "\x00" + pass)
+	response := binary.BigEndian.AppendUint32(nil, uint32(len(token)))
+	opcode, body, err = cqlRequest(conn, cqlAuthResponse, append(response, token...))
+	if err != nil {
+		return false, err
+	}
+	if opcode != cqlAuthSuccess {
+		return false, cqlErrorOf(opcode, body)
+	}
+	return true, nil
+}
+
+func cqlRequest(conn net.Conn, opcode byte, body []byte) (byte, []byte, error) {
+	frame := []byte{0x04, 0x00, 0x00, 0x00, opcode}
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(body)))
+	if _, err := conn.Write(append(frame, body...)); err != nil {
+		return 0, nil, err
+	}
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[5:])
+	if length > 1<<20 {
+		return 0, nil, errors.New("invalid cql frame")
+	}
+	reply := make([]byte, length)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return 0, nil, err
+	}
+	return header[4], reply, nil
+}
+
+// ERROR 消息体为错误码和错误信息
+func cqlErrorOf(opcode byte, body []byte) error {
+	if opcode == cqlError && len(body) >= 6 {
+		n := int(binary.BigEndian.Uint16(body[4:6]))
+		if len(body) >= 6+n {
+			return errors.New(string(body[6 : 6+n]))
+		}
+	}
+	return fmt.Errorf("unexpected cql opcode 0x%02x", opcode)
+}
@@ -0,0 +1,252 @@
+package portscan
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"slack-wails/core/webscan"
+	"slack-wails/lib/clients"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"slack-wails/lib/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/net/proxy"
+)
+
+// GrabCertificate 与 TLS 端口握手获取服务器证书，不校验证书有效性
+func GrabCertificate(ip string, port int, timeout time.Duration, pr clients.Proxy) (*structs.CertInfo, error) {
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	var conn net.Conn
+	var err error
+	if pr.Enabled {
+		// HTTP 代理无法转发任意 TCP 连接
+		if pr.Mode == "HTTP" {
+			return nil, errors.New("http proxy is not supported")
+		}
+		dialer, dErr := proxy.SOCKS5("tcp", net.JoinHostPort(pr.Address, strconv.Itoa(pr.Port)), &proxy.Auth{User: pr.Username, Password: pr.Password}, &net.Dialer{Timeout: timeout})
+		if dErr != nil {
+			return nil, dErr
+		}
+		conn, err = dialer.Dial("tcp", address)
+	} else {
+		conn, err = net.DialTimeout("tcp", address, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: serverNameOf(ip)})
+	if err = tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("no peer certificate")
+	}
+	info := ParseCertificate(certs[0])
+	info.Host, info.Port = ip, port
+	return info, nil
+}
+
+// 获取证书并推送到前端，证书存在问题时输出告警
+func grabCertificate(ctx context.Context, taskId, ip string, port int, timeout time.Duration, pr clients.Proxy) *structs.CertInfo {
+	cert, err := GrabCertificate(ip, port, timeout, pr)
+	if err != nil {
+		gologger.Debug(ctx, fmt.Sprintf("[portscan] %s grab certificate: %v", net.JoinHostPort(ip, strconv.Itoa(port)), err))
+		return nil
+	}
+	cert.TaskId = taskId
+	if len(cert.Issues) > 0 {
+		gologger.Warning(ctx, fmt.Sprintf("[portscan] %s 证书存在问题: %s", net.JoinHostPort(ip, strconv.Itoa(port)), strings.Join(cert.Issues, ", ")))
+	}
+	runtime.EventsEmit(ctx, "tlsCertificate", cert)
+	return cert
+}
+
+// 解析证书中新发现的域名，解析成功的域名作为 https 目标与端口扫描结果一同推送，进入后续的网站扫描；
+// 通配符域名的根域名提示进行子域名枚举
+func feedCertificateDomains(ctx, ctrlCtx context.Context, taskId string, certs []structs.CertInfo, retChan chan<- *structs.InfoResult) {
+	for _, domain := range CertificateDomains(certs) {
+		if ctrlCtx.Err() != nil {
+			return
+		}
+		lookupCtx, cancel := context.WithTimeout(ctrlCtx, 5*time.Second)
+		ips, err := net.DefaultResolver.LookupHost(lookupCtx, domain.Domain)
+		cancel()
+		if err != nil || len(ips) == 0 {
+			gologger.Debug(ctx, fmt.Sprintf("[portscan] 证书域名 %s 解析失败: %v", domain.Domain, err))
+			continue
+		}
+		gologger.Info(ctx, fmt.Sprintf("[portscan] 证书域名 %s 解析到 %s", domain.Domain, strings.Join(ips, ", ")))
+		if domain.Wildcard {
+			gologger.Info(ctx, fmt.Sprintf("[portscan] 证书包含通配符域名 *.%s，可进行子域名枚举", domain.Domain))
+		}
+		for _, target := range domain.Targets {
+			host, port, err := util.SplitHostPort(strings.TrimPrefix(target, "https://"))
+			if err != nil {
+				host, port = domain.Domain, 443
+			}
+			result := &structs.InfoResult{
+				TaskId: taskId,
+				URL:    target,
+				Scheme: "https",
+				Host:   host,
+				Port:   port,
+				Detect: "Certificate",
+				TLS:    true,
+			}
+			if resp, err := clients.SimpleGet(target, clients.NewRestyClient(nil, true)); err == nil {
+				result.StatusCode = resp.StatusCode()
+			}
+			retChan <- result
+		}
+	}
+}
+
+// 目标为域名时携带 SNI，避免拿到默认证书
+func serverNameOf(host string) string {
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	return host
+}
+
+// ParseCertificate 提取证书信息，并标记过期、自签名以及弱密钥、弱签名算法等问题
+func ParseCertificate(cert *x509.Certificate) *structs.CertInfo {
+	sum := sha256.Sum256(cert.Raw)
+	info := &structs.CertInfo{
+		SubjectCN:          cert.Subject.CommonName,
+		SubjectDN:          webscan.ParseASN1DNSequenceWithZpkixOrDefault(cert.RawSubject, cert.Subject.String()),
+		IssuerCN:           cert.Issuer.CommonName,
+		IssuerDN:           webscan.ParseASN1DNSequenceWithZpkixOrDefault(cert.RawIssuer, cert.Issuer.String()),
+		SANs:               cert.DNSNames,
+		NotBefore:          cert.NotBefore.Format("2006-01-02 15:04:05"),
+		NotAfter:           cert.NotAfter.Format("2006-01-02 15:04:05"),
+		SHA256:             hex.EncodeToString(sum[:]),
+		SerialNumber:       cert.SerialNumber.Text(16),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		KeyAlgorithm:       cert.PublicKeyAlgorithm.String(),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	info.Domains = certDomains(cert)
+
+	now := time.Now()
+	if now.After(cert.NotAfter) {
+		info.Expired = true
+		info.Issues = append(info.Issues, "证书已过期")
+	} else if now.Before(cert.NotBefore) {
+		info.Expired = true
+		info.Issues = append(info.Issues, "证书尚未生效")
+	}
+	if bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil {
+		info.SelfSigned = true
+		info.Issues = append(info.Issues, "自签名证书")
+	}
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.KeyBits = key.N.BitLen()
+		if info.KeyBits < 2048 {
+			info.Weak = true
+			info.Issues = append(info.Issues, fmt.Sprintf("RSA 密钥长度过短(%d)", info.KeyBits))
+		}
+	case *ecdsa.PublicKey:
+		info.KeyBits = key.Curve.Params().BitSize
+		if info.KeyBits < 224 {
+			info.Weak = true
+			info.Issues = append(info.Issues, fmt.Sprintf("ECDSA 密钥长度过短(%d)", info.KeyBits))
+		}
+	case ed25519.PublicKey:
+		info.KeyBits = 256
+	}
+	switch cert.SignatureAlgorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		info.Weak = true
+		info.Issues = append(info.Issues, "弱签名算法 "+cert.SignatureAlgorithm.String())
+	}
+	return info
+}
+
+// 证书 CN 和 SAN 中的域名，通配符域名保留 *. 前缀，IP 地址不计入
+func certDomains(cert *x509.Certificate) []string {
+	seen := make(map[string]bool)
+	var domains []string
+	for _, name := range append([]string{cert.Subject.CommonName}, cert.DNSNames...) {
+		name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+		if !isCertDomain(name) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		domains = append(domains, name)
+	}
+	return domains
+}
+
+func isCertDomain(name string) bool {
+	name = strings.TrimPrefix(name, "*.")
+	if !strings.Contains(name, ".") || net.ParseIP(name) != nil {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// CertificateDomains 汇总证书中的域名作为新的扫描目标，
+// 通配符域名的根域名用于子域名枚举，其余域名生成网站扫描的 URL
+func CertificateDomains(certs []structs.CertInfo) []structs.CertDomain {
+	index := make(map[string]*structs.CertDomain)
+	var order []string
+	for _, cert := range certs {
+		for _, name := range cert.Domains {
+			wildcard := strings.HasPrefix(name, "*.")
+			domain := strings.TrimPrefix(name, "*.")
+			d, ok := index[domain]
+			if !ok {
+				d = &structs.CertDomain{Domain: domain}
+				index[domain] = d
+				order = append(order, domain)
+			}
+			d.Wildcard = d.Wildcard || wildcard
+			hostPort := net.JoinHostPort(cert.Host, strconv.Itoa(cert.Port))
+			if !util.ArrayContains(hostPort, d.Hosts) {
+				d.Hosts = append(d.Hosts, hostPort)
+			}
+			if wildcard {
+				continue
+			}
+			target := "https://" + domain
+			if cert.Port != 443 {
+				target += ":" + strconv.Itoa(cert.Port)
+			}
+			if !util.ArrayContains(target, d.Targets) {
+				d.Targets = append(d.Targets, target)
+			}
+		}
+	}
+	sort.Strings(order)
+	result := make([]structs.CertDomain, 0, len(order))
+	for _, domain := range order {
+		result = append(result, *index[domain])
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package portscan
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"slack-wails/lib/structs"
+	"testing"
+	"time"
+)
+
+func TestParseCertificate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com", "*.example.org", "localhost"},
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := ParseCertificate(cert)
+	if !info.Expired || !info.SelfSigned || !info.Weak || info.KeyBits != 1024 {
+		t.Fatalf("unexpected flags: %+v", info)
+	}
+	if len(info.Domains) != 2 || info.Domains[0] != "www.example.com" || info.Domains[1] != "*.example.org" {
+		t.Fatalf("unexpected domains: %v", info.Domains)
+	}
+}
+
+func TestCertificateDomains(t *testing.T) {
+	certs := []structs.CertInfo{
+		{Host: "10.0.0.1", Port: 443, Domains: []string{"www.example.com", "*.example.org"}},
+		{Host: "10.0.0.2", Port: 8443, Domains: []string{"www.example.com"}},
+	}
+	domains := CertificateDomains(certs)
+	if len(domains) != 2 {
+		t.Fatalf("unexpected domains: %+v", domains)
+	}
+	if d := domains[0]; d.Domain != "example.org" || !d.Wildcard || len(d.Targets) != 0 {
+		t.Fatalf("unexpected wildcard domain: %+v", d)
+	}
+	if d := domains[1]; d.Domain != "www.example.com" || len(d.Hosts) != 2 || len(d.Targets) != 2 || d.Targets[1] != "https://www.example.com:8443" {
+		t.Fatalf("unexpected domain: %+v", d)
+	}
+}
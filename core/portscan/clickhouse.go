@@ -0,0 +1,33 @@
+package portscan
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var clickhouseModule = &BruteModule{
+	Name:    "clickhouse",
+	Type:    "ClickHouse",
+	Request: "SELECT version()",
+	Unauth: func(host string) (bool, string) {
+		version, err := ClickhouseConn(host, "", "")
+		return err == nil, version
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		version, err := ClickhouseConn(host, user, pass)
+		return err == nil, version, err
+	}),
+}
+
+// ClickhouseConn 通过 HTTP 接口执行查询，user 为空时使用服务端的 default 用户
+func ClickhouseConn(host, user, pass string) (string, error) {
+	resp, err := httpBasicGet(host, "/?query=SELECT%20version()", user, pass, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return "", fmt.Errorf("status code %d", resp.StatusCode())
+	}
+	return strings.TrimSpace(string(resp.Body())), nil
+}
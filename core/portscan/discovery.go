@@ -0,0 +1,336 @@
+package portscan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 主机存活探测方式
+const (
+	DiscoveryICMP = "icmp"
+	DiscoveryPing = "ping"
+	DiscoveryTCP  = "tcp"
+	DiscoveryARP  = "arp"
+	DiscoveryUDP  = "udp"
+)
+
+var (
+	defaultDiscoveryTCPPorts = []int{80, 443, 22, 445, 3389, 135, 139, 8080}
+	defaultDiscoveryUDPPorts = []int{53, 123, 137, 161, 40125}
+)
+
+// HostDiscovery 主机存活探测，每次探测的状态独立，可以并发使用多个实例
+type HostDiscovery struct {
+	Methods  []string      // 按顺序使用的探测方式，已存活的主机不会被后续方式重复探测
+	TCPPorts []int         // TCP ping 使用的端口
+	UDPPorts []int         // UDP ping 使用的端口
+	Timeout  time.Duration // 单次探测的超时时间
+	Threads  int
+
+	mutex sync.Mutex
+	alive map[string]structs.AliveHost
+	order []string
+}
+
+func NewHostDiscovery(methods ...string) *HostDiscovery {
+	if len(methods) == 0 {
+		methods = []string{DiscoveryICMP}
+	}
+	return &HostDiscovery{
+		Methods:  methods,
+		TCPPorts: defaultDiscoveryTCPPorts,
+		UDPPorts: defaultDiscoveryUDPPorts,
+		Timeout:  3 * time.Second,
+		Threads:  100,
+	}
+}
+
+// Run 返回存活主机及其探测方式与 RTT，顺序与发现顺序一致
+func (d *HostDiscovery) Run(ctx, ctrlCtx context.Context, hosts []string) []structs.AliveHost {
+	d.mutex.Lock()
+	d.alive = make(map[string]structs.AliveHost)
+	d.order = nil
+	d.mutex.Unlock()
+	for _, method := range d.Methods {
+		pending := d.pending(hosts)
+		if len(pending) == 0 || ctrlCtx.Err() != nil {
+			break
+		}
+		switch method {
+		case DiscoveryICMP:
+			d.runICMP(ctx, ctrlCtx, pending)
+		case DiscoveryPing:
+			d.runPing(ctx, ctrlCtx, pending)
+		case DiscoveryTCP:
+			d.runTCP(ctx, ctrlCtx, pending)
+		case DiscoveryARP:
+			d.runARP(ctx, ctrlCtx, pending)
+		case DiscoveryUDP:
+			d.runUDP(ctx, ctrlCtx, pending)
+		default:
+			gologger.Warning(ctx, fmt.Sprintf("[discovery] unknown method: %s", method))
+		}
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	results := make([]structs.AliveHost, 0, len(d.order))
+	for _, host := range d.order {
+		results = append(results, d.alive[host])
+	}
+	return results
+}
+
+func (d *HostDiscovery) pending(hosts []string) []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	var pending []string
+	for _, host := range hosts {
+		if _, ok := d.alive[host]; !ok {
+			pending = append(pending, host)
+		}
+	}
+	return pending
+}
+
+// 记录存活主机，只保留首次发现的方式
+func (d *HostDiscovery) markAlive(ctx context.Context, host, method string, rtt time.Duration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if _, ok := d.alive[host]; ok {
+		return
+	}
+	d.alive[host] = structs.AliveHost{
+		Host:   host,
+		Method: method,
+		RTT:    float64(rtt.Microseconds()) / 1000,
+	}
+	d.order = append(d.order, host)
+	gologger.Info(ctx, fmt.Sprintf("%s is alive! [%s]", host, method))
+}
+
+// 并发对每个主机执行探测
+func (d *HostDiscovery) parallel(ctrlCtx context.Context, hosts []string, threads int, probe func(host string)) {
+	var wg sync.WaitGroup
+	limiter := make(chan struct{}, max(threads, 1))
+	for _, host := range hosts {
+		if ctrlCtx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(host string) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			probe(host)
+		}(host)
+	}
+	wg.Wait()
+}
+
+// 具备 root 权限时发送原始 SYN/ACK 包，收到 SYN/ACK 或 RST 均说明主机存活；
+// 否则使用 TCP 连接，连接成功或被拒绝均说明主机存活
+func (d *HostDiscovery) runTCP(ctx, ctrlCtx context.Context, hosts []string) {
+	if SynAvailable() {
+		if conn, err := net.ListenPacket("ip4:tcp", "0.0.0.0"); err == nil {
+			// 原始套接字仅支持 IPv4，其余主机使用 TCP 连接探测
+			var ipv4Hosts, others []string
+			for _, host := range hosts {
+				if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+					ipv4Hosts = append(ipv4Hosts, host)
+				} else {
+					others = append(others, host)
+				}
+			}
+			d.runRawTCP(ctx, ctrlCtx, conn, ipv4Hosts)
+			hosts = others
+		}
+	}
+	d.parallel(ctrlCtx, hosts, d.Threads, func(host string) {
+		for _, port := range d.TCPPorts {
+			start := time.Now()
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), d.Timeout)
+			if err == nil {
+				conn.Close()
+			}
+			if err == nil || isConnRefused(err) {
+				d.markAlive(ctx, host, DiscoveryTCP, time.Since(start))
+				return
+			}
+		}
+	})
+}
+
+func (d *HostDiscovery) runRawTCP(ctx, ctrlCtx context.Context, conn net.PacketConn, hosts []string) {
+	defer conn.Close()
+	srcPort := uint16(40000 + rand.Intn(20000))
+	var sent sync.Map
+	done := make(chan struct{})
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil || n < 20 || binary.BigEndian.Uint16(buf[2:4]) != srcPort {
+				continue
+			}
+			host := from.(*net.IPAddr).IP.String()
+			if start, ok := sent.Load(host); ok {
+				d.markAlive(ctx, host, DiscoveryTCP, time.Since(start.(time.Time)))
+			}
+		}
+	}()
+	var srcIPs sync.Map
+	for _, host := range hosts {
+		if ctrlCtx.Err() != nil {
+			break
+		}
+		dst := net.ParseIP(host).To4()
+		src, err := sourceIP(&srcIPs, host)
+		if dst == nil || err != nil {
+			continue
+		}
+		sent.Store(host, time.Now())
+		for _, port := range d.TCPPorts {
+			conn.WriteTo(buildTCPPacket(src, dst, srcPort, uint16(port), rand.Uint32(), 0, tcpFlagSyn), &net.IPAddr{IP: dst})
+		}
+		// ACK ping 可以穿过只拦截 SYN 的无状态防火墙
+		conn.WriteTo(buildTCPPacket(src, dst, srcPort, 80, rand.Uint32(), rand.Uint32(), tcpFlagAck), &net.IPAddr{IP: dst})
+	}
+	d.wait(ctrlCtx, hosts)
+	close(done)
+}
+
+// UDP ping 收到任何响应或 ICMP 端口不可达均说明主机存活
+func (d *HostDiscovery) runUDP(ctx, ctrlCtx context.Context, hosts []string) {
+	d.parallel(ctrlCtx, hosts, d.Threads, func(host string) {
+		for _, port := range d.UDPPorts {
+			start := time.Now()
+			conn, err := net.DialTimeout("udp", net.JoinHostPort(host, strconv.Itoa(port)), d.Timeout)
+			if err != nil {
+				continue
+			}
+			conn.SetDeadline(time.Now().Add(d.Timeout))
+			conn.Write([]byte{0})
+			_, err = conn.Read(make([]byte, 512))
+			conn.Close()
+			if err == nil || isConnRefused(err) || strings.Contains(err.Error(), "reset") {
+				d.markAlive(ctx, host, DiscoveryUDP, time.Since(start))
+				return
+			}
+		}
+	})
+}
+
+// ARP 探测仅对本地直连网段有效：先发送 UDP 数据包触发系统的 ARP 解析，再读取 ARP 缓存表
+func (d *HostDiscovery) runARP(ctx, ctrlCtx context.Context, hosts []string) {
+	networks := localNetworks()
+	var local []string
+	for _, host := range hosts {
+		ip := net.ParseIP(host)
+		for _, n := range networks {
+			if ip != nil && n.Contains(ip) {
+				local = append(local, host)
+				break
+			}
+		}
+	}
+	if len(local) == 0 {
+		return
+	}
+	start := time.Now()
+	d.parallel(ctrlCtx, local, d.Threads, func(host string) {
+		if conn, err := net.Dial("udp", net.JoinHostPort(host, "9")); err == nil {
+			conn.Write([]byte{0})
+			conn.Close()
+		}
+	})
+	time.Sleep(time.Second)
+	table := arpTable()
+	for _, host := range local {
+		if _, ok := table[host]; ok {
+			d.markAlive(ctx, host, DiscoveryARP, time.Since(start))
+		}
+	}
+}
+
+// 等待原始套接字的响应，所有主机存活或超时后返回
+func (d *HostDiscovery) wait(ctrlCtx context.Context, hosts []string) {
+	wait := d.Timeout
+	if len(hosts) > 256 {
+		wait *= 2
+	}
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) && ctrlCtx.Err() == nil {
+		if len(d.pending(hosts)) == 0 {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func localNetworks() []*net.IPNet {
+	var networks []*net.IPNet
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if n, ok := addr.(*net.IPNet); ok && !n.IP.IsLoopback() && n.IP.To4() != nil {
+			networks = append(networks, n)
+		}
+	}
+	return networks
+}
+
+var arpLineRegexp = regexp.MustCompile(`(\d+\.\d+\.\d+\.\d+)\D.*?(([0-9a-fA-F]{1,2}[:-]){5}[0-9a-fA-F]{1,2})`)
+
+// 读取系统 ARP 缓存，返回 IP 与 MAC 的对应关系
+func arpTable() map[string]string {
+	var output []byte
+	if runtime.GOOS == "linux" {
+		output, _ = os.ReadFile("/proc/net/arp")
+	} else {
+		output, _ = exec.Command("arp", "-a").Output()
+	}
+	return parseArpTable(output)
+}
+
+// 兼容 /proc/net/arp 以及 Windows、macOS 下 arp -a 的输出格式
+func parseArpTable(output []byte) map[string]string {
+	table := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		matches := arpLineRegexp.FindStringSubmatch(scanner.Text())
+		if len(matches) < 3 {
+			continue
+		}
+		mac := strings.ToLower(strings.ReplaceAll(matches[2], "-", ":"))
+		if mac == "00:00:00:00:00:00" || mac == "ff:ff:ff:ff:ff:ff" {
+			continue
+		}
+		table[matches[1]] = mac
+	}
+	return table
+}
@@ -0,0 +1,26 @@
+package portscan
+
+import "testing"
+
+func TestParseArpTable(t *testing.T) {
+	output := []byte(`IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:01     *        eth0
+192.168.1.9      0x1         0x0         00:00:00:00:00:00     *        eth0
+  192.168.1.20          aa-bb-cc-dd-ee-02     动态
+? (192.168.1.30) at aa:bb:cc:dd:ee:3 on en0 ifscope [ethernet]
+`)
+	table := parseArpTable(output)
+	expect := map[string]string{
+		"192.168.1.1":  "aa:bb:cc:dd:ee:01",
+		"192.168.1.20": "aa:bb:cc:dd:ee:02",
+		"192.168.1.30": "aa:bb:cc:dd:ee:3",
+	}
+	if len(table) != len(expect) {
+		t.Fatalf("unexpected table: %v", table)
+	}
+	for ip, mac := range expect {
+		if table[ip] != mac {
+			t.Errorf("%s: got %q, want %q", ip, table[ip], mac)
+		}
+	}
+}
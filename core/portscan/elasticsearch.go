@@ -0,0 +1,59 @@
+package portscan
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var elasticsearchModule = &BruteModule{
+	Name: "elasticsearch",
+	Type: "Elasticsearch",
+	Unauth: func(host string) (bool, string) {
+		body, err := ElasticsearchConn(host, "", "")
+		return err == nil, body
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		body, err := ElasticsearchConn(host, user, pass)
+		return err == nil, body, err
+	}),
+}
+
+var kibanaModule = &BruteModule{
+	Name: "kibana",
+	Type: "Kibana",
+	Unauth: func(host string) (bool, string) {
+		body, err := KibanaConn(host, "", "")
+		return err == nil, body
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		body, err := KibanaConn(host, user, pass)
+		return err == nil, body, err
+	}),
+}
+
+// ElasticsearchConn 访问根路径获取集群信息，开启认证时未携带或携带错误的账号会返回 401
+func ElasticsearchConn(host, user, pass string) (string, error) {
+	resp, err := httpBasicGet(host, "/", user, pass, nil)
+	if err != nil {
+		return "", err
+	}
+	body := string(resp.Body())
+	if resp.StatusCode() != http.StatusOK || !strings.Contains(body, "cluster_name") {
+		return "", fmt.Errorf("status code %d", resp.StatusCode())
+	}
+	return body, nil
+}
+
+// KibanaConn 访问状态接口，开启 X-Pack 认证时返回 401
+func KibanaConn(host, user, pass string) (string, error) {
+	resp, err := httpBasicGet(host, "/api/status", user, pass, map[string]string{"kbn-xsrf": "true"})
+	if err != nil {
+		return "", err
+	}
+	body := string(resp.Body())
+	if resp.StatusCode() != http.StatusOK || !strings.Contains(body, "version") {
+		return "", fmt.Errorf("status code %d", resp.StatusCode())
+	}
+	return body, nil
+}
@@ -0,0 +1,77 @@
+package portscan
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 证据中每条查询最多保留的行数
+const evidenceMaxRows = 50
+
+// 执行只读查询收集数据库证据，返回执行的语句和查询结果
+func sqlEvidence(driver, dsn string, queries ...string) (string, string) {
+	request := strings.Join(queries, "\n")
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return request, err.Error()
+	}
+	defer db.Close()
+	db.SetConnMaxLifetime(10 * time.Second)
+	var response strings.Builder
+	for _, query := range queries {
+		fmt.Fprintf(&response, "> %s\n", query)
+		rows, err := queryRows(db, query)
+		if err != nil {
+			fmt.Fprintf(&response, "%v\n", err)
+			continue
+		}
+		for _, row := range rows {
+			response.WriteString(strings.Join(row, " | ") + "\n")
+		}
+	}
+	return request, strings.TrimSpace(response.String())
+}
+
+func queryRows(db *sql.DB, query string) ([][]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result [][]string
+	for rows.Next() && len(result) < evidenceMaxRows {
+		values := make([]sql.RawBytes, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return result, err
+		}
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = strings.TrimSpace(string(v))
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// 依次执行命令，输出格式与终端一致
+func commandEvidence(run func(command string) (string, error), commands ...string) (string, string) {
+	var response strings.Builder
+	for _, command := range commands {
+		output, err := run(command)
+		if err != nil {
+			output = err.Error()
+		}
+		fmt.Fprintf(&response, "$ %s\n%s\n", command, strings.TrimSpace(output))
+	}
+	return "[Command] " + strings.Join(commands, "; "), strings.TrimSpace(response.String())
+}
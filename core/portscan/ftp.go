@@ -1,57 +1,24 @@
 package portscan
 
 import (
-	"context"
-	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
 	"strings"
 	"time"
 
 	"github.com/jlaffaye/ftp"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func FtpScan(ctx, ctrlCtx context.Context, taskId, address string, usernames, passwords []string) {
-	flag, directories, err := FtpConn(address, "anonymous", "")
-	if flag && err == nil {
-		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-			TaskId:   taskId,
-			ID:       "ftp unauthorized",
-			Name:     "ftp unauthorized",
-			URL:      address,
-			Type:     "FTP",
-			Severity: "HIGH",
-			Response: strings.Join(directories, "\n"),
-		})
-		return
-	} else {
-		gologger.Info(ctx, fmt.Sprintf("ftp://%s is no unauthorized access", address))
-	}
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[ftp] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", user, -1)
-			flag, directories, err := FtpConn(address, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					ID:       "ftp weak password",
-					Name:     "ftp weak password",
-					URL:      address,
-					Type:     "ftp",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-					Response: strings.Join(directories, "\n"),
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("ftp://%s %s:%s is login failed", address, user, pass))
-			}
-		}
-	}
+var ftpModule = &BruteModule{
+	Name:    "ftp",
+	Type:    "FTP",
+	Request: "LIST /",
+	Unauth: func(host string) (bool, string) {
+		flag, directories, err := FtpConn(host, "anonymous", "")
+		return flag && err == nil, strings.Join(directories, "\n")
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, directories, err := FtpConn(host, user, pass)
+		return flag, strings.Join(directories, "\n"), err
+	}),
 }
 
 func FtpConn(address, user, pass string) (flag bool, directories []string, err error) {
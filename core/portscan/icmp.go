@@ -8,7 +8,6 @@ import (
 	"os/exec"
 	"runtime"
 	"slack-wails/lib/gologger"
-	"slack-wails/lib/util"
 
 	"strings"
 	"sync"
@@ -17,166 +16,147 @@ import (
 	"golang.org/x/net/icmp"
 )
 
-var (
-	AliveHosts []string
-	ExistHosts = make(map[string]struct{})
-	livewg     sync.WaitGroup
-)
-
+// CheckLive 使用 ICMP 或系统 ping 命令探测存活主机
 func CheckLive(ctx context.Context, hostslist []string, Ping bool) []string {
-	chanHosts := make(chan string, len(hostslist))
-	go func() {
-		for ip := range chanHosts {
-			if _, ok := ExistHosts[ip]; !ok && util.ArrayContains(ip, hostslist) {
-				ExistHosts[ip] = struct{}{}
-				gologger.Info(ctx, ip+" is alive!")
-				AliveHosts = append(AliveHosts, ip)
-			}
-			livewg.Done()
-		}
-	}()
-
+	method := DiscoveryICMP
 	if Ping {
-		//使用ping探测
-		RunPing(hostslist, chanHosts)
-	} else {
-		//优先尝试监听本地icmp,批量探测
-		conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
-		if err == nil {
-			RunIcmp1(hostslist, conn, chanHosts)
+		method = DiscoveryPing
+	}
+	var alive []string
+	for _, host := range NewHostDiscovery(method).Run(ctx, context.Background(), hostslist) {
+		alive = append(alive, host.Host)
+	}
+	return alive
+}
+
+func (d *HostDiscovery) runICMP(ctx, ctrlCtx context.Context, hosts []string) {
+	var ipv4Hosts, ipv6Hosts []string
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			ipv6Hosts = append(ipv6Hosts, host)
 		} else {
-			//尝试无监听icmp探测
-			gologger.Info(ctx, "trying RunIcmp2")
-			conn, err := net.DialTimeout("ip4:icmp", "127.0.0.1", 3*time.Second)
-			defer func() {
-				if conn != nil {
-					conn.Close()
-				}
-			}()
-			if err == nil {
-				RunIcmp2(hostslist, chanHosts)
-			} else {
-				//使用ping探测
-				RunPing(hostslist, chanHosts)
-			}
+			ipv4Hosts = append(ipv4Hosts, host)
 		}
 	}
+	if len(ipv4Hosts) > 0 {
+		d.runICMPv4(ctx, ctrlCtx, ipv4Hosts)
+	}
+	if len(ipv6Hosts) > 0 {
+		// ICMPv6 同样需要原始套接字，无权限时使用ping探测
+		if conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::"); err == nil {
+			d.runIcmpListen(ctx, ctrlCtx, conn, ipv6Hosts, true)
+		} else {
+			d.runPing(ctx, ctrlCtx, ipv6Hosts)
+		}
+	}
+}
 
-	livewg.Wait()
-	close(chanHosts)
+func (d *HostDiscovery) runICMPv4(ctx, ctrlCtx context.Context, hosts []string) {
+	//优先尝试监听本地icmp,批量探测
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err == nil {
+		d.runIcmpListen(ctx, ctrlCtx, conn, hosts, false)
+		return
+	}
+	//尝试无监听icmp探测
+	gologger.Info(ctx, "trying icmp dial")
+	testConn, err := net.DialTimeout("ip4:icmp", "127.0.0.1", 3*time.Second)
+	if err != nil {
+		//使用ping探测
+		d.runPing(ctx, ctrlCtx, hosts)
+		return
+	}
+	testConn.Close()
+	d.parallel(ctrlCtx, hosts, 1000, func(host string) {
+		if rtt, ok := icmpalive(host, 2*d.Timeout); ok {
+			d.markAlive(ctx, host, DiscoveryICMP, rtt)
+		}
+	})
+}
 
-	return AliveHosts
+type icmpProbe struct {
+	host string
+	sent time.Time
 }
 
-func RunIcmp1(hostslist []string, conn *icmp.PacketConn, chanHosts chan string) {
-	endflag := false
+func (d *HostDiscovery) runIcmpListen(ctx, ctrlCtx context.Context, conn *icmp.PacketConn, hosts []string, ipv6 bool) {
+	// 回显应答的类型，ICMPv6 为 129
+	replyType := byte(0)
+	if ipv6 {
+		replyType = 129
+	}
+	var probes sync.Map // 应答的源地址与输入主机的对应关系
+	done := make(chan struct{})
 	go func() {
+		msg := make([]byte, 100)
 		for {
-			if endflag {
+			select {
+			case <-done:
 				return
+			default:
 			}
-			msg := make([]byte, 100)
-			_, sourceIP, _ := conn.ReadFrom(msg)
-			if sourceIP != nil {
-				livewg.Add(1)
-				chanHosts <- sourceIP.String()
+			conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+			n, source, err := conn.ReadFrom(msg)
+			// 只处理回显应答，避免把其他 ICMP 报文当作存活
+			if err != nil || source == nil || n < 1 || msg[0] != replyType {
+				continue
+			}
+			if p, ok := probes.Load(source.(*net.IPAddr).IP.String()); ok {
+				d.markAlive(ctx, p.(icmpProbe).host, DiscoveryICMP, time.Since(p.(icmpProbe).sent))
 			}
 		}
 	}()
-
-	for _, host := range hostslist {
-		dst, _ := net.ResolveIPAddr("ip", host)
-		IcmpByte := makemsg(host)
-		conn.WriteTo(IcmpByte, dst)
-	}
-	//根据hosts数量修改icmp监听时间
-	start := time.Now()
-	for {
-		if len(AliveHosts) == len(hostslist) {
+	for _, host := range hosts {
+		if ctrlCtx.Err() != nil {
 			break
 		}
-		since := time.Since(start)
-		var wait time.Duration
-		switch {
-		case len(hostslist) <= 256:
-			wait = time.Second * 3
-		default:
-			wait = time.Second * 6
+		dst, err := net.ResolveIPAddr("ip", host)
+		if err != nil {
+			continue
 		}
-		if since > wait {
-			break
+		probes.Store(dst.IP.String(), icmpProbe{host: host, sent: time.Now()})
+		if ipv6 {
+			conn.WriteTo(makemsg6(host), dst)
+		} else {
+			conn.WriteTo(makemsg(host), dst)
 		}
 	}
-	endflag = true
+	//根据hosts数量修改icmp监听时间
+	d.wait(ctrlCtx, hosts)
+	close(done)
 	conn.Close()
 }
 
-func RunIcmp2(hostslist []string, chanHosts chan string) {
-	num := 1000
-	if len(hostslist) < num {
-		num = len(hostslist)
-	}
-	var wg sync.WaitGroup
-	limiter := make(chan struct{}, num)
-	for _, host := range hostslist {
-		wg.Add(1)
-		limiter <- struct{}{}
-		go func(host string) {
-			if icmpalive(host) {
-				livewg.Add(1)
-				chanHosts <- host
-			}
-			<-limiter
-			wg.Done()
-		}(host)
-	}
-	wg.Wait()
-	close(limiter)
-}
-
-func icmpalive(host string) bool {
+func icmpalive(host string, timeout time.Duration) (time.Duration, bool) {
 	startTime := time.Now()
-	conn, err := net.DialTimeout("ip4:icmp", host, 6*time.Second)
-	defer func() {
-		if conn != nil {
-			conn.Close()
-		}
-	}()
+	conn, err := net.DialTimeout("ip4:icmp", host, timeout)
 	if err != nil {
-		return false
+		return 0, false
 	}
-	if err := conn.SetDeadline(startTime.Add(6 * time.Second)); err != nil {
-		return false
+	defer conn.Close()
+	if err := conn.SetDeadline(startTime.Add(timeout)); err != nil {
+		return 0, false
 	}
 	msg := makemsg(host)
 	if _, err := conn.Write(msg); err != nil {
-		return false
+		return 0, false
 	}
 
 	receive := make([]byte, 60)
 	if _, err := conn.Read(receive); err != nil {
-		return false
+		return 0, false
 	}
 
-	return true
+	return time.Since(startTime), true
 }
 
-func RunPing(hostslist []string, chanHosts chan string) {
-	var wg sync.WaitGroup
-	limiter := make(chan struct{}, 50)
-	for _, host := range hostslist {
-		wg.Add(1)
-		limiter <- struct{}{}
-		go func(host string) {
-			if ExecCommandPing(host) {
-				livewg.Add(1)
-				chanHosts <- host
-			}
-			<-limiter
-			wg.Done()
-		}(host)
-	}
-	wg.Wait()
+func (d *HostDiscovery) runPing(ctx, ctrlCtx context.Context, hosts []string) {
+	d.parallel(ctrlCtx, hosts, 50, func(host string) {
+		start := time.Now()
+		if ExecCommandPing(host) {
+			d.markAlive(ctx, host, DiscoveryPing, time.Since(start))
+		}
+	})
 }
 
 func ExecCommandPing(ip string) bool {
@@ -185,7 +165,11 @@ func ExecCommandPing(ip string) bool {
 	case "windows":
 		command = exec.Command("cmd", "/c", "ping -n 1 -w 1 "+ip+" && echo true || echo false") //ping -c 1 -i 0.5 -t 4 -W 2 -w 5 "+ip+" >/dev/null && echo true || echo false"
 	case "darwin":
-		command = exec.Command("/bin/bash", "-c", "ping -c 1 -W 1 "+ip+" && echo true || echo false") //ping -c 1 -i 0.5 -t 4 -W 2 -w 5 "+ip+" >/dev/null && echo true || echo false"
+		pingCmd := "ping"
+		if strings.Contains(ip, ":") {
+			pingCmd = "ping6"
+		}
+		command = exec.Command("/bin/bash", "-c", pingCmd+" -c 1 -W 1 "+ip+" && echo true || echo false") //ping -c 1 -i 0.5 -t 4 -W 2 -w 5 "+ip+" >/dev/null && echo true || echo false"
 	default: //linux
 		command = exec.Command("/bin/bash", "-c", "ping -c 1 -w 1 "+ip+" && echo true || echo false") //ping -c 1 -i 0.5 -t 4 -W 2 -w 5 "+ip+" >/dev/null && echo true || echo false"
 	}
@@ -222,6 +206,16 @@ func makemsg(host string) []byte {
 	return msg
 }
 
+// ICMPv6 回显请求，校验和需要包含伪首部，由内核计算填充
+func makemsg6(host string) []byte {
+	msg := make([]byte, 40)
+	id0, id1 := genIdentifier(host)
+	msg[0] = 128
+	msg[4], msg[5] = id0, id1
+	msg[6], msg[7] = genSequence(1)
+	return msg
+}
+
 func checkSum(msg []byte) uint16 {
 	sum := 0
 	length := len(msg)
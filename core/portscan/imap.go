@@ -0,0 +1,57 @@
+package portscan
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var imapModule = &BruteModule{
+	Name: "imap",
+	Type: "IMAP",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := ImapConn(host, user, pass)
+		return flag, "", err
+	}),
+}
+
+// ImapConn 使用 LOGIN 命令登录，993 端口使用 TLS
+func ImapConn(host, user, pass string) (bool, error) {
+	conn, err := dialService(host, portOf(host) == 993, 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	greeting, err := reader.ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return false, errors.New(strings.TrimSpace(greeting))
+	}
+	if _, err = fmt.Fprintf(conn, "a1 LOGIN %s %s\r\n", imapQuote(user), imapQuote(pass)); err != nil {
+		return false, err
+	}
+	// 跳过服务器在登录结果之前返回的未标记响应
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return false, err
+		}
+		if strings.HasPrefix(line, "a1 ") {
+			if strings.HasPrefix(line, "a1 OK") {
+				fmt.Fprint(conn, "a2 LOGOUT\r\n")
+				return true, nil
+			}
+			return false, errors.New(strings.TrimSpace(line))
+		}
+	}
+}
+
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
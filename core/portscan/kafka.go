@@ -2,58 +2,24 @@
 package portscan
 
 import (
-	"context"
 	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
 	"strings"
 	"time"
 
 	"github.com/IBM/sarama"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func KafkaScan(ctx, ctrlCtx context.Context, taskId, address string, usernames, passwords []string) {
-	flag, err := KafkaConn(address, "", "")
-	if flag && err == nil {
-		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-			TaskId:   taskId,
-			ID:       "kafka unauthorized",
-			Name:     "kafka unauthorized",
-			URL:      address,
-			Type:     "Kafka",
-			Severity: "HIGH",
-			Extract:  "",
-		})
-		gologger.Success(ctx, fmt.Sprintf("kafka://%s is unauthorized access", address))
-		return
-	} else {
-		gologger.Info(ctx, fmt.Sprintf("kafka://%s is no unauthorized access", address))
-	}
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[kafka] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", user, -1)
-			flag, err := KafkaConn(address, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "kafka weak password",
-					Name:     "kafka weak password",
-					URL:      address,
-					Type:     "Kafka",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("kafka://%s %s:%s is login failed", address, user, pass))
-			}
-		}
-	}
+var kafkaModule = &BruteModule{
+	Name: "kafka",
+	Type: "Kafka",
+	Unauth: func(host string) (bool, string) {
+		flag, err := KafkaConn(host, "", "")
+		return flag && err == nil, ""
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := KafkaConn(host, user, pass)
+		return flag, "", err
+	}),
 }
 
 // KafkaConn 尝试 Kafka 连接
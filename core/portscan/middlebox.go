@@ -0,0 +1,87 @@
+package portscan
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MiddleboxDetector 检测全端口开放的主机(CDN、防火墙、蜜罐等代为响应的设备)
+// 对若干随机高端口发起连接，如果全部可以连接且响应一致，则认为该主机的端口开放状态不可信
+type MiddleboxDetector struct {
+	probes  int
+	timeout time.Duration
+	hosts   sync.Map
+}
+
+type middleboxResult struct {
+	once      sync.Once
+	middlebox bool
+}
+
+func NewMiddleboxDetector(probes int, timeout time.Duration) *MiddleboxDetector {
+	if probes <= 0 {
+		probes = 5
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &MiddleboxDetector{probes: probes, timeout: timeout}
+}
+
+// Check 每个主机只检测一次，并发调用时等待首次检测完成
+func (m *MiddleboxDetector) Check(host string) bool {
+	v, _ := m.hosts.LoadOrStore(host, &middleboxResult{})
+	r := v.(*middleboxResult)
+	r.once.Do(func() {
+		r.middlebox = m.detect(host)
+	})
+	return r.middlebox
+}
+
+func (m *MiddleboxDetector) detect(host string) bool {
+	var first []byte
+	for i, port := range randomHighPorts(m.probes) {
+		banner, ok := m.grab(host, port)
+		if !ok {
+			return false
+		}
+		if i == 0 {
+			first = banner
+		} else if !bytes.Equal(first, banner) {
+			return false
+		}
+	}
+	return true
+}
+
+// 连接端口并读取少量响应，用于比较不同端口的行为是否一致
+func (m *MiddleboxDetector) grab(host string, port int) ([]byte, bool) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), m.timeout)
+	if err != nil {
+		return nil, false
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(m.timeout))
+	buf := make([]byte, 256)
+	n, _ := conn.Read(buf)
+	return buf[:n], true
+}
+
+// 随机选取不常用的高端口，避免命中真实服务
+func randomHighPorts(count int) []int {
+	ports := make([]int, 0, count)
+	seen := make(map[int]bool)
+	for len(ports) < count {
+		port := 40000 + rand.Intn(25000)
+		if seen[port] {
+			continue
+		}
+		seen[port] = true
+		ports = append(ports, port)
+	}
+	return ports
+}
@@ -0,0 +1,19 @@
+package portscan
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMiddleboxDetector(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// 本机只有少量端口开放，不应被识别为全端口开放
+	if NewMiddleboxDetector(3, time.Second).Check("127.0.0.1") {
+		t.Fatal("localhost should not be detected as middlebox")
+	}
+}
@@ -3,56 +3,35 @@ package portscan
 import (
 	"context"
 	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
 	"strings"
 	"time"
 
-	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-func MongodbScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	flag, err := MongodbConn(host, "", "")
-	if flag && err == nil {
-		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-			TaskId:   taskId,
-			ID:       "mongodb unauthorized",
-			Name:     "mongodb unauthorized",
-			URL:      host,
-			Type:     "Mongodb",
-			Severity: "HIGH",
-		})
-		return
-	} else {
-		gologger.Info(ctx, fmt.Sprintf("mongodb://%s is no unauthorized access", host))
-	}
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[mongodb] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", string(user), -1)
-			flag, err := MongodbConn(host, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "mongodb weak password",
-					Name:     "mongodb weak password",
-					URL:      host,
-					Type:     "mongodb",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("mongodb://%s %s:%s is login failed", host, user, pass))
-			}
+var mongodbModule = &BruteModule{
+	Name:    "mongodb",
+	Type:    "Mongodb",
+	Request: "listDatabases",
+	// 开启认证时 ping 不需要登录，需要列出数据库才能确认未授权访问
+	Unauth: func(host string) (bool, string) {
+		databases, err := MongodbDatabases(host, "", "")
+		return err == nil, strings.Join(databases, "\n")
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := MongodbConn(host, user, pass)
+		return flag, "", err
+	}),
+	Evidence: func(host, user, pass string) (string, string) {
+		databases, err := MongodbDatabases(host, user, pass)
+		if err != nil {
+			return "listDatabases", err.Error()
 		}
-	}
+		return "listDatabases", strings.Join(databases, "\n")
+	},
 }
 
 // For higher versions of MongoDB, this function cannot be authenticated and will prompt to upgrade the driver. Abandoned on 1.6.6
@@ -120,3 +99,19 @@ func MongodbConn(host, user, pass string) (flag bool, err error) {
 	}
 	return flag, err
 }
+
+// MongodbDatabases 列出数据库名称
+func MongodbDatabases(host, user, pass string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clientOpts := options.Client().ApplyURI(fmt.Sprintf("mongodb://%s", host))
+	if user != "" && pass != "" {
+		clientOpts.SetAuth(options.Credential{Username: user, Password: pass})
+	}
+	client, err := mongo.Connect(ctx, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Disconnect(context.Background())
+	return client.ListDatabaseNames(ctx, bson.D{})
+}
@@ -1,49 +1,36 @@
 package portscan
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
-	"strings"
+	"slack-wails/lib/util"
 	"time"
 
 	_ "github.com/microsoft/go-mssqldb"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func MssqlScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[mssql] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", user, -1)
-			flag, err := MssqlConn(host, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "mssql weak password",
-					Name:     "mssql weak password",
-					URL:      host,
-					Type:     "Mssql",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("mssql://%s %s:%s is login failed", host, user, pass))
-			}
+var mssqlModule = &BruteModule{
+	Name: "mssql",
+	Type: "Mssql",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := MssqlConn(host, user, pass)
+		return flag, "", err
+	}),
+	Evidence: func(host, user, pass string) (string, string) {
+		dsn, err := mssqlDSN(host, user, pass)
+		if err != nil {
+			return "", err.Error()
 		}
-	}
+		return sqlEvidence("mssql", dsn, "SELECT @@VERSION, SYSTEM_USER", "SELECT name FROM sys.databases")
+	},
 }
 
 func MssqlConn(host, user, pass string) (flag bool, err error) {
 	flag = false
-	Host, Port := strings.Split(host, ":")[0], strings.Split(host, ":")[1]
-	dataSourceName := fmt.Sprintf("server=%s;user id=%s;password=%s;port=%v;encrypt=disable;timeout=%v", Host, user, pass, Port, 10*time.Second)
+	dataSourceName, err := mssqlDSN(host, user, pass)
+	if err != nil {
+		return false, err
+	}
 	db, err := sql.Open("mssql", dataSourceName)
 	if err == nil {
 		db.SetConnMaxLifetime(10 * time.Second)
@@ -57,3 +44,11 @@ func MssqlConn(host, user, pass string) (flag bool, err error) {
 	}
 	return flag, err
 }
+
+func mssqlDSN(host, user, pass string) (string, error) {
+	Host, Port, err := util.SplitHostPort(host)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("server=%s;user id=%s;password=%s;port=%v;encrypt=disable;timeout=%v", Host, user, pass, Port, 10*time.Second), nil
+}
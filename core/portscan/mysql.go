@@ -1,43 +1,24 @@
 package portscan
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
-	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func MysqlScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[mssql] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", user, -1)
-			flag, err := MysqlConn(host, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "mysql weak password",
-					Name:     "mysql weak password",
-					URL:      host,
-					Type:     "Mysql",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("mysql://%s %s:%s is login failed", host, user, pass))
-			}
-		}
-	}
+var mysqlModule = &BruteModule{
+	Name: "mysql",
+	Type: "Mysql",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := MysqlConn(host, user, pass)
+		return flag, "", err
+	}),
+	Evidence: func(host, user, pass string) (string, string) {
+		dsn := fmt.Sprintf("%v:%v@tcp(%v)/information_schema?charset=utf8&timeout=%v", user, pass, host, 10*time.Second)
+		return sqlEvidence("mysql", dsn, "SELECT VERSION(), CURRENT_USER()", "SHOW DATABASES")
+	},
 }
 
 func MysqlConn(host, user, pass string) (flag bool, err error) {
@@ -0,0 +1,89 @@
+package portscan
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"regexp"
+	"time"
+)
+
+var neo4jModule = &BruteModule{
+	Name: "neo4j",
+	Type: "Neo4j",
+	Unauth: func(host string) (bool, string) {
+		server, err := Neo4jConn(host, "", "")
+		return err == nil, server
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		server, err := Neo4jConn(host, user, pass)
+		return err == nil, server, err
+	}),
+}
+
+var regNeo4jServer = regexp.MustCompile(`Neo4j/[\w.\-]+`)
+
+// Neo4jConn 通过 Bolt 协议发送 HELLO 消息认证，user 为空时使用 none 认证检测是否关闭了认证
+func Neo4jConn(host, user, pass string) (string, error) {
+	conn, err := dialService(host, false, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	// 握手: 魔数以及按优先级排列的 4.4、4.0、3.0 协议版本
+	handshake := []byte{0x60, 0x60, 0xB0, 0x17, 0, 0, 4, 4, 0, 0, 0, 4, 0, 0, 0, 3, 0, 0, 0, 0}
+	if _, err = conn.Write(handshake); err != nil {
+		return "", err
+	}
+	version := make([]byte, 4)
+	if _, err = io.ReadFull(conn, version); err != nil {
+		return "", err
+	}
+	if binary.BigEndian.Uint32(version) == 0 {
+		return "", errors.New("no supported bolt version")
+	}
+	fields := [][2]string{{"user_agent", "slack/1.0"}, {"scheme", "none"}}
+	if user != "" {
+		fields = [][2]string{{"user_agent", "slack/1.0"}, {"scheme", "basic"}, {"principal", user}, {"credentials", pass}}
+	}
+	// HELLO 消息: 单字段结构体，签名 0x01，字段为认证信息的 map
+	var msg bytes.Buffer
+	msg.Write([]byte{0xB1, 0x01, 0xA0 | byte(len(fields))})
+	for _, field := range fields {
+		packString(&msg, field[0])
+		packString(&msg, field[1])
+	}
+	chunk := binary.BigEndian.AppendUint16(nil, uint16(msg.Len()))
+	chunk = append(append(chunk, msg.Bytes()...), 0, 0)
+	if _, err = conn.Write(chunk); err != nil {
+		return "", err
+	}
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	reply := make([]byte, binary.BigEndian.Uint16(header))
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		return "", err
+	}
+	// SUCCESS 0x70，FAILURE 0x7F
+	if len(reply) < 2 || reply[1] != 0x70 {
+		return "", errors.New("authentication failure")
+	}
+	return regNeo4jServer.FindString(string(reply)), nil
+}
+
+// PackStream 字符串编码
+func packString(buf *bytes.Buffer, s string) {
+	switch n := len(s); {
+	case n < 16:
+		buf.WriteByte(0x80 | byte(n))
+	case n < 256:
+		buf.Write([]byte{0xD0, byte(n)})
+	default:
+		buf.WriteByte(0xD1)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	}
+	buf.WriteString(s)
+}
@@ -0,0 +1,28 @@
+package portscan
+
+import (
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// LM:NT 格式（如 secretsdump 输出）或 ntlm: 前缀加 NT 哈希
+var ntlmHashPattern = regexp.MustCompile(`^(?i)(?:[0-9a-f]{32}:|ntlm:)([0-9a-f]{32})$`)
+
+// 拆分 DOMAIN\user 格式的域账号，user@domain 格式 NTLM 可以直接使用，不需要拆分
+func splitDomainUser(user string) (domain, name string) {
+	if domain, name, found := strings.Cut(user, `\`); found {
+		return domain, name
+	}
+	return "", user
+}
+
+// 密码为 NTLM 哈希时返回 NT 哈希，用于哈希传递
+func parseNtlmHash(pass string) ([]byte, bool) {
+	match := ntlmHashPattern.FindStringSubmatch(pass)
+	if match == nil {
+		return nil, false
+	}
+	hash, err := hex.DecodeString(match[1])
+	return hash, err == nil
+}
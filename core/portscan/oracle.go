@@ -1,51 +1,30 @@
 package portscan
 
 import (
-	"context"
 	"database/sql"
-	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
-	"strings"
+	"net/url"
 	"time"
 
 	_ "github.com/sijms/go-ora/v2"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 const defaultOracleServerName = "orcl"
 
-func OracleScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[oracle] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", user, -1)
-			flag, err := OracleConn(host, defaultOracleServerName, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "oracle weak password",
-					Name:     "oracle weak password",
-					URL:      host,
-					Type:     "Oracle",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("oracle://%s %s:%s is login failed", host, user, pass))
-			}
-		}
-	}
+var oracleModule = &BruteModule{
+	Name: "oracle",
+	Type: "Oracle",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := OracleConn(host, defaultOracleServerName, user, pass)
+		return flag, "", err
+	}),
+	Evidence: func(host, user, pass string) (string, string) {
+		return sqlEvidence("oracle", oracleDSN(host, defaultOracleServerName, user, pass), "SELECT banner FROM v$version WHERE ROWNUM = 1", "SELECT user FROM dual", "SELECT username FROM all_users")
+	},
 }
 
 func OracleConn(host, servername, user, pass string) (flag bool, err error) {
 	flag = false
-	dataSourceName := fmt.Sprintf("oracle://%s:%s@%s/%s", user, pass, host, servername)
-	db, err := sql.Open("oracle", dataSourceName)
+	db, err := sql.Open("oracle", oracleDSN(host, servername, user, pass))
 	if err == nil {
 		db.SetConnMaxLifetime(time.Duration(1) * time.Second)
 		db.SetConnMaxIdleTime(time.Duration(1) * time.Second)
@@ -58,3 +37,9 @@ func OracleConn(host, servername, user, pass string) (flag bool, err error) {
 	}
 	return flag, err
 }
+
+// 账号密码中可能包含 @、/、# 等字符，需要按 URL 规则编码
+func oracleDSN(host, servername, user, pass string) string {
+	u := url.URL{Scheme: "oracle", User: url.UserPassword(user, pass), Host: host, Path: "/" + servername}
+	return u.String()
+}
@@ -1 +1,54 @@
 package portscan
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var pop3Module = &BruteModule{
+	Name: "pop3",
+	Type: "POP3",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := Pop3Conn(host, user, pass)
+		return flag, "", err
+	}),
+}
+
+// Pop3Conn 使用 USER/PASS 命令登录，995 端口使用 TLS
+func Pop3Conn(host, user, pass string) (bool, error) {
+	conn, err := dialService(host, portOf(host) == 995, 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	command := func(cmd string) (string, error) {
+		if cmd != "" {
+			if _, err := fmt.Fprintf(conn, "%s\r\n", cmd); err != nil {
+				return "", err
+			}
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(line, "+OK") {
+			return line, errors.New(strings.TrimSpace(line))
+		}
+		return line, nil
+	}
+	if _, err = command(""); err != nil {
+		return false, err
+	}
+	if _, err = command("USER " + user); err != nil {
+		return false, err
+	}
+	if _, err = command("PASS " + pass); err != nil {
+		return false, err
+	}
+	command("QUIT")
+	return true, nil
+}
@@ -9,6 +9,7 @@ import (
 	"slack-wails/lib/gologger"
 	"slack-wails/lib/gonmap"
 	"slack-wails/lib/structs"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -18,12 +19,17 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func TcpScan(ctx, ctrlCtx context.Context, taskId string, addresses <-chan Address, workers, timeout int, proxy clients.Proxy) {
+// timing 为空或使用代理时，所有探测使用固定的超时时间
+// middlebox 不为空时检测全端口开放的主机，filterMiddlebox 为真时过滤这类主机上未识别到服务的端口
+func TcpScan(ctx, ctrlCtx context.Context, taskId string, addresses <-chan Address, workers, timeout int, timing *HostTiming, middlebox *MiddleboxDetector, filterMiddlebox bool, proxy clients.Proxy) {
 	var id int32
 	single := make(chan struct{})
 	retChan := make(chan *structs.InfoResult)
 	var wg sync.WaitGroup
-	openPorts := make(map[string]bool) // 记录开放的端口
+	// TLS 端口在推送结果后另起协程获取证书，不占用扫描协程
+	var certWg sync.WaitGroup
+	var certMutex sync.Mutex
+	var certs []structs.CertInfo
 	go func() {
 		for pr := range retChan {
 			runtime.EventsEmit(ctx, "webFingerScan", pr)
@@ -40,21 +46,44 @@ func TcpScan(ctx, ctrlCtx context.Context, taskId string, addresses <-chan Addre
 		if ctrlCtx.Err() != nil {
 			return
 		}
-		pr := Connect(ctx, taskId, add.IP, add.Port, timeout, proxy)
+		serviceTimeout := time.Second * time.Duration(timeout)
+		if timing != nil && !proxy.Enabled {
+			// SYN 扫描已确认开放的端口不再重复探测
+			if !add.Open {
+				timing.Acquire(add.IP)
+				alive := timing.Probe(add.IP, add.Port)
+				timing.Release(add.IP)
+				if !alive {
+					return
+				}
+			}
+			serviceTimeout = timing.ServiceTimeout(add.IP, serviceTimeout)
+		}
+		pr := Connect(ctx, taskId, add.IP, add.Port, serviceTimeout, proxy)
 		// atomic.AddInt32(&id, 1)
 		// runtime.EventsEmit(ctx, "progressID", id)
 		if pr == nil {
 			return
 		}
-		// 检查1-10端口的开放情况
-		if pr.Port >= 1 && pr.Port <= 20 {
-			openPorts[pr.Host] = true // 记录该IP有开放端口
-			gologger.IntervalError(ctx, fmt.Sprintf("[portscan] %s 疑似cdn地址，会对未识别到服务的端口进行过滤", pr.Host))
-		} else if openPorts[pr.Host] && pr.Scheme == "" {
-			// 如果该IP在1-10端口有开放，后续端口必须识别到服务
-			return // 如果没有识别到服务，则不返回
+		if middlebox != nil && !proxy.Enabled && middlebox.Check(pr.Host) {
+			pr.Middlebox = true
+			gologger.IntervalError(ctx, fmt.Sprintf("[portscan] %s 全端口开放，疑似CDN或防火墙代为响应", pr.Host))
+			if filterMiddlebox && pr.Scheme == "unknow" {
+				return
+			}
 		}
 		retChan <- pr
+		if pr.TLS {
+			certWg.Add(1)
+			go func() {
+				defer certWg.Done()
+				if cert := grabCertificate(ctx, taskId, pr.Host, pr.Port, serviceTimeout, proxy); cert != nil {
+					certMutex.Lock()
+					certs = append(certs, *cert)
+					certMutex.Unlock()
+				}
+			}()
+		}
 	}
 	threadPool, _ := ants.NewPoolWithFunc(workers, func(ipaddr interface{}) {
 		ipa := ipaddr.(Address)
@@ -69,6 +98,8 @@ func TcpScan(ctx, ctrlCtx context.Context, taskId string, addresses <-chan Addre
 		threadPool.Invoke(add)
 	}
 	wg.Wait()
+	certWg.Wait()
+	feedCertificateDomains(ctx, ctrlCtx, taskId, certs, retChan)
 	close(retChan)
 	<-single
 }
@@ -76,11 +107,12 @@ func TcpScan(ctx, ctrlCtx context.Context, taskId string, addresses <-chan Addre
 type Address struct {
 	IP   string
 	Port int
+	Open bool // SYN 扫描已收到 SYN/ACK
 }
 
-func Connect(ctx context.Context, taskId, ip string, port, timeout int, proxy clients.Proxy) *structs.InfoResult {
+func Connect(ctx context.Context, taskId, ip string, port int, timeout time.Duration, proxy clients.Proxy) *structs.InfoResult {
 	scanner := gonmap.New()
-	status, response := scanner.Scan(ip, port, time.Second*time.Duration(timeout), proxy)
+	status, response := scanner.Scan(ip, port, timeout, proxy)
 
 	// 端口关闭或未知，直接返回 nil
 	if status == gonmap.Closed || status == gonmap.Unknown {
@@ -92,22 +124,33 @@ func Connect(ctx context.Context, taskId, ip string, port, timeout int, proxy cl
 	if response != nil && response.FingerPrint.Service != "" {
 		scheme = response.FingerPrint.Service
 	}
+	result := &structs.InfoResult{
+		TaskId: taskId,
+		Host:   ip,
+		Port:   port,
+		Scheme: scheme,
+		URL:    fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(ip, strconv.Itoa(port))),
+		Detect: "Default",
+	}
+	setServiceInfo(result, response)
+	result.TLS = response.TLS || scheme == "https"
 	tcpinfo := &webscan.WebInfo{
-		Protocol: scheme,
-		Banner:   strings.ToLower(response.Raw),
+		Protocol:   scheme,
+		Port:       port,
+		Banner:     strings.ToLower(response.Raw),
+		Product:    strings.ToLower(result.Product),
+		Version:    strings.ToLower(result.Version),
+		ExtraInfo:  strings.ToLower(result.ExtraInfo),
+		DeviceType: strings.ToLower(result.DeviceType),
+		OS:         strings.ToLower(result.OS),
+		CPE:        strings.ToLower(result.CPE),
 	}
 	if scheme == "http" || scheme == "https" {
 		tcpfinger = webscan.Scan(ctx, tcpinfo, webscan.FingerprintDB)
+	} else if result.Product != "" {
+		tcpfinger = webscan.Scan(ctx, tcpinfo, webscan.ServiceFingerprintDB)
 	}
-	result := &structs.InfoResult{
-		TaskId:       taskId,
-		Host:         ip,
-		Port:         port,
-		Scheme:       scheme,
-		URL:          fmt.Sprintf("%s://%s:%d", scheme, ip, port),
-		Fingerprints: tcpfinger,
-		Detect:       "Default",
-	}
+	result.Fingerprints = tcpfinger
 
 	// 若是 HTTP/HTTPS，尝试请求获取状态码
 	if scheme == "http" || scheme == "https" {
@@ -120,6 +163,20 @@ func Connect(ctx context.Context, taskId, ip string, port, timeout int, proxy cl
 	return result
 }
 
+// 保存 gonmap 匹配到的产品、版本、操作系统等信息
+func setServiceInfo(result *structs.InfoResult, response *gonmap.Response) {
+	if response == nil || response.FingerPrint == nil {
+		return
+	}
+	fp := response.FingerPrint
+	result.Product = fp.ProductName
+	result.Version = fp.Version
+	result.ExtraInfo = fp.Info
+	result.DeviceType = fp.DeviceType
+	result.OS = fp.OperatingSystem
+	result.CPE = fp.CPE
+}
+
 func WrapperTcpWithTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
 	d := &net.Dialer{Timeout: timeout}
 	return WrapperTCP(network, address, d)
@@ -1,49 +1,28 @@
 package portscan
 
 import (
-	"context"
 	"database/sql"
-	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
-	"strings"
+	"net/url"
 	"time"
 
 	_ "github.com/lib/pq"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func PostgresScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[postgres] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", string(user), -1)
-			flag, err := PostgresConn(host, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "postgres weak password",
-					Name:     "postgres weak password",
-					URL:      host,
-					Type:     "Postgres",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("postgres://%s %s:%s is login failed", host, user, pass))
-			}
-		}
-	}
+var postgresModule = &BruteModule{
+	Name: "postgres",
+	Type: "Postgres",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := PostgresConn(host, user, pass)
+		return flag, "", err
+	}),
+	Evidence: func(host, user, pass string) (string, string) {
+		return sqlEvidence("postgres", postgresDSN(host, user, pass), "SELECT version(), current_user", "SELECT datname FROM pg_database WHERE NOT datistemplate")
+	},
 }
 
 func PostgresConn(host, user, pass string) (flag bool, err error) {
 	flag = false
-	dataSourceName := fmt.Sprintf("postgres://%v:%v@%v/postgres?sslmode=disable", user, pass, host)
-	db, err := sql.Open("postgres", dataSourceName)
+	db, err := sql.Open("postgres", postgresDSN(host, user, pass))
 	if err == nil {
 		db.SetConnMaxLifetime(10 * time.Second)
 		defer db.Close()
@@ -54,3 +33,9 @@ func PostgresConn(host, user, pass string) (flag bool, err error) {
 	}
 	return flag, err
 }
+
+// 账号密码中可能包含 @、/、# 等字符，需要按 URL 规则编码
+func postgresDSN(host, user, pass string) string {
+	u := url.URL{Scheme: "postgres", User: url.UserPassword(user, pass), Host: host, Path: "/postgres", RawQuery: "sslmode=disable"}
+	return u.String()
+}
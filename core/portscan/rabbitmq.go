@@ -0,0 +1,29 @@
+package portscan
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// 管理后台默认账号 guest/guest 只允许本地登录，远程仍需要尝试其他账号
+var rabbitmqModule = &BruteModule{
+	Name:    "rabbitmq",
+	Type:    "RabbitMQ",
+	Request: "[GET] /api/whoami",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		body, err := RabbitmqConn(host, user, pass)
+		return err == nil, body, err
+	}),
+}
+
+// RabbitmqConn 使用 Basic 认证访问管理接口
+func RabbitmqConn(host, user, pass string) (string, error) {
+	resp, err := httpBasicGet(host, "/api/whoami", user, pass, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return "", fmt.Errorf("status code %d", resp.StatusCode())
+	}
+	return string(resp.Body()), nil
+}
@@ -1,16 +1,15 @@
 package portscan
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
+	"unsafe"
 
 	"github.com/tomatome/grdp/core"
 	"github.com/tomatome/grdp/glog"
@@ -20,47 +19,26 @@ import (
 	"github.com/tomatome/grdp/protocol/t125"
 	"github.com/tomatome/grdp/protocol/tpkt"
 	"github.com/tomatome/grdp/protocol/x224"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func RdpScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	var wg sync.WaitGroup
-	var mutex sync.Mutex
-	limiter := make(chan bool, 10) // 限制协程数量
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				close(limiter)
-				wg.Wait()
-				return
-			}
-			pass = strings.Replace(pass, "{user}", string(user), -1)
-			wg.Add(1)
-			limiter <- true
-			go func(user, pass string) {
-				wg.Done()
-				flag, err := RdpConn(host, "", user, pass, 10)
-				mutex.Lock()
-				if flag && err == nil {
-					runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-						TaskId:   taskId,
-						ID:       "rdp weak password",
-						Name:     "rdp weak password",
-						URL:      host,
-						Type:     "RDP",
-						Severity: "CRITICAL",
-						Extract:  user + "/" + pass,
-					})
-					return
-				} else {
-					gologger.Info(ctx, fmt.Sprintf("rdp://%s %s:%s is login failed", host, user, pass))
-				}
-				mutex.Unlock()
-				<-limiter
-			}(user, pass)
-		}
+var rdpModule = &BruteModule{
+	Name:     "rdp",
+	Type:     "RDP",
+	Severity: "CRITICAL",
+	Threads:  10,
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := RdpAuth(host, user, pass)
+		return flag, "", err
+	}),
+}
+
+// RdpAuth 用户名支持 DOMAIN\user 格式，密码为 NTLM 哈希时进行哈希传递
+func RdpAuth(host, user, pass string) (bool, error) {
+	domain, name := splitDomainUser(user)
+	if hash, ok := parseNtlmHash(pass); ok {
+		return RdpHashConn(host, domain, name, hash, 10)
 	}
-	wg.Wait()
+	return RdpConn(host, domain, name, pass, 10)
 }
 
 func RdpConn(host, domain, user, password string, timeout int) (bool, error) {
@@ -74,6 +52,28 @@ func RdpConn(host, domain, user, password string, timeout int) (bool, error) {
 	return false, err
 }
 
+// RdpHashConn 使用 NT 哈希进行 NLA 认证，NLA 通过后发送的凭据密码为空，
+// 服务端需要开启 Restricted Admin 模式才能建立会话
+func RdpHashConn(host, domain, user string, hash []byte, timeout int) (bool, error) {
+	g := NewClient(host, glog.NONE)
+	ntlm := nla.NewNTLMv2(domain, user, "")
+	setNtlmHash(ntlm, domain, user, hash)
+	if err := g.login(ntlm, domain, user, "", timeout); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// grdp 只支持密码认证，按 NTOWFv2 的计算方式使用 NT 哈希替换私有的 respKeyNT 和 respKeyLM
+func setNtlmHash(ntlm *nla.NTLMv2, domain, user string, hash []byte) {
+	key := nla.HMAC_MD5(hash, core.UnicodeEncode(strings.ToUpper(user)+domain))
+	v := reflect.ValueOf(ntlm).Elem()
+	for _, name := range []string{"respKeyNT", "respKeyLM"} {
+		field := v.FieldByName(name)
+		reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(key))
+	}
+}
+
 type Client struct {
 	Host string // ip:port
 	tpkt *tpkt.TPKT
@@ -94,6 +94,10 @@ func NewClient(host string, logLevel glog.LEVEL) *Client {
 }
 
 func (g *Client) Login(domain, user, pwd string, timeout int) error {
+	return g.login(nla.NewNTLMv2(domain, user, pwd), domain, user, pwd, timeout)
+}
+
+func (g *Client) login(ntlm *nla.NTLMv2, domain, user, pwd string, timeout int) error {
 	conn, err := WrapperTcpWithTimeout("tcp", g.Host, time.Duration(timeout)*time.Second)
 	defer func() {
 		if conn != nil {
@@ -104,7 +108,7 @@ func (g *Client) Login(domain, user, pwd string, timeout int) error {
 		return fmt.Errorf("[dial err] %v", err)
 	}
 	glog.Info(conn.LocalAddr().String())
-	g.tpkt = tpkt.New(core.NewSocketLayer(conn), nla.NewNTLMv2(domain, user, pwd))
+	g.tpkt = tpkt.New(core.NewSocketLayer(conn), ntlm)
 	g.x224 = x224.New(g.tpkt)
 	g.mcs = t125.NewMCSClient(g.x224)
 	g.sec = sec.NewClient(g.mcs)
@@ -1,54 +1,42 @@
 package portscan
 
 import (
-	"context"
+	"bufio"
+	"errors"
 	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
+	"io"
+	"net"
+	"strconv"
 	"strings"
 	"time"
-
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func RedisScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	flag, err := RedisUnauth(host)
-	if flag && err == nil {
-		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-			TaskId:   taskId,
-			ID:       "redis unauthorized",
-			Name:     "redis unauthorized",
-			URL:      host,
-			Type:     "Redis",
-			Severity: "HIGH",
-		})
-		gologger.Success(ctx, fmt.Sprintf("redis://%s is unauthorized access", host))
-		return
-	} else {
-		gologger.Info(ctx, fmt.Sprintf("redis://%s is no unauthorized access", host))
-	}
-	for _, pass := range passwords {
-		if ctrlCtx.Err() != nil {
-			gologger.Warning(ctx, "[redis] User exits crack scanning")
-			return
+// RedisAuditHost 执行的命令
+const redisAuditRequest = "INFO server; INFO replication; CONFIG GET dir; CONFIG GET dbfilename"
+
+var redisModule = &BruteModule{
+	Name:        "redis",
+	Type:        "Redis",
+	DefaultUser: "redis",
+	Request:     redisAuditRequest,
+	Unauth: func(host string) (bool, string) {
+		audit, err := RedisAuditHost(host, "")
+		if err != nil || audit.Info["redis_version"] == "" {
+			return false, ""
 		}
-		pass = strings.Replace(pass, "{user}", "redis", -1)
+		return true, audit.String()
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
 		flag, err := RedisConn(host, pass)
-		if flag && err == nil {
-			runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-				TaskId:   taskId,
-				ID:       "redis weak password",
-				Name:     "redis weak password",
-				URL:      host,
-				Type:     "Redis",
-				Severity: "HIGH",
-				Extract:  pass,
-			})
-			return
-		} else {
-			gologger.Info(ctx, fmt.Sprintf("redis://%s %s is login failed", host, pass))
+		return flag, "", err
+	}),
+	Evidence: func(host, user, pass string) (string, string) {
+		audit, err := RedisAuditHost(host, pass)
+		if err != nil {
+			return redisAuditRequest, err.Error()
 		}
-	}
+		return redisAuditRequest, audit.String()
+	},
 }
 
 func RedisConn(address, password string) (flag bool, err error) {
@@ -110,3 +98,226 @@ func RedisUnauth(address string) (flag bool, err error) {
 	}
 	return flag, err
 }
+
+// RedisClient 保持一个连接依次执行命令
+type RedisClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisClient 连接 Redis，pass 不为空时先进行认证
+func NewRedisClient(address, pass string, timeout time.Duration) (*RedisClient, error) {
+	conn, err := WrapperTcpWithTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &RedisClient{conn: conn, reader: bufio.NewReader(conn)}
+	c.SetTimeout(timeout)
+	if pass != "" {
+		if _, err = c.Do("AUTH", pass); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// SetTimeout 重新设置后续命令的超时时间
+func (c *RedisClient) SetTimeout(timeout time.Duration) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+}
+
+// Do 执行一条命令，参数中可以包含空格和换行，数组结果按行拼接
+func (c *RedisClient) Do(args ...string) (string, error) {
+	if err := writeRedisCommand(c.conn, args...); err != nil {
+		return "", err
+	}
+	return readRedisReply(c.reader)
+}
+
+// DoList 执行一条命令，数组结果展开为列表，SCAN 等嵌套数组按顺序展开
+func (c *RedisClient) DoList(args ...string) ([]string, error) {
+	if err := writeRedisCommand(c.conn, args...); err != nil {
+		return nil, err
+	}
+	return readRedisList(c.reader)
+}
+
+// ConfigGet 返回单个配置项的值
+func (c *RedisClient) ConfigGet(name string) (string, error) {
+	reply, err := c.Do("CONFIG", "GET", name)
+	if err != nil {
+		return "", err
+	}
+	key, value, found := strings.Cut(reply, "\n")
+	if !found || key != name {
+		return "", fmt.Errorf("config %s not found", name)
+	}
+	return value, nil
+}
+
+func (c *RedisClient) Close() error {
+	return c.conn.Close()
+}
+
+// RedisCommand 执行一条命令并返回结果，pass 不为空时先进行认证，命令按空白分割为参数
+func RedisCommand(address, pass, command string) (string, error) {
+	client, err := NewRedisClient(address, pass, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	return client.Do(strings.Fields(command)...)
+}
+
+// RedisAudit 未授权或登录成功后收集的配置信息
+type RedisAudit struct {
+	Info           map[string]string // INFO server 和 INFO replication 的字段
+	Dir            string
+	DBFilename     string
+	ConfigWritable bool // CONFIG SET 可用，可以通过修改 dir 和 dbfilename 写文件
+}
+
+// Replication 返回主从复制暴露的地址，从节点返回主节点地址，主节点返回从节点列表
+func (a *RedisAudit) Replication() []string {
+	var exposed []string
+	if a.Info["role"] == "slave" && a.Info["master_host"] != "" {
+		exposed = append(exposed, "master "+net.JoinHostPort(a.Info["master_host"], a.Info["master_port"]))
+	}
+	slaves, _ := strconv.Atoi(a.Info["connected_slaves"])
+	for i := 0; i < slaves; i++ {
+		if slave := a.Info[fmt.Sprintf("slave%d", i)]; slave != "" {
+			exposed = append(exposed, "slave "+slave)
+		}
+	}
+	return exposed
+}
+
+func (a *RedisAudit) String() string {
+	var b strings.Builder
+	for _, key := range []string{"redis_version", "os", "executable", "config_file", "role"} {
+		if value := a.Info[key]; value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", key, value)
+		}
+	}
+	for _, replica := range a.Replication() {
+		fmt.Fprintf(&b, "replication: %s\n", replica)
+	}
+	if a.Dir != "" {
+		fmt.Fprintf(&b, "dir: %s\ndbfilename: %s\n", a.Dir, a.DBFilename)
+	}
+	fmt.Fprintf(&b, "config writable: %v", a.ConfigWritable)
+	return b.String()
+}
+
+// RedisAuditHost 收集 INFO、dir 和 dbfilename，并将 dir 设置为原值检测配置是否可写，不会修改任何配置
+func RedisAuditHost(address, pass string) (*RedisAudit, error) {
+	client, err := NewRedisClient(address, pass, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	audit := &RedisAudit{Info: make(map[string]string)}
+	for _, section := range []string{"server", "replication"} {
+		info, err := client.Do("INFO", section)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(info, "\n") {
+			if key, value, found := strings.Cut(strings.TrimSpace(line), ":"); found {
+				audit.Info[key] = value
+			}
+		}
+	}
+	// CONFIG 命令可能被 rename-command 禁用
+	if audit.Dir, err = client.ConfigGet("dir"); err == nil {
+		audit.DBFilename, _ = client.ConfigGet("dbfilename")
+		_, err = client.Do("CONFIG", "SET", "dir", audit.Dir)
+		audit.ConfigWritable = err == nil
+	}
+	return audit, nil
+}
+
+// 使用 RESP 数组格式发送命令，参数中可以包含空格和换行
+func writeRedisCommand(w io.Writer, args ...string) error {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+func readRedisReply(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty redis reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return "", err
+		}
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return "", err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", err
+		}
+		items := make([]string, 0, max(n, 0))
+		for i := 0; i < n; i++ {
+			item, err := readRedisReply(reader)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		return strings.Join(items, "\n"), nil
+	}
+	return "", fmt.Errorf("unexpected redis reply: %s", line)
+}
+
+func readRedisList(reader *bufio.Reader) ([]string, error) {
+	prefix, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if prefix[0] != '*' {
+		item, err := readRedisReply(reader)
+		if err != nil {
+			return nil, err
+		}
+		return []string{item}, nil
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimRight(line[1:], "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]string, 0, max(n, 0))
+	for i := 0; i < n; i++ {
+		item, err := readRedisList(reader)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item...)
+	}
+	return items, nil
+}
@@ -5,55 +5,21 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
 	"strings"
 	"time"
-
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func RsyncScan(ctx, ctrlCtx context.Context, taskId, address string, usernames, passwords []string) {
-	flag, moduleName, err := RsyncConn(address, "", "")
-	if flag && err == nil {
-		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-			TaskId:   taskId,
-			ID:       "rsync unauthorized",
-			Name:     "rsync unauthorized",
-			URL:      address,
-			Type:     "Rsync",
-			Severity: "HIGH",
-			Extract:  "moduleName: " + moduleName,
-		})
-		gologger.Success(ctx, fmt.Sprintf("rsync://%s is unauthorized access", address))
-		return
-	} else {
-		gologger.Info(ctx, fmt.Sprintf("rsync://%s is no unauthorized access", address))
-	}
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[rsync] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", string(user), -1)
-			flag, moduleName, err = RsyncConn(address, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "rsync weak password",
-					Name:     "rsync weak password",
-					URL:      address,
-					Type:     "Rsync",
-					Severity: "HIGH",
-					Extract:  "moduleName: " + moduleName,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("rsync://%s %s:%s is login failed", address, user, pass))
-			}
-		}
-	}
+var rsyncModule = &BruteModule{
+	Name: "rsync",
+	Type: "Rsync",
+	Unauth: func(host string) (bool, string) {
+		flag, moduleName, err := RsyncConn(host, "", "")
+		return flag && err == nil, "moduleName: " + moduleName
+	},
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, moduleName, err := RsyncConn(host, user, pass)
+		return flag, "moduleName: " + moduleName, err
+	}),
 }
 
 // RsyncConn 尝试Rsync连接
@@ -5,42 +5,93 @@ import (
 	"fmt"
 	"net/url"
 	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"strings"
 )
 
 type crackFunc func(context.Context, context.Context, string, string, []string, []string)
 
+// 基于账号密码认证的协议，由 BruteForce 统一调度
+var bruteModules = map[string]*BruteModule{
+	"ftp":           ftpModule,
+	"ssh":           sshModule,
+	"telnet":        telnetModule,
+	"smb":           smbModule,
+	"oracle":        oracleModule,
+	"mssql":         mssqlModule,
+	"mysql":         mysqlModule,
+	"rdp":           rdpModule,
+	"postgresql":    postgresModule,
+	"mongodb":       mongodbModule,
+	"ldap":          ldapModule,
+	"mqtt":          mqttModule,
+	"socks5":        socks5Module,
+	"vnc":           vncModule,
+	"redis":         redisModule,
+	"activemq":      activemqModule,
+	"rsync":         rsyncModule,
+	"kafka":         kafkaModule,
+	"smtp":          smtpModule,
+	"pop3":          pop3Module,
+	"imap":          imapModule,
+	"snmp":          snmpModule,
+	"winrm":         winrmModule,
+	"elasticsearch": elasticsearchModule,
+	"kibana":        kibanaModule,
+	"rabbitmq":      rabbitmqModule,
+	"neo4j":         neo4jModule,
+	"cassandra":     cassandraModule,
+	"clickhouse":    clickhouseModule,
+	"zookeeper":     zookeeperModule,
+	"memcached":     memcachedModule,
+}
+
+// 服务识别结果中的协议别名
+var bruteAliases = map[string]string{
+	"smtps":    "smtp",
+	"pop3s":    "pop3",
+	"imaps":    "imap",
+	"wsman":    "winrm",
+	"wsmans":   "winrm",
+	"postgres": "postgresql",
+}
+
+// 将协议别名转换为暴破模块的名称
+func crackScheme(scheme string) string {
+	scheme = strings.ToLower(scheme)
+	if name, ok := bruteAliases[scheme]; ok {
+		return name
+	}
+	return scheme
+}
+
+// 只检测未授权访问的协议
 var crackScanners = map[string]crackFunc{
-	"ftp":        FtpScan,
-	"ssh":        SshScan,
-	"telnet":     TelnetScan,
-	"smb":        SmbScan,
-	"oracle":     OracleScan,
-	"mssql":      MssqlScan,
-	"mysql":      MysqlScan,
-	"rdp":        RdpScan,
-	"postgresql": PostgresScan,
-	"mongodb":    MongodbScan,
-	"ldap":       LdapScan,
-	"mqtt":       MqttScan,
-	"socks5":     Socks5Scan,
-	"vnc":        VncScan,
-	"redis":      RedisScan,
-	"memcached":  MemcachedScan,
-	"jdwp":       JdwpScan,
-	"adb":        AdbScan,
-	"rmi":        RmiScan,
-	"activemq":   ActiveMQScan,
-	"rsync":      RsyncScan,
-	"kafka":      KafkaScan,
+	"jdwp": JdwpScan,
+	"adb":  AdbScan,
+	"rmi":  RmiScan,
 }
 
 func Runner(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
+	RunnerWithOption(ctx, ctrlCtx, taskId, host, usernames, passwords, structs.BruteOption{})
+}
+
+func RunnerWithOption(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string, option structs.BruteOption) {
+	runner(ctx, ctrlCtx, taskId, host, usernames, passwords, option, newSshKeySet(option), NewAttemptCounter())
+}
+
+// keys 为 SSH 私钥认证使用的私钥，attempts 为账号的尝试次数，批量暴破时由所有主机共用
+func runner(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string, option structs.BruteOption, keys *sshKeySet, attempts *AttemptCounter) {
 	u, err := url.Parse(host)
 	if err != nil {
 		gologger.Debug(ctx, fmt.Sprintf("[!] Parse url error: %s\n", err))
 		return
 	}
-	if scanFunc, ok := crackScanners[u.Scheme]; ok {
+	u.Scheme = crackScheme(u.Scheme)
+	var cracked bool
+	if module, ok := bruteModules[u.Scheme]; ok {
+		cracked = BruteForce(ctx, ctrlCtx, taskId, u.Host, module, usernames, passwords, option, attempts)
+	} else if scanFunc, ok := crackScanners[u.Scheme]; ok {
 		scanFunc(ctx, ctrlCtx, taskId, u.Host, usernames, passwords)
 	} else {
 		gologger.Error(ctx, fmt.Sprintf("[!] No brute module registered for: %s\n", u.Scheme))
@@ -48,6 +99,14 @@ func Runner(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwo
 	// 额外漏洞扫描
 	switch u.Scheme {
 	case "smb":
-		MS17010(ctx, taskId, u.Host)
+		SmbCheck(ctx, taskId, u.Host)
+	case "ssh":
+		// 主机成功后停止的策略下，密码已经登录成功时不再尝试私钥
+		if cracked && stopOnHost(option) {
+			return
+		}
+		if option.KeyDir != "" && ctrlCtx.Err() == nil {
+			sshKeyBrute(ctx, ctrlCtx, taskId, u.Host, usernames, option, keys, attempts)
+		}
 	}
 }
@@ -1,78 +1,184 @@
 package portscan
 
 import (
-	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
-	"strconv"
+	"slack-wails/lib/util"
 	"strings"
 	"time"
 
+	"github.com/projectdiscovery/go-smb2"
 	"github.com/stacktitan/smb/smb"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func SmbScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[smb] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", user, -1)
-			flag, err := doWithTimeOut(host, user, pass)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "smb weak password",
-					Name:     "smb weak password",
-					URL:      host,
-					Type:     "SMB",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("smb://%s %s:%s is login failed", host, user, pass))
-			}
+// 用户名支持 DOMAIN\user 格式，密码支持 LM:NT 或 ntlm:NT 格式的哈希
+var smbModule = &BruteModule{
+	Name:    "smb",
+	Type:    "SMB",
+	Request: "[ListShares]",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := SmbConn(host, user, pass)
+		return flag, "", err
+	}),
+	Evidence: func(host, user, pass string) (string, string) {
+		shares, err := SmbShares(host, user, pass, false)
+		if err != nil {
+			return "[ListShares]", err.Error()
 		}
-	}
+		return "[ListShares]", strings.Join(shares, "\n")
+	},
 }
 
-func SmblConn(host, user, pass string, signal chan struct{}) (flag bool, err error) {
-	flag = false
-	Host := strings.Split(host, ":")[0]
-	Port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+// 匿名会话无法枚举共享，依次尝试连接常见的共享名称
+var smbCommonShares = []string{"ADMIN$", "C$", "D$", "NETLOGON", "SYSVOL", "Users", "Public", "share", "print$"}
+
+func smbOptions(host, user, pass string) (smb.Options, error) {
+	Host, Port, err := util.SplitHostPort(host)
+	if err != nil {
+		return smb.Options{}, err
+	}
+	domain, name := splitDomainUser(user)
 	options := smb.Options{
-		Host:        Host,
-		Port:        Port,
-		User:        user,
-		Password:    pass,
-		Domain:      "",
-		Workstation: "",
-	}
-	session, err := smb.NewSession(options, false)
-	if err == nil {
-		session.Close()
-		if session.IsAuthenticated {
-			flag = true
-		}
+		Host:     Host,
+		Port:     Port,
+		User:     name,
+		Password: pass,
+		Domain:   domain,
+	}
+	if hash, ok := parseNtlmHash(pass); ok {
+		options.Password = ""
+		options.Hash = hex.EncodeToString(hash)
 	}
-	signal <- struct{}{}
-	return flag, err
+	return options, nil
 }
 
-func doWithTimeOut(host, user, pass string) (flag bool, err error) {
-	signal := make(chan struct{})
+// 在超时时间内执行 SMB 操作，stacktitan/smb 不支持设置超时
+func smbWithTimeout[T any](timeout time.Duration, fn func() (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
 	go func() {
-		flag, err = SmblConn(host, user, pass, signal)
+		value, err := fn()
+		done <- result{value, err}
 	}()
 	select {
-	case <-signal:
-		return flag, err
-	case <-time.After(10 * time.Second):
-		return false, errors.New("time out")
+	case r := <-done:
+		return r.value, r.err
+	case <-time.After(timeout):
+		var zero T
+		return zero, errors.New("time out")
+	}
+}
+
+// SmbConn 使用账号密码或 NTLM 哈希登录
+func SmbConn(host, user, pass string) (bool, error) {
+	return smbWithTimeout(10*time.Second, func() (bool, error) {
+		options, err := smbOptions(host, user, pass)
+		if err != nil {
+			return false, err
+		}
+		session, err := smb.NewSession(options, false)
+		if session != nil {
+			defer session.Close()
+		}
+		if err != nil {
+			return false, err
+		}
+		return session.IsAuthenticated, nil
+	})
+}
+
+// SmbShares 通过 srvsvc 枚举共享并检查读权限，checkWrite 为真时通过创建并删除临时文件检查写权限
+func SmbShares(host, user, pass string, checkWrite bool) ([]string, error) {
+	conn, err := dialService(host, false, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	domain, name := splitDomainUser(user)
+	initiator := &smb2.NTLMInitiator{User: name, Password: pass, Domain: domain}
+	if hash, ok := parseNtlmHash(pass); ok {
+		initiator.Password = ""
+		initiator.Hash = hash
+	}
+	session, err := (&smb2.Dialer{Initiator: initiator}).Dial(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Logoff()
+	names, err := session.ListSharenames()
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]string, 0, len(names))
+	for _, share := range names {
+		shares = append(shares, fmt.Sprintf("%s [%s]", share, smbShareAccess(session, share, checkWrite)))
+	}
+	return shares, nil
+}
+
+func smbShareAccess(session *smb2.Session, share string, checkWrite bool) string {
+	fs, err := session.Mount(share)
+	if err != nil {
+		return "NO ACCESS"
+	}
+	defer fs.Umount()
+	var access []string
+	if _, err = fs.ReadDir("."); err == nil {
+		access = append(access, "READ")
+	}
+	// C$、ADMIN$ 等以 $ 结尾的管理共享以及 IPC$ 不写入文件
+	if checkWrite && !strings.HasSuffix(share, "$") {
+		probe := fmt.Sprintf("slack_%d.txt", time.Now().UnixNano())
+		if file, err := fs.Create(probe); err == nil {
+			file.Close()
+			if err = fs.Remove(probe); err != nil {
+				access = append(access, "WRITE (failed to remove "+probe+": "+err.Error()+")")
+			} else {
+				access = append(access, "WRITE")
+			}
+		}
+	}
+	if len(access) == 0 {
+		return "NO ACCESS"
+	}
+	return strings.Join(access, ",")
+}
+
+// SmbGuestShares 返回匿名会话和 guest 账号可以访问的共享，不包括 IPC$
+func SmbGuestShares(host string) []string {
+	var shares []string
+	anonymous, _ := smbWithTimeout(30*time.Second, func() ([]string, error) {
+		options, err := smbOptions(host, "", "")
+		if err != nil {
+			return nil, err
+		}
+		session, err := smb.NewSession(options, false)
+		if session != nil {
+			defer session.Close()
+		}
+		if err != nil || !session.IsAuthenticated {
+			return nil, err
+		}
+		var connected []string
+		for _, share := range smbCommonShares {
+			if session.TreeConnect(share) == nil {
+				connected = append(connected, share)
+			}
+		}
+		return connected, nil
+	})
+	for _, share := range anonymous {
+		shares = append(shares, share+" [anonymous]")
+	}
+	guest, _ := SmbShares(host, "guest", "", false)
+	for _, share := range guest {
+		if !strings.HasSuffix(share, "[NO ACCESS]") && !strings.HasPrefix(strings.ToUpper(share), "IPC$ ") {
+			shares = append(shares, share+" [guest]")
+		}
 	}
+	return shares
 }
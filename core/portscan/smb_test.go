@@ -0,0 +1,77 @@
+package portscan
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestParseNtlmHash(t *testing.T) {
+	for pass, ok := range map[string]bool{
+		"aad3b435b51404eeaad3b435b51404ee:31d6cfe0d16ae931b73c59d7e0c089c0": true,
+		"ntlm:31D6CFE0D16AE931B73C59D7E0C089C0":                             true,
+		"31d6cfe0d16ae931b73c59d7e0c089c0":                                  false,
+		"P@ssw0rd":                                                          false,
+	} {
+		if _, got := parseNtlmHash(pass); got != ok {
+			t.Fatalf("%s: got %v", pass, got)
+		}
+	}
+	if domain, name := splitDomainUser(`CORP\admin`); domain != "CORP" || name != "admin" {
+		t.Fatalf("got %s %s", domain, name)
+	}
+}
+
+func TestSmbNegotiate(t *testing.T) {
+	host := serveOnce(t, func(conn net.Conn) {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		request := make([]byte, binary.BigEndian.Uint32(header))
+		io.ReadFull(conn, request)
+		// 响应头部沿用请求头部，协商上下文从偏移 128 开始
+		response := append(request[:64:64], make([]byte, 64)...)
+		body := response[64:]
+		binary.LittleEndian.PutUint16(body[2:], 0x01)
+		binary.LittleEndian.PutUint16(body[4:], smb2Dialect311)
+		binary.LittleEndian.PutUint16(body[6:], 1)
+		binary.LittleEndian.PutUint32(body[60:], 128)
+		response = binary.LittleEndian.AppendUint16(response, smb2CompressionCapability)
+		response = binary.LittleEndian.AppendUint16(response, 10)
+		response = append(response, make([]byte, 4)...)
+		response = binary.LittleEndian.AppendUint16(response, 1)
+		response = append(response, make([]byte, 8)...)
+		conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(response))), response...))
+		// SESSION_SETUP 响应中的 NTLMSSP 质询，系统版本为 10.0.18363
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		request = make([]byte, binary.BigEndian.Uint32(header))
+		io.ReadFull(conn, request)
+		challenge := append([]byte("NTLMSSP\x00"), 2, 0, 0, 0)
+		challenge = append(challenge, make([]byte, 8)...)
+		challenge = binary.LittleEndian.AppendUint32(challenge, ntlmNegotiateVersion)
+		challenge = append(challenge, make([]byte, 24)...)
+		challenge = append(challenge, 10, 0)
+		challenge = binary.LittleEndian.AppendUint16(challenge, 18363)
+		challenge = append(challenge, 0, 0, 0, 15)
+		response = append(append(request[:64:64], make([]byte, 8)...), challenge...)
+		conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(response))), response...))
+	})
+	negotiate, err := SmbNegotiate(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if negotiate.SigningRequired || !negotiate.Compression || negotiate.Dialect != smb2Dialect311 {
+		t.Fatalf("unexpected result %+v", negotiate)
+	}
+	if negotiate.OSVersion != "10.0.18363" || !negotiate.smbGhostAffected() {
+		t.Fatalf("unexpected os version %+v", negotiate)
+	}
+	// 其他版本只作为压缩开启的提示
+	if (&smb2Negotiate{OSVersion: "10.0.19041", OSBuild: 19041}).smbGhostAffected() || (&smb2Negotiate{}).smbGhostAffected() {
+		t.Fatal("only build 18362 and 18363 are affected")
+	}
+}
@@ -0,0 +1,245 @@
+package portscan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	smb2SigningRequired       = 0x02
+	smb2Dialect311            = 0x0311
+	smb2PreauthIntegrity      = 0x0001
+	smb2CompressionCapability = 0x0003
+	smb2CommandSessionSetup   = 0x0001
+)
+
+// NTLMSSP 协商标志：UNICODE、REQUEST_TARGET、NTLM、ALWAYS_SIGN、EXTENDED_SESSIONSECURITY、
+// TARGET_INFO、VERSION、128、KEY_EXCH、56
+const (
+	ntlmNegotiateFlags   = 0xe2888205
+	ntlmNegotiateVersion = 0x02000000
+)
+
+// SMB2 协商结果
+type smb2Negotiate struct {
+	Dialect         uint16
+	SigningRequired bool
+	Compression     bool   // 服务端支持 SMB 3.1.1 压缩，存在 CVE-2020-0796 的前提
+	OSVersion       string // NTLMSSP 质询中的系统版本，例如 10.0.18362，获取失败时为空
+	OSBuild         int
+}
+
+// CVE-2020-0796 只影响 Windows 10 / Server 1903(18362) 和 1909(18363)
+func (n *smb2Negotiate) smbGhostAffected() bool {
+	return strings.HasPrefix(n.OSVersion, "10.0.") && (n.OSBuild == 18362 || n.OSBuild == 18363)
+}
+
+// SmbCheck 在 SMB 暴破后执行的额外检测：MS17-010、guest 共享访问、SMBGhost 和 SMB 签名
+func SmbCheck(ctx context.Context, taskId, host string) {
+	MS17010(ctx, taskId, host)
+	if shares := SmbGuestShares(host); len(shares) > 0 {
+		gologger.Success(ctx, fmt.Sprintf("[+] %s SMB guest share access: %s", host, strings.Join(shares, ", ")))
+		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
+			TaskId:      taskId,
+			ID:          "smb-guest-share-access",
+			Name:        "SMB guest share access",
+			URL:         host,
+			Type:        "SMB",
+			Severity:    "MEDIUM",
+			Description: "Shares can be accessed with an anonymous session or the guest account",
+			Extract:     strings.Join(shares, "\n"),
+		})
+	}
+	negotiate, err := SmbNegotiate(host)
+	if err != nil {
+		gologger.Debug(ctx, fmt.Sprintf("[smb] %s negotiate failed: %v", host, err))
+		return
+	}
+	if negotiate.Compression && negotiate.smbGhostAffected() {
+		gologger.Success(ctx, fmt.Sprintf("[+] CVE-2020-0796 %s Windows %s SMBv3.1.1 compression enabled", host, negotiate.OSVersion))
+		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
+			TaskId:      taskId,
+			ID:          "CVE-2020-0796",
+			Name:        "SMBGhost",
+			URL:         host,
+			Type:        "SMB",
+			Severity:    "CRITICAL",
+			Description: "Windows 10 1903/1909 with SMBv3.1.1 compression enabled, the host is vulnerable if KB4551762 is not installed",
+			Extract:     "Windows " + negotiate.OSVersion,
+		})
+	} else if negotiate.Compression {
+		gologger.Info(ctx, fmt.Sprintf("[smb] %s SMBv3 compression enabled, os version: %s", host, negotiate.OSVersion))
+		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
+			TaskId:      taskId,
+			ID:          "smbv3-compression-enabled",
+			Name:        "SMBv3 compression enabled",
+			URL:         host,
+			Type:        "SMB",
+			Severity:    "LOW",
+			Description: "SMBv3.1.1 compression is enabled, the os build is not affected by CVE-2020-0796 or could not be determined",
+			Extract:     negotiate.OSVersion,
+		})
+	}
+	if !negotiate.SigningRequired {
+		gologger.Success(ctx, fmt.Sprintf("[+] %s SMB signing not required", host))
+		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
+			TaskId:      taskId,
+			ID:          "smb-signing-not-required",
+			Name:        "SMB signing not required",
+			URL:         host,
+			Type:        "SMB",
+			Severity:    "MEDIUM",
+			Description: "SMB message signing is not required, the host can be used as an NTLM relay target",
+			Extract:     fmt.Sprintf("dialect 0x%04x", negotiate.Dialect),
+		})
+	}
+}
+
+// SmbNegotiate 发送带有压缩能力上下文的 SMB2 NEGOTIATE 请求，
+// 随后发送只包含 NTLMSSP 协商消息的 SESSION_SETUP 请求，从质询消息中获取系统版本
+func SmbNegotiate(host string) (*smb2Negotiate, error) {
+	conn, err := dialService(host, false, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err = conn.Write(smb2NegotiateRequest()); err != nil {
+		return nil, err
+	}
+	data, err := readSmbPacket(conn)
+	if err != nil {
+		return nil, err
+	}
+	result, err := parseSmb2Negotiate(data)
+	if err != nil {
+		return nil, err
+	}
+	// 获取系统版本失败不影响协商结果
+	if _, err = conn.Write(smb2SessionSetupRequest()); err == nil {
+		if data, err = readSmbPacket(conn); err == nil {
+			if major, minor, build, err := parseNtlmChallengeVersion(data); err == nil {
+				result.OSVersion = fmt.Sprintf("%d.%d.%d", major, minor, build)
+				result.OSBuild = build
+			}
+		}
+	}
+	return result, nil
+}
+
+// 读取一个 SMB 报文，返回的数据不包括 NetBIOS 头部
+func readSmbPacket(conn io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header) & 0xffffff
+	if length > 1<<16 {
+		return nil, errors.New("invalid smb response length")
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func smb2NegotiateRequest() []byte {
+	dialects := []uint16{0x0202, 0x0210, 0x0300, 0x0302, smb2Dialect311}
+	var body bytes.Buffer
+	// SMB2 头部，命令为 NEGOTIATE
+	body.Write([]byte{0xfe, 'S', 'M', 'B', 64, 0})
+	body.Write(make([]byte, 8))                         // CreditCharge, Status, Command
+	binary.Write(&body, binary.LittleEndian, uint16(1)) // CreditRequest
+	body.Write(make([]byte, 64-16))
+	contextOffset := 64 + 36 + 2*len(dialects)
+	padding := (8 - contextOffset%8) % 8
+	binary.Write(&body, binary.LittleEndian, []uint16{36, uint16(len(dialects)), 1, 0}) // StructureSize, DialectCount, SecurityMode
+	body.Write(make([]byte, 4+16))                                                      // Capabilities, ClientGuid
+	binary.Write(&body, binary.LittleEndian, uint32(contextOffset+padding))
+	binary.Write(&body, binary.LittleEndian, []uint16{2, 0}) // NegotiateContextCount
+	binary.Write(&body, binary.LittleEndian, dialects)
+	body.Write(make([]byte, padding))
+	// SMB2_PREAUTH_INTEGRITY_CAPABILITIES：SHA-512 和 32 字节盐值
+	binary.Write(&body, binary.LittleEndian, []uint16{smb2PreauthIntegrity, 38, 0, 0, 1, 32, 1})
+	body.Write(make([]byte, 32+2)) // 盐值和 8 字节对齐
+	// SMB2_COMPRESSION_CAPABILITIES：LZNT1
+	binary.Write(&body, binary.LittleEndian, []uint16{smb2CompressionCapability, 10, 0, 0, 1, 0, 0, 0, 1})
+	packet := binary.BigEndian.AppendUint32(nil, uint32(body.Len()))
+	return append(packet, body.Bytes()...)
+}
+
+// 解析 NEGOTIATE 响应，data 不包括 NetBIOS 头部
+func parseSmb2Negotiate(data []byte) (*smb2Negotiate, error) {
+	if len(data) < 64+64 || !bytes.Equal(data[:4], []byte{0xfe, 'S', 'M', 'B'}) {
+		return nil, errors.New("not an smb2 negotiate response")
+	}
+	if status := binary.LittleEndian.Uint32(data[8:12]); status != 0 {
+		return nil, fmt.Errorf("smb2 negotiate status 0x%08x", status)
+	}
+	body := data[64:]
+	result := &smb2Negotiate{
+		SigningRequired: binary.LittleEndian.Uint16(body[2:4])&smb2SigningRequired != 0,
+		Dialect:         binary.LittleEndian.Uint16(body[4:6]),
+	}
+	if result.Dialect != smb2Dialect311 {
+		return result, nil
+	}
+	count := int(binary.LittleEndian.Uint16(body[6:8]))
+	offset := int(binary.LittleEndian.Uint32(body[60:64]))
+	for i := 0; i < count && offset+8 <= len(data); i++ {
+		contextType := binary.LittleEndian.Uint16(data[offset:])
+		dataLength := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		if contextType == smb2CompressionCapability && dataLength >= 2 && offset+10 <= len(data) {
+			result.Compression = binary.LittleEndian.Uint16(data[offset+8:]) > 0
+		}
+		offset += (8 + dataLength + 7) &^ 7
+	}
+	return result, nil
+}
+
+func smb2SessionSetupRequest() []byte {
+	token := append([]byte("NTLMSSP\x00"), binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, 1), ntlmNegotiateFlags)...)
+	token = append(token, make([]byte, 16+8)...) // DomainNameFields, WorkstationFields, Version
+	var body bytes.Buffer
+	// SMB2 头部，命令为 SESSION_SETUP，MessageId 为 1
+	body.Write([]byte{0xfe, 'S', 'M', 'B', 64, 0})
+	body.Write(make([]byte, 6))                                                     // CreditCharge, Status
+	binary.Write(&body, binary.LittleEndian, []uint16{smb2CommandSessionSetup, 1})  // Command, CreditRequest
+	body.Write(make([]byte, 8))                                                     // Flags, NextCommand
+	binary.Write(&body, binary.LittleEndian, uint64(1))                             // MessageId
+	body.Write(make([]byte, 64-32))                                                 // ProcessId, TreeId, SessionId, Signature
+	binary.Write(&body, binary.LittleEndian, uint16(25))                            // StructureSize
+	body.Write([]byte{0, 1})                                                        // Flags, SecurityMode
+	body.Write(make([]byte, 8))                                                     // Capabilities, Channel
+	binary.Write(&body, binary.LittleEndian, []uint16{64 + 24, uint16(len(token))}) // SecurityBufferOffset, SecurityBufferLength
+	body.Write(make([]byte, 8))                                                     // PreviousSessionId
+	body.Write(token)
+	packet := binary.BigEndian.AppendUint32(nil, uint32(body.Len()))
+	return append(packet, body.Bytes()...)
+}
+
+// 从 SESSION_SETUP 响应的 NTLMSSP 质询消息中解析系统版本，兼容 SPNEGO 封装
+func parseNtlmChallengeVersion(data []byte) (major, minor, build int, err error) {
+	index := bytes.Index(data, []byte("NTLMSSP\x00"))
+	if index < 0 {
+		return 0, 0, 0, errors.New("no ntlmssp message")
+	}
+	msg := data[index:]
+	if len(msg) < 56 || binary.LittleEndian.Uint32(msg[8:12]) != 2 {
+		return 0, 0, 0, errors.New("not an ntlmssp challenge message")
+	}
+	if binary.LittleEndian.Uint32(msg[20:24])&ntlmNegotiateVersion == 0 {
+		return 0, 0, 0, errors.New("ntlmssp challenge has no version")
+	}
+	return int(msg[48]), int(msg[49]), int(binary.LittleEndian.Uint16(msg[50:52])), nil
+}
@@ -0,0 +1,80 @@
+package portscan
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var smtpModule = &BruteModule{
+	Name: "smtp",
+	Type: "SMTP",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := SmtpConn(host, user, pass)
+		return flag, "", err
+	}),
+}
+
+// SmtpConn 使用 AUTH PLAIN 或 AUTH LOGIN 登录，服务器支持 STARTTLS 时先升级为加密连接
+func SmtpConn(host, user, pass string) (bool, error) {
+	conn, err := dialService(host, portOf(host) == 465, 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	serverName, _, _ := net.SplitHostPort(host)
+	client, err := smtp.NewClient(conn, serverName)
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+	if err = client.Hello("localhost"); err != nil {
+		return false, err
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{InsecureSkipVerify: true}); err != nil {
+			return false, err
+		}
+	}
+	ok, mechanisms := client.Extension("AUTH")
+	if !ok {
+		return false, errors.New("smtp server does not support AUTH")
+	}
+	auth := &smtpAuth{user: user, pass: pass, mechanism: "PLAIN"}
+	if !strings.Contains(strings.ToUpper(mechanisms), "PLAIN") && strings.Contains(strings.ToUpper(mechanisms), "LOGIN") {
+		auth.mechanism = "LOGIN"
+	}
+	if err = client.Auth(auth); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 标准库的 PlainAuth 拒绝在非加密连接上发送密码，暴破时不需要这个限制
+type smtpAuth struct {
+	user, pass, mechanism string
+}
+
+func (a *smtpAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if a.mechanism == "LOGIN" {
+		return "LOGIN", nil, nil
+	}
+	return "PLAIN", []byte("\x00" + a.user + "\x00" + a.pass), nil
+}
+
+func (a *smtpAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	prompt := strings.ToLower(string(fromServer))
+	switch {
+	case strings.Contains(prompt, "username"):
+		return []byte(a.user), nil
+	case strings.Contains(prompt, "password"):
+		return []byte(a.pass), nil
+	}
+	return nil, errors.New("unexpected server challenge")
+}
@@ -0,0 +1,97 @@
+package portscan
+
+import (
+	"encoding/asn1"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// 团体字作为密码，结果中记录 sysDescr
+var snmpModule = &BruteModule{
+	Name:        "snmp",
+	Type:        "SNMP",
+	DefaultUser: "snmp",
+	Passwords:   []string{"public", "private", "manager", "community", "snmp", "monitor"},
+	Request:     "[GetRequest] sysDescr",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		descr, err := SnmpConn(host, pass, 3*time.Second)
+		if err != nil {
+			return false, "", err
+		}
+		return true, descr, nil
+	}),
+}
+
+var oidSysDescr = asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 1, 0}
+
+type snmpVarBind struct {
+	Name  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type snmpPDU struct {
+	RequestID   int
+	ErrorStatus int
+	ErrorIndex  int
+	VarBinds    []snmpVarBind
+}
+
+type snmpRequest struct {
+	Version   int
+	Community []byte
+	PDU       snmpPDU `asn1:"tag:0"` // GetRequest
+}
+
+type snmpResponse struct {
+	Version   int
+	Community []byte
+	PDU       snmpPDU `asn1:"tag:2"` // GetResponse
+}
+
+// SnmpConn 使用 v2c 和 v1 同时查询 sysDescr，团体字错误时服务端不会响应，只能等待超时
+func SnmpConn(host, community string, timeout time.Duration) (string, error) {
+	conn, err := net.DialTimeout("udp", host, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	requestID := rand.Intn(1 << 30)
+	for _, version := range []int{1, 0} {
+		packet, err := asn1.Marshal(snmpRequest{
+			Version:   version,
+			Community: []byte(community),
+			PDU: snmpPDU{
+				RequestID: requestID,
+				VarBinds:  []snmpVarBind{{Name: oidSysDescr, Value: asn1.NullRawValue}},
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		if _, err = conn.Write(packet); err != nil {
+			return "", err
+		}
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		var response snmpResponse
+		if _, err = asn1.Unmarshal(buf[:n], &response); err != nil || response.PDU.RequestID != requestID || string(response.Community) != community {
+			continue
+		}
+		if response.PDU.ErrorStatus != 0 {
+			return "", errors.New("snmp error status")
+		}
+		var descr string
+		if len(response.PDU.VarBinds) > 0 {
+			descr = string(response.PDU.VarBinds[0].Value.Bytes)
+		}
+		return descr, nil
+	}
+}
@@ -2,90 +2,69 @@ package portscan
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"slack-wails/lib/gologger"
 	"slack-wails/lib/structs"
+	"slack-wails/lib/util"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"golang.org/x/crypto/ssh"
 )
 
-type task struct {
-	User string
-	Pass string
+// 私钥登录成功的结果类型，与密码登录的弱口令区分，复测时使用私钥认证
+const sshKeyKind = "private key"
+
+var sshModule = &BruteModule{
+	Name:    "ssh",
+	Type:    "SSH",
+	Threads: 5,
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := SshConn(host, user, pass)
+		return flag, "", err
+	}),
+	Evidence: func(host, user, pass string) (string, string) {
+		return commandEvidence(func(command string) (string, error) {
+			return ExecuteSshCommand(host, user, pass, command)
+		}, "id", "uname -a", "hostname")
+	},
 }
 
-func SshScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	taskChan := make(chan task)
-	doneChan := make(chan struct{})
-
-	// 启动多线程暴破
-	for range 5 {
-		go func() {
-			for t := range taskChan {
-				// 检查是否需要停止
-				if ctrlCtx.Err() != nil {
-					continue
-				}
-
-				pass := strings.Replace(t.Pass, "{user}", t.User, -1)
-				flag, err := SshConn(host, t.User, pass)
-				if flag && err == nil {
-					result, err := ExecuteSshCommand(host, t.User, pass, "whoami")
-					if err != nil {
-						result = err.Error()
-					}
-					runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-						TaskId:   taskId,
-						ID:       "ssh weak password",
-						Name:     "ssh weak password",
-						URL:      host,
-						Type:     "SSH",
-						Severity: "HIGH",
-						Extract:  t.User + "/" + pass,
-						Request:  "[Command] whoami",
-						Response: result,
-					})
-				} else {
-					gologger.Info(ctx, fmt.Sprintf("ssh://%s %s:%s login failed", host, t.User, pass))
-				}
-			}
-			doneChan <- struct{}{}
-		}()
-	}
-
-	// 分发任务
-	go func() {
-		for _, user := range usernames {
-			for _, pass := range passwords {
-				// 如果中途终止，就不要继续塞任务了
-				if ctrlCtx.Err() != nil {
-					break
-				}
-				taskChan <- task{User: user, Pass: pass}
+// 私钥文件超过该大小时不再解析
+const sshKeyMaxSize = 64 * 1024
+
+// 密码认证同时支持 password 和 keyboard-interactive 方式，后者对所有问题都回答该密码
+func sshPasswordAuth(pass string) []ssh.AuthMethod {
+	return []ssh.AuthMethod{
+		ssh.Password(pass),
+		ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range answers {
+				answers[i] = pass
 			}
-		}
-		close(taskChan)
-	}()
-
-	// 等待所有worker退出
-	for range 5 {
-		<-doneChan
+			return answers, nil
+		}),
 	}
 }
 
-func SshConn(host, user, pass string) (bool, error) {
+func sshDial(host, user string, timeout time.Duration, auth []ssh.AuthMethod) (*ssh.Client, error) {
 	config := &ssh.ClientConfig{
 		User:            user,
-		Auth:            []ssh.AuthMethod{ssh.Password(pass)},
-		Timeout:         10 * time.Second,
+		Auth:            auth,
+		Timeout:         timeout,
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 	}
+	return ssh.Dial("tcp", host, config)
+}
 
-	client, err := ssh.Dial("tcp", host, config)
+func sshLogin(host, user string, auth []ssh.AuthMethod) (bool, error) {
+	client, err := sshDial(host, user, 10*time.Second, auth)
 	if err != nil {
 		return false, err
 	}
@@ -100,20 +79,9 @@ func SshConn(host, user, pass string) (bool, error) {
 	return true, nil
 }
 
-func ExecuteSshCommand(host, username, password, command string) (string, error) {
-	config := &ssh.ClientConfig{
-		User: username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(password),
-		},
-		Timeout: 5 * time.Second,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
-	}
-
+func sshExecute(host, user string, auth []ssh.AuthMethod, command string) (string, error) {
 	// Connect to the SSH server
-	client, err := ssh.Dial("tcp", host, config)
+	client, err := sshDial(host, user, 5*time.Second, auth)
 	if err != nil {
 		return "", fmt.Errorf("failed to dial: %v", err)
 	}
@@ -134,3 +102,145 @@ func ExecuteSshCommand(host, username, password, command string) (string, error)
 
 	return string(output), nil
 }
+
+func SshConn(host, user, pass string) (bool, error) {
+	return sshLogin(host, user, sshPasswordAuth(pass))
+}
+
+func ExecuteSshCommand(host, username, password, command string) (string, error) {
+	return sshExecute(host, username, sshPasswordAuth(password), command)
+}
+
+// SshKeyConn 使用私钥登录
+func SshKeyConn(host, user string, signer ssh.Signer) (bool, error) {
+	return sshLogin(host, user, []ssh.AuthMethod{ssh.PublicKeys(signer)})
+}
+
+// 复测私钥登录时重新读取记录的私钥文件，加密私钥的密码没有保存，无法复测
+func loadSshKey(keyFile string) (ssh.Signer, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, fmt.Errorf("private key %s is encrypted", keyFile)
+	}
+	return signer, err
+}
+
+// LoadSshKeys 递归读取目录下的私钥，键为相对于目录的文件名。加密的私钥依次尝试 passphrases 解密，
+// 无法解密的私钥文件名通过 locked 返回
+func LoadSshKeys(dir string, passphrases []string) (keys map[string]ssh.Signer, locked []string, err error) {
+	keys = make(map[string]ssh.Signer)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(d.Name(), ".pub") {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > sshKeyMaxSize {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		name, _ := filepath.Rel(dir, path)
+		signer, err := ssh.ParsePrivateKey(data)
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			for _, passphrase := range passphrases {
+				if signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase)); err == nil {
+					break
+				}
+			}
+			if err != nil {
+				locked = append(locked, name)
+				return nil
+			}
+		}
+		// 不是私钥的文件直接忽略
+		if err == nil {
+			keys[name] = signer
+		}
+		return nil
+	})
+	return keys, locked, err
+}
+
+// 私钥目录中的私钥，第一次使用时加载，键为私钥文件的绝对路径，批量暴破时所有主机共用同一份
+type sshKeySet struct {
+	once        sync.Once
+	dir         string
+	passphrases []string
+	keys        map[string]ssh.Signer
+	names       []string
+}
+
+func newSshKeySet(option structs.BruteOption) *sshKeySet {
+	return &sshKeySet{dir: option.KeyDir, passphrases: option.KeyPassphrases}
+}
+
+func (k *sshKeySet) load(ctx context.Context) (map[string]ssh.Signer, []string) {
+	k.once.Do(func() {
+		keys, locked, err := LoadSshKeys(k.dir, k.passphrases)
+		if err != nil {
+			gologger.Error(ctx, fmt.Sprintf("[ssh] load private keys from %s failed: %v", k.dir, err))
+			return
+		}
+		for _, name := range locked {
+			gologger.Warning(ctx, fmt.Sprintf("[ssh] private key %s is encrypted and no passphrase matched", name))
+		}
+		if len(keys) == 0 {
+			gologger.Warning(ctx, fmt.Sprintf("[ssh] no private key found in %s", k.dir))
+			return
+		}
+		// 结果中记录私钥的绝对路径，复测时重新读取
+		dir, _ := filepath.Abs(k.dir)
+		k.keys = make(map[string]ssh.Signer, len(keys))
+		for name, signer := range keys {
+			keyFile := filepath.Join(dir, name)
+			k.keys[keyFile] = signer
+			k.names = append(k.names, keyFile)
+		}
+		sort.Strings(k.names)
+	})
+	return k.keys, k.names
+}
+
+// 使用目录下的私钥对用户名进行认证，结果为 ssh private key，提取内容为 用户名/私钥文件路径
+func sshKeyBrute(ctx, ctrlCtx context.Context, taskId, host string, usernames []string, option structs.BruteOption, keySet *sshKeySet, attempts *AttemptCounter) {
+	keys, names := keySet.load(ctx)
+	if len(names) == 0 {
+		return
+	}
+	module := &BruteModule{
+		Name:    sshModule.Name,
+		Kind:    sshKeyKind,
+		Type:    sshModule.Type,
+		Threads: sshModule.Threads,
+		Auth: AuthFunc(func(host, user, name string) (bool, string, error) {
+			flag, err := SshKeyConn(host, user, keys[name])
+			return flag, "", err
+		}),
+		Evidence: func(host, user, name string) (string, string) {
+			auth := []ssh.AuthMethod{ssh.PublicKeys(keys[name])}
+			return commandEvidence(func(command string) (string, error) {
+				return sshExecute(host, user, auth, command)
+			}, "id", "uname -a", "hostname")
+		},
+	}
+	// 私钥模式下使用账号组合时只取其中的用户名
+	if len(option.Credentials) > 0 {
+		usernames = usernames[:0:0]
+		for _, t := range parseCredentials(option.Credentials, "") {
+			usernames = append(usernames, t.User)
+		}
+		usernames = util.RemoveDuplicates(usernames)
+		option.Credentials = nil
+	}
+	BruteForce(ctx, ctrlCtx, taskId, host, module, usernames, names, option, attempts)
+}
@@ -0,0 +1,54 @@
+package portscan
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestLoadSshKeys(t *testing.T) {
+	dir := t.TempDir()
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	for name, passphrase := range map[string]string{"id_plain": "", "id_secret": "secret", "id_locked": "unknown"} {
+		var block *pem.Block
+		var err error
+		if passphrase == "" {
+			block, err = ssh.MarshalPrivateKey(key, "")
+		} else {
+			block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600)
+	}
+	os.WriteFile(filepath.Join(dir, "id_plain.pub"), []byte("ssh-ed25519 AAAA"), 0600)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a key"), 0600)
+
+	keys, locked, err := LoadSshKeys(dir, []string{"123456", "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys["id_plain"] == nil || keys["id_secret"] == nil {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	if len(locked) != 1 || locked[0] != "id_locked" {
+		t.Fatalf("unexpected locked keys: %v", locked)
+	}
+
+	// 私钥登录的结果记录私钥的路径，复测时重新读取
+	plain := filepath.Join(dir, "id_plain")
+	if signer, err := loadSshKey(plain); err != nil || signer == nil {
+		t.Fatalf("reload %s failed: %v", plain, err)
+	}
+	// 加密私钥无法复测，不能当作认证失败
+	if _, err := VerifyFinding("ssh private key", "127.0.0.1:1", "root/"+filepath.Join(dir, "id_secret")); err == nil || errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("got %v", err)
+	}
+}
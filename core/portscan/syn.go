@@ -0,0 +1,272 @@
+package portscan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"runtime"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/util"
+	"sync"
+	"time"
+)
+
+const (
+	tcpFlagSyn = 0x02
+	tcpFlagRst = 0x04
+	tcpFlagAck = 0x10
+)
+
+// SYN 扫描的最大发包速率，过大的速率会使发包间隔为 0 导致 time.NewTicker panic
+const maxSynRate = 100000
+
+// SynAvailable 仅 Linux 下以 root 权限运行时可以使用原始套接字进行 SYN 扫描
+func SynAvailable() bool {
+	return runtime.GOOS == "linux" && util.IsRoot()
+}
+
+type synProbe struct {
+	addr  Address
+	tries int
+	sent  time.Time
+}
+
+// SynScanner 使用原始套接字发送 SYN 包快速发现开放端口，收到 SYN/ACK 即认为端口开放
+type SynScanner struct {
+	conn    net.PacketConn
+	rate    int           // 每秒发包数
+	retries int           // 未响应端口的重传次数
+	timeout time.Duration // 等待响应的时间
+	srcPort uint16
+	seq     uint32
+
+	mutex   sync.Mutex
+	pending map[string]*synProbe
+	opened  sync.Map
+	srcIPs  sync.Map // 目标IP对应的本地出口IP，用于计算校验和
+}
+
+func NewSynScanner(rate, retries int, timeout time.Duration) (*SynScanner, error) {
+	if !SynAvailable() {
+		return nil, errors.New("syn scan requires root privileges on linux")
+	}
+	conn, err := net.ListenPacket("ip4:tcp", "0.0.0.0")
+	if err != nil {
+		return nil, err
+	}
+	if rate <= 0 {
+		rate = 1000
+	}
+	rate = min(rate, maxSynRate)
+	if retries < 0 {
+		retries = 0
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &SynScanner{
+		conn:    conn,
+		rate:    rate,
+		retries: retries,
+		timeout: timeout,
+		srcPort: uint16(40000 + rand.Intn(20000)),
+		seq:     rand.Uint32(),
+		pending: make(map[string]*synProbe),
+	}, nil
+}
+
+// Scan 发送 SYN 探测包，开放的端口会写入返回的通道，所有探测完成后通道关闭
+func (s *SynScanner) Scan(ctx, ctrlCtx context.Context, addresses <-chan Address) <-chan Address {
+	results := make(chan Address)
+	limiter := time.NewTicker(time.Second / time.Duration(s.rate))
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.receive(results, done)
+	}()
+	go func() {
+		defer func() {
+			limiter.Stop()
+			close(done)
+			wg.Wait() // 等待接收协程退出后再关闭结果通道
+			s.conn.Close()
+			close(results)
+		}()
+		lastCheck := time.Now()
+		for add := range addresses {
+			if ctrlCtx.Err() != nil {
+				return
+			}
+			// 原始套接字仅支持 IPv4，IPv6 地址直接交给全连接扫描
+			if ip := net.ParseIP(add.IP); ip == nil || ip.To4() == nil {
+				select {
+				case results <- add:
+				case <-ctrlCtx.Done():
+					return
+				}
+				continue
+			}
+			<-limiter.C
+			s.send(ctx, &synProbe{addr: add})
+			if time.Since(lastCheck) > 100*time.Millisecond {
+				s.retransmit(ctx, limiter)
+				lastCheck = time.Now()
+			}
+		}
+		// 输入结束后继续等待未响应的端口，直到重传次数用尽
+		for s.retransmit(ctx, limiter) > 0 {
+			if ctrlCtx.Err() != nil {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+	return results
+}
+
+func (s *SynScanner) send(ctx context.Context, p *synProbe) {
+	dst := net.ParseIP(p.addr.IP).To4()
+	if dst == nil {
+		return
+	}
+	src, err := sourceIP(&s.srcIPs, p.addr.IP)
+	if err != nil {
+		gologger.Debug(ctx, fmt.Sprintf("[syn] %s route err: %v", p.addr.IP, err))
+		return
+	}
+	packet := buildTCPPacket(src, dst, s.srcPort, uint16(p.addr.Port), s.seq, 0, tcpFlagSyn)
+	if _, err = s.conn.WriteTo(packet, &net.IPAddr{IP: dst}); err != nil {
+		gologger.Debug(ctx, fmt.Sprintf("[syn] send to %s:%d err: %v", p.addr.IP, p.addr.Port, err))
+	}
+	p.tries++
+	p.sent = time.Now()
+	s.mutex.Lock()
+	s.pending[addressKey(p.addr)] = p
+	s.mutex.Unlock()
+}
+
+// 重传超时未响应的探测包，返回仍在等待响应的数量
+func (s *SynScanner) retransmit(ctx context.Context, limiter *time.Ticker) int {
+	var resend []*synProbe
+	s.mutex.Lock()
+	for key, p := range s.pending {
+		if _, ok := s.opened.Load(key); ok {
+			delete(s.pending, key)
+			continue
+		}
+		if time.Since(p.sent) < s.timeout {
+			continue
+		}
+		if p.tries > s.retries {
+			delete(s.pending, key)
+			continue
+		}
+		resend = append(resend, p)
+	}
+	s.mutex.Unlock()
+	for _, p := range resend {
+		<-limiter.C
+		s.send(ctx, p)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.pending)
+}
+
+func (s *SynScanner) receive(results chan<- Address, done <-chan struct{}) {
+	buf := make([]byte, 1500)
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		s.conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		n, from, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			continue
+		}
+		// Linux 下 ip4 原始套接字读取时已去除 IP 头部
+		if n < 20 {
+			continue
+		}
+		srcPort := binary.BigEndian.Uint16(buf[0:2])
+		dstPort := binary.BigEndian.Uint16(buf[2:4])
+		ack := binary.BigEndian.Uint32(buf[8:12])
+		flags := buf[13]
+		if dstPort != s.srcPort || ack != s.seq+1 {
+			continue
+		}
+		if flags&tcpFlagSyn == 0 || flags&tcpFlagAck == 0 || flags&tcpFlagRst != 0 {
+			continue
+		}
+		add := Address{IP: from.(*net.IPAddr).IP.String(), Port: int(srcPort), Open: true}
+		if _, loaded := s.opened.LoadOrStore(addressKey(add), true); loaded {
+			continue
+		}
+		select {
+		case results <- add:
+		case <-done:
+			return
+		}
+	}
+}
+
+func sourceIP(cache *sync.Map, ip string) (net.IP, error) {
+	if src, ok := cache.Load(ip); ok {
+		return src.(net.IP), nil
+	}
+	// UDP 拨号不会发送数据包，仅用于获取路由对应的本地地址
+	conn, err := net.Dial("udp4", net.JoinHostPort(ip, "80"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	src := conn.LocalAddr().(*net.UDPAddr).IP.To4()
+	cache.Store(ip, src)
+	return src, nil
+}
+
+func addressKey(add Address) string {
+	return net.JoinHostPort(add.IP, fmt.Sprint(add.Port))
+}
+
+// 构造不含 IP 头部的 TCP 报文，IP 头部由内核填充
+func buildTCPPacket(src, dst net.IP, srcPort, dstPort uint16, seq, ack uint32, flags byte) []byte {
+	tcp := make([]byte, 24)
+	binary.BigEndian.PutUint16(tcp[0:2], srcPort)
+	binary.BigEndian.PutUint16(tcp[2:4], dstPort)
+	binary.BigEndian.PutUint32(tcp[4:8], seq)
+	binary.BigEndian.PutUint32(tcp[8:12], ack)
+	tcp[12] = 6 << 4 // 数据偏移，包含 4 字节的 MSS 选项
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:16], 1024) // 窗口大小
+	copy(tcp[20:], []byte{0x02, 0x04, 0x05, 0xb4})
+	binary.BigEndian.PutUint16(tcp[16:18], tcpChecksum(src, dst, tcp))
+	return tcp
+}
+
+func tcpChecksum(src, dst net.IP, tcp []byte) uint16 {
+	pseudo := make([]byte, 0, 12+len(tcp))
+	pseudo = append(pseudo, src.To4()...)
+	pseudo = append(pseudo, dst.To4()...)
+	pseudo = append(pseudo, 0, 6)
+	pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(len(tcp)))
+	pseudo = append(pseudo, tcp...)
+	var sum uint32
+	for i := 0; i+1 < len(pseudo); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(pseudo[i:]))
+	}
+	if len(pseudo)%2 == 1 {
+		sum += uint32(pseudo[len(pseudo)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
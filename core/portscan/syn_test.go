@@ -0,0 +1,15 @@
+package portscan
+
+import (
+	"net"
+	"testing"
+)
+
+func TestTcpChecksum(t *testing.T) {
+	src, dst := net.ParseIP("192.168.1.2"), net.ParseIP("192.168.1.1")
+	packet := buildTCPPacket(src, dst, 40000, 80, 1, 0, tcpFlagSyn)
+	// 携带正确校验和的报文重新计算校验和结果应为 0
+	if tcpChecksum(src, dst, packet) != 0 {
+		t.Fatal("invalid tcp checksum")
+	}
+}
@@ -1,45 +1,33 @@
 package portscan
 
 import (
-	"context"
-	"fmt"
-	"slack-wails/lib/gologger"
 	"slack-wails/lib/gotelnet"
-	"slack-wails/lib/structs"
-	"strconv"
-	"strings"
-
-	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"slack-wails/lib/util"
+	"sync"
 )
 
-func TelnetScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	h := strings.Split(host, ":")[0]
-	p, _ := strconv.Atoi(strings.Split(host, ":")[1])
-	serverType := getTelnetServerType(h, p)
-	for _, user := range usernames {
-		for _, pass := range passwords {
-			if ctrlCtx.Err() != nil {
-				gologger.Warning(ctx, "[telnet] User exits crack scanning")
-				return
-			}
-			pass = strings.Replace(pass, "{user}", user, -1)
-			flag, err := TelnetConn(h, user, pass, p, serverType)
-			if flag && err == nil {
-				runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-					TaskId:   taskId,
-					ID:       "telnet weak password",
-					Name:     "telnet weak password",
-					URL:      host,
-					Type:     "Telnet",
-					Severity: "HIGH",
-					Extract:  user + "/" + pass,
-				})
-				return
-			} else {
-				gologger.Info(ctx, fmt.Sprintf("telnet://%s %s:%s is login failed", host, user, pass))
-			}
-		}
+var telnetModule = &BruteModule{
+	Name: "telnet",
+	Type: "Telnet",
+	Auth: &telnetAuthenticator{},
+}
+
+// 缓存每个主机的服务器类型，避免每次尝试都重新探测
+type telnetAuthenticator struct {
+	serverTypes sync.Map
+}
+
+func (t *telnetAuthenticator) Auth(host, user, pass string) (bool, string, error) {
+	h, p, err := util.SplitHostPort(host)
+	if err != nil {
+		return false, "", err
+	}
+	serverType, ok := t.serverTypes.Load(host)
+	if !ok {
+		serverType, _ = t.serverTypes.LoadOrStore(host, getTelnetServerType(h, p))
 	}
+	flag, err := TelnetConn(h, user, pass, p, serverType.(int))
+	return flag, "", err
 }
 
 func getTelnetServerType(ip string, port int) int {
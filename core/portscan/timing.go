@@ -0,0 +1,157 @@
+package portscan
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// TimingProfile 参考 nmap 的 -T1 ~ -T5 时序模板
+type TimingProfile struct {
+	Name            string
+	InitialRTT      time.Duration // 没有 RTT 样本时使用的超时时间
+	MinRTT          time.Duration
+	MaxRTT          time.Duration
+	MaxRetries      int           // 超时(被过滤)端口的重传次数
+	HostParallelism int           // 单个主机同时探测的端口数
+	ScanDelay       time.Duration // 同一主机两次探测之间的间隔
+}
+
+var TimingProfiles = map[int]TimingProfile{
+	1: {Name: "sneaky", InitialRTT: 3 * time.Second, MinRTT: time.Second, MaxRTT: 10 * time.Second, MaxRetries: 3, HostParallelism: 1, ScanDelay: time.Second},
+	2: {Name: "polite", InitialRTT: 2 * time.Second, MinRTT: 500 * time.Millisecond, MaxRTT: 5 * time.Second, MaxRetries: 2, HostParallelism: 2, ScanDelay: 200 * time.Millisecond},
+	3: {Name: "normal", InitialRTT: time.Second, MinRTT: 100 * time.Millisecond, MaxRTT: 3 * time.Second, MaxRetries: 1, HostParallelism: 10},
+	4: {Name: "aggressive", InitialRTT: 500 * time.Millisecond, MinRTT: 100 * time.Millisecond, MaxRTT: 1250 * time.Millisecond, MaxRetries: 1, HostParallelism: 50},
+	5: {Name: "insane", InitialRTT: 250 * time.Millisecond, MinRTT: 50 * time.Millisecond, MaxRTT: 300 * time.Millisecond, MaxRetries: 0, HostParallelism: 100},
+}
+
+type hostState struct {
+	mutex    sync.Mutex
+	srtt     time.Duration
+	rttvar   time.Duration
+	samples  int
+	lastSend time.Time
+	slots    chan struct{}
+}
+
+// HostTiming 按主机记录 RTT，计算自适应超时并限制单主机并发
+type HostTiming struct {
+	profile TimingProfile
+	hosts   sync.Map
+}
+
+func NewHostTiming(level int) *HostTiming {
+	profile, ok := TimingProfiles[level]
+	if !ok {
+		profile = TimingProfiles[3]
+	}
+	return &HostTiming{profile: profile}
+}
+
+func (t *HostTiming) state(host string) *hostState {
+	if s, ok := t.hosts.Load(host); ok {
+		return s.(*hostState)
+	}
+	s, _ := t.hosts.LoadOrStore(host, &hostState{slots: make(chan struct{}, t.profile.HostParallelism)})
+	return s.(*hostState)
+}
+
+// Acquire 占用主机的并发名额，并按时序模板控制探测间隔
+func (t *HostTiming) Acquire(host string) {
+	s := t.state(host)
+	s.slots <- struct{}{}
+	if t.profile.ScanDelay <= 0 {
+		return
+	}
+	s.mutex.Lock()
+	wait := time.Until(s.lastSend.Add(t.profile.ScanDelay))
+	if wait < 0 {
+		wait = 0
+	}
+	s.lastSend = time.Now().Add(wait)
+	s.mutex.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+func (t *HostTiming) Release(host string) {
+	<-t.state(host).slots
+}
+
+// Timeout 根据平滑 RTT 计算超时时间(RFC 6298)，限制在模板的上下限之间
+func (t *HostTiming) Timeout(host string) time.Duration {
+	s := t.state(host)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.samples == 0 {
+		return t.profile.InitialRTT
+	}
+	return clampDuration(s.srtt+4*s.rttvar, t.profile.MinRTT, t.profile.MaxRTT)
+}
+
+// ServiceTimeout 服务识别需要等待 banner，以用户设置的超时为下限，网络延迟较大时按 RTT 延长
+func (t *HostTiming) ServiceTimeout(host string, floor time.Duration) time.Duration {
+	return max(10*t.Timeout(host), floor, time.Second)
+}
+
+func (t *HostTiming) observe(host string, rtt time.Duration) {
+	s := t.state(host)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.samples == 0 {
+		s.srtt, s.rttvar = rtt, rtt/2
+	} else {
+		diff := s.srtt - rtt
+		if diff < 0 {
+			diff = -diff
+		}
+		s.rttvar = (3*s.rttvar + diff) / 4
+		s.srtt = (7*s.srtt + rtt) / 8
+	}
+	s.samples++
+}
+
+// Probe 使用自适应超时探测端口是否开放，连接被拒绝说明端口关闭，
+// 超时说明端口可能被过滤，超时时间翻倍后重传
+func (t *HostTiming) Probe(host string, port int) bool {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	timeout := t.Timeout(host)
+	for try := 0; try <= t.profile.MaxRetries; try++ {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err == nil {
+			t.observe(host, time.Since(start))
+			conn.Close()
+			return true
+		}
+		if isConnRefused(err) {
+			t.observe(host, time.Since(start))
+			return false
+		}
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			return false
+		}
+		timeout = clampDuration(timeout*2, 0, t.profile.MaxRTT)
+	}
+	return false
+}
+
+func isConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(err.Error(), "refused")
+}
+
+func clampDuration(d, lower, upper time.Duration) time.Duration {
+	if d > upper {
+		d = upper
+	}
+	if d < lower {
+		d = lower
+	}
+	return d
+}
@@ -0,0 +1,38 @@
+package portscan
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHostTiming(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	timing := NewHostTiming(4)
+	if timing.Timeout("127.0.0.1") != TimingProfiles[4].InitialRTT {
+		t.Fatal("initial timeout should come from the timing profile")
+	}
+	if !timing.Probe("127.0.0.1", port) {
+		t.Fatal("listening port should be open")
+	}
+	l.Close()
+	if timing.Probe("127.0.0.1", port) {
+		t.Fatal("closed port should be refused")
+	}
+	// 本地回环的 RTT 很小，超时时间应收敛到模板的下限
+	if timing.Timeout("127.0.0.1") != TimingProfiles[4].MinRTT {
+		t.Fatalf("unexpected adaptive timeout: %v", timing.Timeout("127.0.0.1"))
+	}
+	if timing.ServiceTimeout("127.0.0.1", 5*time.Second) != 5*time.Second {
+		t.Fatal("service timeout should not be shorter than the user timeout")
+	}
+	if slow := NewHostTiming(1); slow.ServiceTimeout("127.0.0.1", 5*time.Second) != 30*time.Second {
+		t.Fatal("service timeout should grow with the initial RTT")
+	}
+}
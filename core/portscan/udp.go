@@ -0,0 +1,67 @@
+package portscan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slack-wails/lib/gonmap"
+	"slack-wails/lib/structs"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// UdpScan 使用协议相关的 UDP 探针识别服务，只有收到响应的端口才会作为结果返回
+func UdpScan(ctx, ctrlCtx context.Context, taskId string, addresses <-chan Address, workers, timeout int) {
+	var id int32
+	var wg sync.WaitGroup
+	threadPool, _ := ants.NewPoolWithFunc(workers, func(ipaddr interface{}) {
+		defer wg.Done()
+		defer func() {
+			atomic.AddInt32(&id, 1)
+			runtime.EventsEmit(ctx, "progressID", id)
+		}()
+		if ctrlCtx.Err() != nil {
+			return
+		}
+		add := ipaddr.(Address)
+		if pr := UdpConnect(taskId, add.IP, add.Port, timeout); pr != nil {
+			runtime.EventsEmit(ctx, "webFingerScan", pr)
+		}
+	})
+	defer threadPool.Release()
+	for add := range addresses {
+		if ctrlCtx.Err() != nil {
+			return
+		}
+		wg.Add(1)
+		threadPool.Invoke(add)
+	}
+	wg.Wait()
+}
+
+func UdpConnect(taskId, ip string, port, timeout int) *structs.InfoResult {
+	scanner := gonmap.New()
+	status, response := scanner.ScanUDP(ip, port, time.Second*time.Duration(timeout))
+	if status != gonmap.Matched && status != gonmap.NotMatched {
+		return nil
+	}
+	scheme := "unknow"
+	if response != nil && response.FingerPrint.Service != "" {
+		scheme = gonmap.FixProtocol(response.FingerPrint.Service)
+	}
+	result := &structs.InfoResult{
+		TaskId: taskId,
+		Host:   ip,
+		Port:   port,
+		Scheme: scheme,
+		URL:    fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(ip, strconv.Itoa(port))),
+		Detect: "UDP",
+	}
+	setServiceInfo(result, response)
+	return result
+}
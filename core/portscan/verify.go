@@ -0,0 +1,95 @@
+package portscan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrAuthFailed 复测时目标可以连接但认证失败，说明弱口令或未授权访问已修复
+var ErrAuthFailed = errors.New("authentication failed")
+
+// IsBuiltinFinding 判断漏洞是否由端口爆破模块产生
+func IsBuiltinFinding(id string) bool {
+	return strings.HasSuffix(id, " weak password") || strings.HasSuffix(id, " unauthorized") || id == sshModule.Name+" "+sshKeyKind
+}
+
+// VerifyFinding 使用扫描时记录的凭据重新验证弱口令或未授权访问是否仍然存在，
+// 漏洞ID中的协议名称对应暴破模块，新增的模块不需要单独注册复测方法
+func VerifyFinding(id, host, extract string) (bool, error) {
+	if !IsBuiltinFinding(id) {
+		return false, errors.New("not a brute force finding: " + id)
+	}
+	if id == sshModule.Name+" "+sshKeyKind {
+		user, keyFile, _ := strings.Cut(extract, "/")
+		signer, err := loadSshKey(keyFile)
+		if err != nil {
+			return false, err
+		}
+		return verifyResult(SshKeyConn(host, user, signer))
+	}
+	module, unauth := findingModule(id)
+	if module == nil {
+		return false, fmt.Errorf("no verify module registered for: %s", id)
+	}
+	if !unauth {
+		user, pass := module.DefaultUser, extract
+		if module.DefaultUser == "" {
+			user, pass, _ = strings.Cut(extract, "/")
+		}
+		ok, _, err := module.Auth.Auth(host, user, pass)
+		return verifyResult(ok, err)
+	}
+	if ok, _ := module.Unauth(host); ok {
+		return true, nil
+	}
+	// Unauth 不返回错误，目标无法连接时作为复测出错，不能判断为已修复
+	conn, err := WrapperTcpWithTimeout("tcp", host, 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	conn.Close()
+	return false, fmt.Errorf("%w: unauthorized access is no longer available", ErrAuthFailed)
+}
+
+// 根据漏洞ID找到产生该结果的暴破模块，unauth 表示未授权访问的结果
+func findingModule(id string) (module *BruteModule, unauth bool) {
+	if protocol, ok := strings.CutSuffix(id, " weak password"); ok {
+		if module = bruteModules[crackScheme(protocol)]; module != nil && module.Auth != nil {
+			return module, false
+		}
+	}
+	if protocol, ok := strings.CutSuffix(id, " unauthorized"); ok {
+		if module = bruteModules[crackScheme(protocol)]; module != nil && module.Unauth != nil {
+			return module, true
+		}
+	}
+	return nil, false
+}
+
+// 连接失败、超时等无法得出结论的错误原样返回，其他错误视为认证失败
+func verifyResult(ok bool, err error) (bool, error) {
+	if ok || err == nil || isNetworkError(err) {
+		return ok, err
+	}
+	return false, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+}
+
+// 部分驱动只保留错误信息而不包装原始错误，因此同时匹配错误类型与常见的错误信息
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || isConnRefused(err) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, keyword := range []string{"timeout", "timed out", "no route to host", "network is unreachable", "connection reset", "broken pipe", "no such host", "eof"} {
+		if strings.Contains(msg, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,40 +1,19 @@
 package portscan
 
 import (
-	"context"
-	"fmt"
-	"slack-wails/lib/gologger"
-	"slack-wails/lib/structs"
-	"strings"
 	"time"
 
 	"github.com/mitchellh/go-vnc"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-func VncScan(ctx, ctrlCtx context.Context, taskId, host string, usernames, passwords []string) {
-	for _, pass := range passwords {
-		if ctrlCtx.Err() != nil {
-			gologger.Warning(ctx, "[vnc] User exits crack scanning")
-			return
-		}
-		pass = strings.Replace(pass, "{user}", "vnc", -1)
+var vncModule = &BruteModule{
+	Name:        "vnc",
+	Type:        "VNC",
+	DefaultUser: "vnc",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
 		flag, err := VncConn(host, pass)
-		if flag && err == nil {
-			runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-				TaskId:   taskId,
-				ID:       "vnc weak password",
-				Name:     "vnc weak password",
-				URL:      host,
-				Type:     "VNC",
-				Severity: "HIGH",
-				Extract:  pass,
-			})
-			return
-		} else {
-			gologger.Info(ctx, fmt.Sprintf("vnc://%s %s is login failed", host, pass))
-		}
-	}
+		return flag, "", err
+	}),
 }
 
 func VncConn(host, pass string) (flag bool, err error) {
@@ -0,0 +1,100 @@
+package portscan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slack-wails/lib/clients"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-ntlmssp"
+)
+
+var winrmModule = &BruteModule{
+	Name:     "winrm",
+	Type:     "WinRM",
+	Severity: "CRITICAL",
+	Auth: AuthFunc(func(host, user, pass string) (bool, string, error) {
+		flag, err := WinrmConn(host, user, pass)
+		return flag, "", err
+	}),
+}
+
+// 已确认未认证请求会返回 NTLM/Negotiate 质询的主机
+var winrmHosts sync.Map
+
+// WinrmConn 通过 NTLM 认证访问 /wsman，用户名可以是 DOMAIN\user 格式，5986 端口使用 https
+func WinrmConn(host, user, pass string) (bool, error) {
+	scheme := "http"
+	if portOf(host) == 5986 {
+		scheme = "https"
+	}
+	target := fmt.Sprintf("%s://%s/wsman", scheme, host)
+	// NTLM 握手需要复用同一个连接，不能使用带 Connection: close 的公共客户端；
+	// 认证成功的连接会保持已认证状态，因此每组凭据使用新的连接，结束后关闭
+	transport := &http.Transport{TLSClientConfig: clients.TlsConfig}
+	defer transport.CloseIdleConnections()
+	if err := winrmChallenge(host, target, transport); err != nil {
+		return false, err
+	}
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: ntlmssp.Negotiator{RoundTripper: transport},
+	}
+	req, err := newWinrmRequest(target)
+	if err != nil {
+		return false, err
+	}
+	req.SetBasicAuth(user, pass)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	// 认证通过后空请求会返回 500 等错误，握手后仍为 401 表示认证失败
+	if resp.StatusCode == http.StatusUnauthorized {
+		return false, fmt.Errorf("unauthorized")
+	}
+	return true, nil
+}
+
+// 未认证的请求需要返回 401 以及 NTLM 或 Negotiate 质询，否则握手后的状态码不能说明认证成功
+func winrmChallenge(host, target string, transport http.RoundTripper) error {
+	if _, ok := winrmHosts.Load(host); ok {
+		return nil
+	}
+	req, err := newWinrmRequest(target)
+	if err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second, Transport: transport}).Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("unexpected status %d without credentials", resp.StatusCode)
+	}
+	for _, value := range resp.Header.Values("WWW-Authenticate") {
+		value = strings.ToLower(value)
+		if strings.HasPrefix(value, "negotiate") || strings.HasPrefix(value, "ntlm") {
+			winrmHosts.Store(host, true)
+			return nil
+		}
+	}
+	return errors.New("no NTLM or Negotiate challenge")
+}
+
+func newWinrmRequest(target string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(""))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	return req, nil
+}
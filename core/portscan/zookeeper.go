@@ -0,0 +1,41 @@
+package portscan
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Zookeeper 没有账号密码认证，只检测四字命令是否可以未授权执行
+var zookeeperModule = &BruteModule{
+	Name: "zookeeper",
+	Type: "Zookeeper",
+	Unauth: func(host string) (bool, string) {
+		result, err := ZookeeperUnauth(host)
+		return err == nil, result
+	},
+}
+
+// ZookeeperUnauth 执行 envi 命令获取服务器环境信息，3.5 之后未加入白名单的命令会被拒绝
+func ZookeeperUnauth(host string) (string, error) {
+	conn, err := dialService(host, false, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte("envi")); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(io.LimitReader(conn, 65536))
+	if len(data) == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return "", err
+	}
+	if !strings.Contains(string(data), "Environment") {
+		return "", errors.New("unexpected response")
+	}
+	return string(data), nil
+}
@@ -2,28 +2,26 @@ package core
 
 import (
 	"fmt"
-	"os"
+	"net"
 	"path"
 	"runtime"
 	"slack-wails/lib/util"
 	"strconv"
+	"strings"
 )
 
 type Tools struct{}
 
 func (t *Tools) IsRoot() bool {
-	if runtime.GOOS == "windows" {
-		_, err := os.Open("\\\\.\\PHYSICALDRIVE0")
-		return err == nil
-	} else {
-		return os.Getuid() == 0
-	}
+	return util.IsRoot()
 }
 
 func (t *Tools) GOOS() string {
 	return runtime.GOOS
 }
-func (t *Tools) IPParse(ipList []string) []string {
+
+// IPParse 目标格式错误时返回错误，由前端提示具体的目标
+func (t *Tools) IPParse(ipList []string) ([]string, error) {
 	return util.ParseIPs(ipList)
 }
 
@@ -42,6 +40,16 @@ func (t *Tools) ExtractIP(text string) string {
 		IP_analysis[ip+".0"]++
 	}
 	result += "共计提取到IP资产" + strconv.Itoa(len(deupIPs)) + "个\n"
+	var ipv6s []string
+	for _, ip := range util.RemoveDuplicates(util.RegIPv6.FindAllString(text, -1)) {
+		if parsed := net.ParseIP(ip); parsed != nil && !parsed.IsUnspecified() {
+			ipv6s = append(ipv6s, ip)
+		}
+	}
+	if len(ipv6s) > 0 {
+		result += "\n\n\n---提取IPv6资产---\n" + strings.Join(ipv6s, "\n") + "\n"
+		result += "共计提取到IPv6资产" + strconv.Itoa(len(ipv6s)) + "个\n"
+	}
 	result += "\n\n\n---提取C段资产---\n"
 	for _, p := range util.SortMap(IP_analysis) {
 		result += fmt.Sprintf("%v/24(%v)\n", p.Key, p.Value)
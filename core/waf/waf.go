@@ -1,6 +1,7 @@
 package waf
 
 import (
+	"net"
 	"slack-wails/lib/netutil"
 	"slack-wails/lib/util"
 	"strings"
@@ -41,7 +42,7 @@ type WAF struct {
 // waf 识别
 func ResolveAndWafIdentify(host string, dnsServers []string) *WAF {
 	// 如果是IP则直接返回
-	if util.RegIP.MatchString(host) {
+	if util.RegIP.MatchString(host) || net.ParseIP(strings.Trim(host, "[]")) != nil {
 		return &WAF{}
 	}
 	cnames, err := netutil.LookupCNAME(host, dnsServers, 3)
@@ -8,6 +8,7 @@ import (
 	"runtime/debug"
 	"slack-wails/lib/clients"
 	"slack-wails/lib/gologger"
+	"slack-wails/lib/report"
 	"slack-wails/lib/structs"
 	"slack-wails/lib/util"
 	"strings"
@@ -42,25 +43,7 @@ func NewNucleiEngine(ctx, ctrlCtx context.Context, taskId string, allOptions []s
 		gologger.DualLog(ctx, gologger.Level_INFO, fmt.Sprintf("[nuclei] check vuln: %s", o.URL))
 		ne.LoadTargets([]string{o.URL}, false)
 		err = ne.ExecuteWithCallback(func(event *output.ResultEvent) {
-			gologger.DualLog(ctx, gologger.Level_Success, fmt.Sprintf("[%s] [%s] %s", event.TemplateID, event.Info.SeverityHolder.Severity.String(), event.Matched))
-			var reference string
-			if event.Info.Reference != nil && !event.Info.Reference.IsEmpty() {
-				reference = strings.Join(event.Info.Reference.ToSlice(), ",")
-			}
-			runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-				TaskId:       taskId,
-				ID:           event.TemplateID,
-				Name:         event.Info.Name,
-				Description:  event.Info.Description,
-				Reference:    reference,
-				URL:          showMatched(event),
-				Request:      showRequest(event),
-				Response:     showResponse(event),
-				ResponseTime: limitDecimalPlaces(event.ResponseTime),
-				Extract:      strings.Join(event.ExtractedResults, " | "),
-				Type:         strings.ToUpper(event.Type),
-				Severity:     strings.ToUpper(event.Info.SeverityHolder.Severity.String()),
-			})
+			emitVulnerability(ctx, taskId, event)
 		})
 		if err != nil {
 			gologger.DualLog(ctx, gologger.Level_ERROR, fmt.Sprintf("[nuclei] execute callback err: %v", err))
@@ -86,25 +69,7 @@ func NewThreadSafeNucleiEngine(ctx, ctrlCtx context.Context, taskId string, allO
 	}
 	gologger.DualLog(ctx, gologger.Level_INFO, fmt.Sprintf("[nuclei] loading %d targets to scan", count))
 	ne.GlobalResultCallback(func(event *output.ResultEvent) {
-		gologger.DualLog(ctx, gologger.Level_Success, fmt.Sprintf("[%s] [%s] %s", event.TemplateID, event.Info.SeverityHolder.Severity.String(), event.Matched))
-		var reference string
-		if event.Info.Reference != nil && !event.Info.Reference.IsEmpty() {
-			reference = strings.Join(event.Info.Reference.ToSlice(), ",")
-		}
-		runtime.EventsEmit(ctx, "nucleiResult", structs.VulnerabilityInfo{
-			TaskId:       taskId,
-			ID:           event.TemplateID,
-			Name:         event.Info.Name,
-			Description:  event.Info.Description,
-			Reference:    reference,
-			URL:          showMatched(event),
-			Request:      showRequest(event),
-			Response:     showResponse(event),
-			ResponseTime: limitDecimalPlaces(event.ResponseTime),
-			Extract:      strings.Join(event.ExtractedResults, " | "),
-			Type:         strings.ToUpper(event.Type),
-			Severity:     strings.ToUpper(event.Info.SeverityHolder.Severity.String()),
-		})
+		emitVulnerability(ctx, taskId, event)
 	})
 
 	// 提交扫描任务
@@ -218,6 +183,52 @@ func finalTags(detectTags, customTags []string) []string {
 	return detectTags
 }
 
+// 推送漏洞结果到前端，已标记为误报的漏洞不再重复上报
+func emitVulnerability(ctx context.Context, taskId string, event *output.ResultEvent) {
+	vuln := NewVulnerabilityInfo(taskId, event)
+	if IsIgnoredFinding(vuln.Fingerprint) {
+		gologger.DualLog(ctx, gologger.Level_INFO, fmt.Sprintf("[%s] %s has been marked as false positive, skipped", event.TemplateID, vuln.URL))
+		return
+	}
+	gologger.DualLog(ctx, gologger.Level_Success, fmt.Sprintf("[%s] [%s] %s", event.TemplateID, event.Info.SeverityHolder.Severity.String(), event.Matched))
+	runtime.EventsEmit(ctx, "nucleiResult", vuln)
+}
+
+// 将 nuclei 的扫描结果转换为前端以及数据库使用的漏洞结构
+func NewVulnerabilityInfo(taskId string, event *output.ResultEvent) structs.VulnerabilityInfo {
+	var reference string
+	if event.Info.Reference != nil && !event.Info.Reference.IsEmpty() {
+		reference = strings.Join(event.Info.Reference.ToSlice(), ",")
+	}
+	vuln := structs.VulnerabilityInfo{
+		TaskId:       taskId,
+		ID:           event.TemplateID,
+		Name:         event.Info.Name,
+		Description:  event.Info.Description,
+		Reference:    reference,
+		URL:          showMatched(event),
+		Request:      showRequest(event),
+		Response:     showResponse(event),
+		ResponseTime: limitDecimalPlaces(event.ResponseTime),
+		Extract:      strings.Join(event.ExtractedResults, " | "),
+		MatcherName:  event.MatcherName,
+		Type:         strings.ToUpper(event.Type),
+		Severity:     strings.ToUpper(event.Info.SeverityHolder.Severity.String()),
+		Status:       FindingStatusNew,
+	}
+	vuln.Fingerprint = VulnerabilityFingerprint(vuln)
+	if c := event.Info.Classification; c != nil {
+		vuln.CVE = strings.Join(c.CVEID.ToSlice(), ",")
+		vuln.CWE = strings.Join(c.CWEID.ToSlice(), ",")
+		vuln.CVSSScore = c.CVSSScore
+		vuln.CVSSVector = c.CVSSMetrics
+		vuln.EPSSScore = c.EPSSScore
+		vuln.EPSSPercentile = c.EPSSPercentile
+	}
+	vuln.RiskScore = report.FindingRisk(vuln)
+	return vuln
+}
+
 func showMatched(event *output.ResultEvent) string {
 	if event.Matched != "" {
 		return event.Matched
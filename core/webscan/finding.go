@@ -0,0 +1,126 @@
+package webscan
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"slack-wails/lib/structs"
+	"strings"
+	"sync"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// 漏洞的研判状态
+const (
+	FindingStatusNew           = "new"
+	FindingStatusConfirmed     = "confirmed"
+	FindingStatusFalsePositive = "false_positive"
+	FindingStatusFixed         = "fixed"
+)
+
+// 漏洞复测结果
+const (
+	VerifyResultVulnerable    = "vulnerable"
+	VerifyResultNotVulnerable = "not_vulnerable"
+	VerifyResultError         = "error"
+)
+
+var FindingStatus = []string{FindingStatusNew, FindingStatusConfirmed, FindingStatusFalsePositive, FindingStatusFixed}
+
+// 已被标记为误报的漏洞指纹，重复扫描时不再上报
+var ignoredFindings sync.Map
+
+// FindingFingerprint 根据模板ID、规范化后的主机以及区分字段生成漏洞的唯一标识，
+// 同一漏洞在不同任务、不同路径参数下的重复结果会得到相同的指纹
+func FindingFingerprint(templateId, target, discriminator string) string {
+	h := sha1.New()
+	h.Write([]byte(strings.ToLower(templateId) + "|" + normalizeHost(target) + "|" + strings.ToLower(discriminator)))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// VulnerabilityFingerprint 扫描、入库、补全旧记录以及复测统一使用的指纹，只依赖数据库中保存的字段，
+// 保证旧记录补全的指纹与重新扫描得到的指纹一致。模板漏洞以匹配器名称区分，
+// 同一主机可能暴破出多个账号，弱口令和私钥登录以提取的账号区分
+func VulnerabilityFingerprint(v structs.VulnerabilityInfo) string {
+	discriminator := v.MatcherName
+	if strings.HasSuffix(v.ID, " weak password") || strings.HasSuffix(v.ID, " private key") {
+		discriminator = v.Extract
+	}
+	return FindingFingerprint(v.ID, v.URL, discriminator)
+}
+
+// 统一为小写的 host:port，缺省端口根据协议补全
+func normalizeHost(target string) string {
+	target = strings.TrimSpace(target)
+	if !strings.Contains(target, "://") {
+		target = "tcp://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(target)
+	}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		switch strings.ToLower(u.Scheme) {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func IsValidFindingStatus(status string) bool {
+	for _, s := range FindingStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFindingStatus 同步漏洞研判状态，误报的漏洞在后续扫描中会被忽略
+func SetFindingStatus(fingerprint, status string) {
+	if status == FindingStatusFalsePositive {
+		ignoredFindings.Store(fingerprint, struct{}{})
+	} else {
+		ignoredFindings.Delete(fingerprint)
+	}
+}
+
+func IsIgnoredFinding(fingerprint string) bool {
+	_, ok := ignoredFindings.Load(fingerprint)
+	return ok
+}
+
+// 漏洞记录的是命中的完整地址，复测时还原为 scheme://host:port，由模板重新拼接请求路径
+func findingRoot(target string) string {
+	u, err := url.Parse(strings.TrimSpace(target))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return target
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// RecheckFinding 仅使用漏洞对应的单个模板对目标重新扫描，用于修复后的复测
+func RecheckFinding(ctx context.Context, o structs.NucleiOption) (bool, error) {
+	ne, err := nuclei.NewNucleiEngineCtx(ctx, NewNucleiSDKOptions(o)...)
+	if err != nil {
+		return false, err
+	}
+	defer ne.Close()
+	ne.LoadTargets([]string{findingRoot(o.URL)}, false)
+	var matched bool
+	err = ne.ExecuteCallbackWithCtx(ctx, func(event *output.ResultEvent) {
+		matched = true
+	})
+	return matched, err
+}
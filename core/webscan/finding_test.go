@@ -0,0 +1,55 @@
+package webscan
+
+import (
+	"slack-wails/lib/structs"
+	"testing"
+)
+
+func TestFindingFingerprint(t *testing.T) {
+	a := FindingFingerprint("thinkphp-5023-rce", "http://Example.com/index.php?s=captcha", "")
+	b := FindingFingerprint("thinkphp-5023-rce", "http://example.com:80/", "")
+	if a != b {
+		t.Fatal("same host with default port should have the same fingerprint")
+	}
+	if a == FindingFingerprint("thinkphp-5023-rce", "https://example.com", "") {
+		t.Fatal("different port should have different fingerprint")
+	}
+	if FindingFingerprint("redis-unauth", "example.com:6379", "") != FindingFingerprint("redis-unauth", "tcp://example.com:6379", "") {
+		t.Fatal("network target without scheme should be normalized")
+	}
+}
+
+func TestVulnerabilityFingerprint(t *testing.T) {
+	// 补全旧记录时只有数据库中的字段，需要与扫描时生成的指纹一致
+	scanned := structs.VulnerabilityInfo{ID: "thinkphp-5023-rce", URL: "http://example.com/index.php?s=captcha", Name: "ThinkPHP 5.0.23 RCE", Extract: "uid=0(root)", MatcherName: "rce"}
+	stored := structs.VulnerabilityInfo{ID: "thinkphp-5023-rce", URL: "http://example.com:80/", MatcherName: "rce"}
+	if VulnerabilityFingerprint(scanned) != VulnerabilityFingerprint(stored) {
+		t.Fatal("stored finding should have the same fingerprint as the scanned one")
+	}
+	// 同一模板的不同匹配器是不同的漏洞
+	other := structs.VulnerabilityInfo{ID: "thinkphp-5023-rce", URL: "http://example.com/", MatcherName: "info-leak"}
+	if VulnerabilityFingerprint(other) == VulnerabilityFingerprint(stored) {
+		t.Fatal("different matchers should have different fingerprints")
+	}
+}
+
+func TestFindingRoot(t *testing.T) {
+	tests := map[string]string{
+		"http://example.com:8080/index.php?s=captcha": "http://example.com:8080",
+		"https://example.com/api/v1":                  "https://example.com",
+		"example.com:6379":                            "example.com:6379",
+	}
+	for target, want := range tests {
+		if got := findingRoot(target); got != want {
+			t.Errorf("findingRoot(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestWeakPasswordFingerprint(t *testing.T) {
+	admin := structs.VulnerabilityInfo{ID: "ssh weak password", URL: "10.0.0.1:22", Extract: "admin/admin"}
+	root := structs.VulnerabilityInfo{ID: "ssh weak password", URL: "10.0.0.1:22", Extract: "root/123456"}
+	if VulnerabilityFingerprint(admin) == VulnerabilityFingerprint(root) {
+		t.Fatal("different accounts on the same host should have different fingerprints")
+	}
+}
@@ -0,0 +1,229 @@
+package webscan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slack-wails/lib/clients"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"strings"
+	"time"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// 导入的请求格式，与 nuclei 的 InputFileMode 对应，har 与 jsfind 会被转换为 jsonl
+const (
+	FuzzFormatBurp    = "burp"
+	FuzzFormatHar     = "har"
+	FuzzFormatOpenAPI = "openapi"
+	FuzzFormatSwagger = "swagger"
+	FuzzFormatJsonl   = "jsonl"
+	FuzzFormatYaml    = "yaml"
+	FuzzFormatJSFind  = "jsfind"
+)
+
+// proxify 格式的请求，nuclei 的 jsonl 输入会解析其中的原始请求
+type proxifyRequest struct {
+	URL     string `json:"url"`
+	Request struct {
+		Raw string `json:"raw"`
+	} `json:"request"`
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method   string      `json:"method"`
+				URL      string      `json:"url"`
+				Headers  []harHeader `json:"headers"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NewNucleiFuzzEngine 将导入的完整请求作为输入，仅运行 DAST 模板进行模糊测试
+func NewNucleiFuzzEngine(ctx, ctrlCtx context.Context, taskId string, o structs.FuzzOption) {
+	inputFile, mode, err := prepareFuzzInput(o)
+	if err != nil {
+		gologger.DualLog(ctx, gologger.Level_ERROR, fmt.Sprintf("[nuclei] load fuzz input err: %v", err))
+		return
+	}
+	if inputFile != o.InputFile {
+		defer os.Remove(inputFile)
+	}
+	templates := o.TemplateFiles
+	if len(templates) == 0 {
+		templates = o.TemplateFolders
+	}
+	options := []nuclei.NucleiSDKOptions{
+		nuclei.DisableUpdateCheck(), // -duc
+		nuclei.DASTMode(),           // -dast
+		nuclei.WithTemplatesOrWorkflows(nuclei.TemplateSources{
+			Templates: templates,
+		}),
+	}
+	if o.CustomHeaders != "" {
+		options = append(options, nuclei.WithHeaders(clients.Str2HeaderList(o.CustomHeaders)))
+	}
+	if o.Proxy != "" {
+		options = append(options, nuclei.WithProxy([]string{o.Proxy}, false)) // -proxy
+	}
+	ne, err := nuclei.NewNucleiEngineCtx(context.Background(), options...)
+	if err != nil {
+		gologger.DualLog(ctx, gologger.Level_ERROR, fmt.Sprintf("[nuclei] init engine err: %v", err))
+		return
+	}
+	defer ne.Close()
+	if err = ne.LoadTargetsWithHttpData(inputFile, mode); err != nil {
+		gologger.DualLog(ctx, gologger.Level_ERROR, fmt.Sprintf("[nuclei] load %s requests err: %v", mode, err))
+		return
+	}
+	gologger.DualLog(ctx, gologger.Level_INFO, fmt.Sprintf("[nuclei] fuzzing requests from %s", filepath.Base(inputFile)))
+	err = ne.ExecuteCallbackWithCtx(ctrlCtx, func(event *output.ResultEvent) {
+		emitVulnerability(ctx, taskId, event)
+	})
+	if err != nil {
+		gologger.DualLog(ctx, gologger.Level_ERROR, fmt.Sprintf("[nuclei] execute callback err: %v", err))
+	}
+}
+
+// 返回 nuclei 可以直接读取的输入文件以及格式，需要转换的格式会写入临时文件
+func prepareFuzzInput(o structs.FuzzOption) (string, string, error) {
+	format := o.Format
+	if format == "" {
+		format = detectFuzzFormat(o.InputFile)
+	}
+	switch format {
+	case FuzzFormatBurp, FuzzFormatOpenAPI, FuzzFormatSwagger, FuzzFormatJsonl, FuzzFormatYaml:
+		if _, err := os.Stat(o.InputFile); err != nil {
+			return "", "", err
+		}
+		return o.InputFile, format, nil
+	case FuzzFormatHar:
+		data, err := os.ReadFile(o.InputFile)
+		if err != nil {
+			return "", "", err
+		}
+		requests, err := parseHar(data)
+		if err != nil {
+			return "", "", err
+		}
+		file, err := writeProxifyFile(requests)
+		return file, FuzzFormatJsonl, err
+	case FuzzFormatJSFind:
+		var requests []proxifyRequest
+		for _, api := range o.APIs {
+			if r, err := newProxifyRequest(o.Method, api, nil, ""); err == nil {
+				requests = append(requests, r)
+			}
+		}
+		file, err := writeProxifyFile(requests)
+		return file, FuzzFormatJsonl, err
+	}
+	return "", "", fmt.Errorf("unsupported input format: %s", format)
+}
+
+func detectFuzzFormat(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".har":
+		return FuzzFormatHar
+	case ".xml":
+		return FuzzFormatBurp
+	case ".json", ".yaml", ".yml":
+		data, _ := os.ReadFile(file)
+		if bytes.Contains(data, []byte("openapi")) {
+			return FuzzFormatOpenAPI
+		}
+		if bytes.Contains(data, []byte("swagger")) {
+			return FuzzFormatSwagger
+		}
+		if strings.HasSuffix(file, ".json") {
+			return FuzzFormatJsonl
+		}
+		return FuzzFormatYaml
+	}
+	return ""
+}
+
+// 将浏览器导出的 HAR 文件转换为原始请求
+func parseHar(data []byte) ([]proxifyRequest, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, err
+	}
+	var requests []proxifyRequest
+	for _, entry := range har.Log.Entries {
+		var body string
+		if entry.Request.PostData != nil {
+			body = entry.Request.PostData.Text
+		}
+		r, err := newProxifyRequest(entry.Request.Method, entry.Request.URL, entry.Request.Headers, body)
+		if err != nil {
+			continue
+		}
+		requests = append(requests, r)
+	}
+	if len(requests) == 0 {
+		return nil, errors.New("no http requests found in har file")
+	}
+	return requests, nil
+}
+
+func newProxifyRequest(method, target string, headers []harHeader, body string) (proxifyRequest, error) {
+	var r proxifyRequest
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return r, fmt.Errorf("invalid url: %s", target)
+	}
+	if method == "" {
+		method = "GET"
+	}
+	var raw strings.Builder
+	raw.WriteString(fmt.Sprintf("%s %s HTTP/1.1\r\n", strings.ToUpper(method), u.RequestURI()))
+	raw.WriteString("Host: " + u.Host + "\r\n")
+	for _, h := range headers {
+		// 跳过 HTTP/2 的伪头部以及需要重新计算的头部
+		if strings.HasPrefix(h.Name, ":") || strings.EqualFold(h.Name, "host") || strings.EqualFold(h.Name, "content-length") {
+			continue
+		}
+		raw.WriteString(h.Name + ": " + h.Value + "\r\n")
+	}
+	raw.WriteString("\r\n")
+	raw.WriteString(body)
+	r.URL = target
+	r.Request.Raw = raw.String()
+	return r, nil
+}
+
+func writeProxifyFile(requests []proxifyRequest) (string, error) {
+	if len(requests) == 0 {
+		return "", errors.New("no http requests to fuzz")
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, r := range requests {
+		if err := encoder.Encode(r); err != nil {
+			return "", err
+		}
+	}
+	file := filepath.Join(os.TempDir(), fmt.Sprintf("slack_fuzz_%d.json", time.Now().UnixNano()))
+	return file, os.WriteFile(file, buf.Bytes(), 0644)
+}
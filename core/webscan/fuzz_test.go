@@ -0,0 +1,27 @@
+package webscan
+
+import (
+	"strings"
+	"testing"
+)
+
+const testHar = `{"log":{"entries":[{"request":{"method":"POST","url":"http://example.com/api/login?from=web",
+"headers":[{"name":":authority","value":"example.com"},{"name":"Content-Type","value":"application/json"},{"name":"Content-Length","value":"17"}],
+"postData":{"mimeType":"application/json","text":"{\"user\":\"admin\"}"}}}]}}`
+
+func TestParseHar(t *testing.T) {
+	requests, err := parseHar([]byte(testHar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := requests[0].Request.Raw
+	if !strings.HasPrefix(raw, "POST /api/login?from=web HTTP/1.1\r\nHost: example.com\r\n") {
+		t.Fatalf("unexpected request line: %q", raw)
+	}
+	if strings.Contains(raw, ":authority") || strings.Contains(raw, "Content-Length") {
+		t.Fatalf("pseudo or length headers should be skipped: %q", raw)
+	}
+	if !strings.HasSuffix(raw, "\r\n\r\n{\"user\":\"admin\"}") {
+		t.Fatalf("body missing: %q", raw)
+	}
+}
@@ -44,6 +44,12 @@ type WebInfo struct {
 	ContentLength int
 	Banner        string // tcp指纹
 	Cert          string // TLS证书
+	Product       string // 端口扫描识别到的服务信息
+	Version       string
+	ExtraInfo     string
+	DeviceType    string
+	OS            string
+	CPE           string
 }
 
 type FingerScanner struct {
@@ -562,6 +568,18 @@ func Scan(ctx context.Context, web *WebInfo, targetDB []FingerPEntity) []string
 				result = dataCheckString(rule.Op, web.ContentType, rule.Value)
 			case "banner":
 				result = dataCheckString(rule.Op, web.Banner, rule.Value)
+			case "product":
+				result = dataCheckString(rule.Op, web.Product, rule.Value)
+			case "version":
+				result = dataCheckString(rule.Op, web.Version, rule.Value)
+			case "info":
+				result = dataCheckString(rule.Op, web.ExtraInfo, rule.Value)
+			case "device":
+				result = dataCheckString(rule.Op, web.DeviceType, rule.Value)
+			case "os":
+				result = dataCheckString(rule.Op, web.OS, rule.Value)
+			case "cpe":
+				result = dataCheckString(rule.Op, web.CPE, rule.Value)
 			}
 
 			if result {
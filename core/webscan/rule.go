@@ -51,6 +51,9 @@ type ActiveFingerPEntity struct {
 var FingerprintDB []FingerPEntity
 var ActiveFingerprintDB []ActiveFingerPEntity
 
+// 可以用于非 HTTP 端口的指纹，加载指纹库时从 FingerprintDB 中筛选
+var ServiceFingerprintDB []FingerPEntity
+
 func (config *Config) InitFingprintDB(ctx context.Context, fingerprintFile string) error {
 	data, err := os.ReadFile(fingerprintFile)
 	if err != nil {
@@ -92,6 +95,7 @@ func (config *Config) InitFingprintDB(ctx context.Context, fingerprintFile strin
 			})
 		}
 	}
+	ServiceFingerprintDB = ServiceFingerprints(FingerprintDB)
 
 	return nil
 }
@@ -122,6 +126,30 @@ func (config *Config) InitActiveScanPath(activefingerFile string) error {
 	return nil
 }
 
+// 端口服务相关的规则字段，仅由这些字段组成的指纹可以用于非 HTTP 端口
+var serviceRuleKeys = map[string]bool{
+	"banner": true, "port": true, "protocol": true, "product": true,
+	"version": true, "info": true, "device": true, "os": true, "cpe": true,
+}
+
+// ServiceFingerprints 筛选出可以用于非 HTTP 端口的指纹
+func ServiceFingerprints(db []FingerPEntity) []FingerPEntity {
+	var result []FingerPEntity
+	for _, finger := range db {
+		matched := len(finger.Rule) > 0
+		for _, rule := range finger.Rule {
+			if !serviceRuleKeys[rule.Key] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, finger)
+		}
+	}
+	return result
+}
+
 func ParseRule(rule string) []RuleData {
 	var result []RuleData
 	empty := RuleData{}
@@ -0,0 +1,24 @@
+package webscan
+
+import (
+	"context"
+	"testing"
+)
+
+func TestServiceFingerprints(t *testing.T) {
+	db := []FingerPEntity{
+		{ProductName: "OpenSSH-Ubuntu", AllString: `product="openssh" && os="linux"`},
+		{ProductName: "Nginx", AllString: `body="nginx" || product="nginx"`},
+	}
+	for i := range db {
+		db[i].Rule = ParseRule(db[i].AllString)
+	}
+	service := ServiceFingerprints(db)
+	if len(service) != 1 || service[0].ProductName != "OpenSSH-Ubuntu" {
+		t.Fatalf("unexpected service fingerprints: %v", service)
+	}
+	web := &WebInfo{Protocol: "ssh", Product: "openssh", Version: "8.9p1", OS: "linux"}
+	if result := Scan(context.Background(), web, service); len(result) != 1 {
+		t.Fatalf("unexpected scan result: %v", result)
+	}
+}
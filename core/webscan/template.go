@@ -0,0 +1,450 @@
+package webscan
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slack-wails/lib/structs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/catalog/disk"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates/signer"
+	"gopkg.in/yaml.v2"
+)
+
+// 模板中可能出现的协议字段，按照优先级排序
+var templateProtocols = []string{"http", "requests", "network", "tcp", "dns", "file", "headless", "ssl", "websocket", "whois", "code", "javascript", "workflows"}
+
+// 签名证书的标识，会写入到模板签名中
+const templateSignerName = "slack"
+
+// TemplateManager 管理本地的 nuclei 模板，禁用的模板会被移动到 disabledDir 中，不会被删除
+type TemplateManager struct {
+	templateDir string
+	disabledDir string
+	keysDir     string
+	templates   []structs.NucleiTemplate
+	signer      *signer.TemplateSigner
+	mutex       sync.RWMutex
+}
+
+// 索引时仅解析需要的字段，避免完整编译模板
+type templateMeta struct {
+	ID   string `yaml:"id"`
+	Info struct {
+		Name           string      `yaml:"name"`
+		Author         interface{} `yaml:"author"`
+		Severity       string      `yaml:"severity"`
+		Description    string      `yaml:"description"`
+		Tags           interface{} `yaml:"tags"`
+		Classification struct {
+			CVEID interface{} `yaml:"cve-id"`
+		} `yaml:"classification"`
+	} `yaml:"info"`
+}
+
+func NewTemplateManager(templateDir, disabledDir, keysDir string) *TemplateManager {
+	return &TemplateManager{
+		templateDir: templateDir,
+		disabledDir: disabledDir,
+		keysDir:     keysDir,
+	}
+}
+
+// Index 重新索引启用和禁用目录下的全部模板
+func (tm *TemplateManager) Index() []structs.NucleiTemplate {
+	var result []structs.NucleiTemplate
+	for _, dir := range []string{tm.templateDir, tm.disabledDir} {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		enabled := dir == tm.templateDir
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() || !strings.HasSuffix(d.Name(), ".yaml") {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			t, err := parseTemplateMeta(data)
+			if err != nil {
+				return nil
+			}
+			t.Path = path
+			t.Enabled = enabled
+			t.Signed = bytes.Contains(data, []byte(signer.SignaturePattern))
+			result = append(result, t)
+			return nil
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	tm.mutex.Lock()
+	tm.templates = result
+	tm.mutex.Unlock()
+	return result
+}
+
+func parseTemplateMeta(data []byte) (structs.NucleiTemplate, error) {
+	var meta templateMeta
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return structs.NucleiTemplate{}, err
+	}
+	if meta.ID == "" {
+		return structs.NucleiTemplate{}, errors.New("template id is empty")
+	}
+	// 仅需要判断顶层字段是否存在
+	var fields map[string]interface{}
+	yaml.Unmarshal(data, &fields)
+	var protocol string
+	for _, p := range templateProtocols {
+		if _, ok := fields[p]; ok {
+			protocol = p
+			break
+		}
+	}
+	switch protocol {
+	case "requests":
+		protocol = "http"
+	case "network":
+		protocol = "tcp"
+	}
+	return structs.NucleiTemplate{
+		ID:          meta.ID,
+		Name:        meta.Info.Name,
+		Author:      strings.Join(toStringList(meta.Info.Author), ","),
+		Severity:    strings.ToLower(meta.Info.Severity),
+		Description: strings.TrimSpace(meta.Info.Description),
+		Tags:        toStringList(meta.Info.Tags),
+		Protocol:    protocol,
+		CVE:         toStringList(meta.Info.Classification.CVEID),
+	}, nil
+}
+
+// 模板中 tags、author 等字段既可以是逗号分隔的字符串，也可以是数组
+func toStringList(v interface{}) []string {
+	var result []string
+	switch value := v.(type) {
+	case string:
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				result = append(result, s)
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			result = append(result, toStringList(item)...)
+		}
+	}
+	return result
+}
+
+// Search 全文检索模板，多个关键字之间为且的关系
+// 支持 severity:high tag:thinkphp protocol:http cve:CVE-2021 id:xxx 的字段过滤
+func (tm *TemplateManager) Search(query string) []structs.NucleiTemplate {
+	tm.mutex.RLock()
+	indexed := tm.templates != nil
+	tm.mutex.RUnlock()
+	if !indexed {
+		tm.Index()
+	}
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return tm.templates
+	}
+	var result []structs.NucleiTemplate
+	for _, t := range tm.templates {
+		matched := true
+		for _, term := range terms {
+			if !matchTemplate(t, term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// FindByID 根据模板ID查找启用中的模板路径
+func (tm *TemplateManager) FindByID(id string) (string, bool) {
+	for _, t := range tm.Search("id:" + id) {
+		if t.Enabled && strings.EqualFold(t.ID, id) {
+			return t.Path, true
+		}
+	}
+	return "", false
+}
+
+func matchTemplate(t structs.NucleiTemplate, term string) bool {
+	if key, value, ok := strings.Cut(term, ":"); ok && value != "" {
+		switch key {
+		case "id":
+			return strings.Contains(strings.ToLower(t.ID), value)
+		case "severity":
+			return t.Severity == value
+		case "protocol":
+			return t.Protocol == value
+		case "tag":
+			return containsFold(t.Tags, value, true)
+		case "cve":
+			return containsFold(t.CVE, value, false)
+		}
+	}
+	fields := []string{t.ID, t.Name, t.Description, t.Author, t.Severity, t.Protocol, strings.Join(t.Tags, ","), strings.Join(t.CVE, ",")}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), term) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, value string, exact bool) bool {
+	for _, item := range list {
+		item = strings.ToLower(item)
+		if (exact && item == value) || (!exact && strings.Contains(item, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 使用 nuclei 的解析器严格校验模板语法以及必填字段
+func (tm *TemplateManager) Validate(path string) error {
+	_, err := parseNucleiTemplate(path)
+	return err
+}
+
+func parseNucleiTemplate(path string) (*templates.Template, error) {
+	parser := templates.NewParser()
+	t, err := parser.ParseTemplate(path, disk.NewCatalog(filepath.Dir(path)))
+	if err != nil {
+		return nil, err
+	}
+	template, ok := t.(*templates.Template)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a nuclei template", path)
+	}
+	var errs []error
+	if template.ID == "" {
+		errs = append(errs, errors.New("mandatory 'id' field is missing"))
+	} else if !templates.ReTemplateID.MatchString(template.ID) {
+		errs = append(errs, fmt.Errorf("invalid field format for 'id' (allowed format is %s)", templates.ReTemplateID.String()))
+	}
+	if strings.TrimSpace(template.Info.Name) == "" {
+		errs = append(errs, errors.New("mandatory 'name' field is missing"))
+	}
+	if template.Info.Authors.IsEmpty() {
+		errs = append(errs, errors.New("mandatory 'author' field is missing"))
+	}
+	if len(template.Workflows) == 0 && template.Requests() == 0 {
+		errs = append(errs, errors.New("no requests defined"))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return template, nil
+}
+
+// Disable 将模板移动到禁用目录，保留原有的相对路径
+func (tm *TemplateManager) Disable(path string) error {
+	return tm.move(path, tm.templateDir, tm.disabledDir)
+}
+
+// Enable 将模板从禁用目录移回模板目录
+func (tm *TemplateManager) Enable(path string) error {
+	return tm.move(path, tm.disabledDir, tm.templateDir)
+}
+
+func (tm *TemplateManager) move(path, from, to string) error {
+	rel, err := filepath.Rel(from, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is not under %s", path, from)
+	}
+	target := filepath.Join(to, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+		return err
+	}
+	if err := os.Rename(path, target); err != nil {
+		return err
+	}
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	for i := range tm.templates {
+		if tm.templates[i].Path == path {
+			tm.templates[i].Path = target
+			tm.templates[i].Enabled = to == tm.templateDir
+		}
+	}
+	return nil
+}
+
+// DisabledTemplates 返回禁用目录中全部模板相对路径，用于模板更新后恢复禁用状态
+func (tm *TemplateManager) DisabledTemplates() []string {
+	var result []string
+	filepath.WalkDir(tm.disabledDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(tm.disabledDir, path); err == nil {
+			result = append(result, rel)
+		}
+		return nil
+	})
+	return result
+}
+
+// ApplyDisabled 更新模板后，将新模板中仍处于禁用状态的模板替换到禁用目录
+func (tm *TemplateManager) ApplyDisabled(rels []string) {
+	for _, rel := range rels {
+		path := filepath.Join(tm.templateDir, rel)
+		if _, err := os.Stat(path); err == nil {
+			os.Rename(path, filepath.Join(tm.disabledDir, rel))
+		}
+	}
+}
+
+// Verify 使用 nuclei 官方证书以及本地证书校验模板签名
+func (tm *TemplateManager) Verify(path string) (bool, error) {
+	// 加载本地证书，使自签名的模板也能通过校验
+	if _, err := tm.loadSigner(false); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	template, err := parseNucleiTemplate(path)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	for _, verifier := range signer.DefaultTemplateVerifiers {
+		if ok, _ := verifier.Verify(data, template); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Sign 使用本地证书对自定义模板进行签名，证书不存在时自动生成
+func (tm *TemplateManager) Sign(path string) error {
+	s, err := tm.loadSigner(true)
+	if err != nil {
+		return err
+	}
+	template, err := parseNucleiTemplate(path)
+	if err != nil {
+		return err
+	}
+	if len(template.Workflows) > 0 {
+		return errors.New("signing workflows is not supported")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	signature, err := s.Sign(data, template)
+	if err != nil {
+		return err
+	}
+	_, content := signer.ExtractSignatureAndContent(data)
+	buff := bytes.NewBuffer(content)
+	buff.WriteString("\n" + signature)
+	if err := os.WriteFile(path, buff.Bytes(), 0644); err != nil {
+		return err
+	}
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	for i := range tm.templates {
+		if tm.templates[i].Path == path {
+			tm.templates[i].Signed = true
+		}
+	}
+	return nil
+}
+
+// 读取本地签名证书并注册到 nuclei 的默认校验列表中，generate 为 true 时证书不存在会自动生成
+func (tm *TemplateManager) loadSigner(generate bool) (*signer.TemplateSigner, error) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	if tm.signer != nil {
+		return tm.signer, nil
+	}
+	certFile := filepath.Join(tm.keysDir, signer.CertFilename)
+	keyFile := filepath.Join(tm.keysDir, signer.PrivateKeyFilename)
+	if _, err := os.Stat(certFile); os.IsNotExist(err) {
+		if !generate {
+			return nil, err
+		}
+		if err := generateSignerKeys(certFile, keyFile); err != nil {
+			return nil, err
+		}
+	}
+	s, err := signer.NewTemplateSignerFromFiles(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := signer.AddSignerToDefault(s); err != nil {
+		return nil, err
+	}
+	tm.signer = s
+	return s, nil
+}
+
+// nuclei 自带的密钥生成需要在终端交互，这里直接生成不带密码的密钥对
+func generateSignerKeys(certFile, keyFile string) error {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	notBefore := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(notBefore.Unix()),
+		Subject:               pkix.Name{CommonName: templateSignerName},
+		SignatureAlgorithm:    x509.ECDSAWithSHA256,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(4 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return err
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: signer.CertType, Bytes: derBytes}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: signer.PrivateKeyType, Bytes: keyBytes}), 0600)
+}
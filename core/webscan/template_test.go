@@ -0,0 +1,65 @@
+package webscan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testTemplate = `id: thinkphp-5023-rce
+
+info:
+  name: ThinkPHP 5.0.23 - Remote Code Execution
+  author: slack
+  severity: critical
+  tags: thinkphp,rce,cve
+  classification:
+    cve-id: CVE-2018-20062
+
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/index.php?s=captcha"
+    matchers:
+      - type: word
+        words:
+          - "PHP Version"
+`
+
+func TestTemplateManager(t *testing.T) {
+	dir := t.TempDir()
+	templateDir := filepath.Join(dir, "pocs")
+	os.MkdirAll(filepath.Join(templateDir, "web"), 0777)
+	file := filepath.Join(templateDir, "web", "thinkphp-5023-rce.yaml")
+	os.WriteFile(file, []byte(testTemplate), 0644)
+
+	tm := NewTemplateManager(templateDir, filepath.Join(dir, "pocs-disabled"), filepath.Join(dir, "keys"))
+	list := tm.Index()
+	if len(list) != 1 || list[0].Protocol != "http" || list[0].CVE[0] != "CVE-2018-20062" {
+		t.Fatalf("unexpected index result: %+v", list)
+	}
+	if len(tm.Search("severity:critical tag:thinkphp")) != 1 || len(tm.Search("weblogic")) != 0 {
+		t.Fatal("search result mismatch")
+	}
+	if err := tm.Validate(file); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tm.Disable(file); err != nil {
+		t.Fatal(err)
+	}
+	disabled := tm.Search("thinkphp")
+	if len(disabled) != 1 || disabled[0].Enabled {
+		t.Fatalf("template should be disabled: %+v", disabled)
+	}
+	if err := tm.Enable(disabled[0].Path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tm.Sign(file); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := tm.Verify(file); err != nil || !ok {
+		t.Fatalf("verify signed template failed: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ go 1.23
 toolchain go1.23.0
 
 require (
+	github.com/Azure/go-ntlmssp v0.0.0-20221128193559-754e69321358
 	github.com/IBM/sarama v1.45.1
 	github.com/Knetic/govaluate v3.0.1-0.20171022003610-9aa49832a739+incompatible
 	github.com/chromedp/cdproto v0.0.0-20241022234722-4d5d5faf59fb
@@ -24,6 +25,7 @@ require (
 	github.com/orcastor/fico v0.0.0-20241117150408-e3bea0a75fd1
 	github.com/panjf2000/ants/v2 v2.9.1
 	github.com/parsiya/golnk v0.0.0-20221103095132-740a4c27c4ff
+	github.com/projectdiscovery/go-smb2 v0.0.0-20240129202741-052cc450c6cb
 	github.com/projectdiscovery/nuclei/v3 v3.3.5
 	github.com/projectdiscovery/utils v0.4.15
 	github.com/sijms/go-ora/v2 v2.8.22
@@ -36,6 +38,7 @@ require (
 	go.mongodb.org/mongo-driver v1.17.0
 	golang.org/x/crypto v0.33.0
 	golang.org/x/net v0.35.0
+	golang.org/x/sys v0.30.0
 	golang.org/x/text v0.22.0
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -46,7 +49,6 @@ require (
 	git.mills.io/prologic/smtpd v0.0.0-20210710122116-a525b76c287a // indirect
 	github.com/Azure/azure-sdk-for-go/sdk/azcore v1.17.0 // indirect
 	github.com/Azure/azure-sdk-for-go/sdk/azidentity v1.7.0 // indirect
-	github.com/Azure/go-ntlmssp v0.0.0-20221128193559-754e69321358 // indirect
 	github.com/Masterminds/semver/v3 v3.2.1 // indirect
 	github.com/Mzack9999/gcache v0.0.0-20230410081825-519e28eab057 // indirect
 	github.com/Mzack9999/go-http-digest-auth-client v0.6.1-0.20220414142836-eb8883508809 // indirect
@@ -224,7 +226,6 @@ require (
 	github.com/projectdiscovery/fastdialer v0.4.0 // indirect
 	github.com/projectdiscovery/fasttemplate v0.0.2 // indirect
 	github.com/projectdiscovery/freeport v0.0.7 // indirect
-	github.com/projectdiscovery/go-smb2 v0.0.0-20240129202741-052cc450c6cb // indirect
 	github.com/projectdiscovery/goflags v0.1.74 // indirect
 	github.com/projectdiscovery/gologger v1.1.49 // indirect
 	github.com/projectdiscovery/gostruct v0.0.2 // indirect
@@ -360,7 +361,6 @@ require (
 	github.com/wailsapp/mimetype v1.4.1 // indirect
 	golang.org/x/exp v0.0.0-20250106191152-7588d65b2ba8 // indirect
 	golang.org/x/mod v0.22.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/tools v0.29.0 // indirect
 )
 
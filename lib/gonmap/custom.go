@@ -0,0 +1,144 @@
+package gonmap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 自定义探针文件支持的指令，Exclude 会影响全局扫描范围，不允许在自定义文件中使用
+var customCommands = map[string]bool{
+	"Probe": true, "match": true, "softmatch": true, "ports": true, "sslports": true,
+	"totalwaitms": true, "tcpwrappedms": true, "rarity": true, "fallback": true,
+}
+
+// LoadCustomProbeDir 加载目录下所有 nmap-service-probes 格式的探针文件
+func LoadCustomProbeDir(dir string) []error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return []error{err}
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return LoadCustomProbes(files...)
+}
+
+// LoadCustomProbes 加载自定义探针文件，同名探针只追加 match 规则和端口，
+// 文件中存在任意错误时整个文件都不会加载，返回的错误带有文件名和行号
+func LoadCustomProbes(files ...string) []error {
+	var errs []error
+	loaded := false
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		probes, fileErrs := parseCustomProbes(filepath.Base(file), string(data))
+		if len(fileErrs) > 0 {
+			errs = append(errs, fileErrs...)
+			continue
+		}
+		for _, p := range probes {
+			nmap.mergeProbe(p)
+		}
+		loaded = loaded || len(probes) > 0
+	}
+	if loaded {
+		for index, value := range nmap.portProbeMap {
+			nmap.portProbeMap[index] = nmap.sortOfRarity(value)
+		}
+		MatchCount, UsedMatchCount = 0, 0
+		statistical()
+	}
+	return errs
+}
+
+// 校验并解析探针文件内容，不会修改已加载的探针库
+func parseCustomProbes(name, content string) ([]*probe, []error) {
+	var probes []*probe
+	var errs []error
+	var current *probe
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		command, _, _ := strings.Cut(line, " ")
+		if !customCommands[command] {
+			errs = append(errs, fmt.Errorf("%s:%d: unsupported directive %q", name, i+1, command))
+			continue
+		}
+		if command == "Probe" {
+			current = &probe{ports: emptyPortList, sslports: emptyPortList}
+			if err := loadLineSafely(current, line); err != nil {
+				errs = append(errs, fmt.Errorf("%s:%d: %v", name, i+1, err))
+				current = nil
+				continue
+			}
+			probes = append(probes, current)
+			continue
+		}
+		if current == nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %s must follow a valid Probe directive", name, i+1, command))
+			continue
+		}
+		if err := loadLineSafely(current, line); err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %v", name, i+1, err))
+		}
+	}
+	return probes, errs
+}
+
+// 原有的解析逻辑遇到错误会 panic，这里转换为错误返回
+func loadLineSafely(p *probe, line string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	if _, args, _ := strings.Cut(line, " "); strings.TrimSpace(args) == "" {
+		return fmt.Errorf("missing arguments")
+	}
+	p.loadLine(line)
+	return nil
+}
+
+func (n *Nmap) mergeProbe(p *probe) {
+	existing, ok := n.probeNameMap[p.name]
+	if !ok {
+		n.pushProbe(*p)
+		if fallback := p.fallback; fallback != "" {
+			if _, ok := n.probeNameMap["TCP_"+fallback]; ok {
+				n.probeNameMap[p.name].fallback = "TCP_" + fallback
+			} else {
+				n.probeNameMap[p.name].fallback = "UDP_" + fallback
+			}
+		}
+		return
+	}
+	existing.matchGroup = append(existing.matchGroup, p.matchGroup...)
+	if existing.rarity > n.filter {
+		return
+	}
+	for _, port := range p.ports {
+		if !existing.ports.exist(port) {
+			existing.ports = append(existing.ports, port)
+			n.portProbeMap[port] = append(n.portProbeMap[port], p.name)
+		}
+	}
+	for _, port := range p.sslports {
+		if !existing.sslports.exist(port) {
+			existing.sslports = append(existing.sslports, port)
+			n.portProbeMap[port] = append(n.portProbeMap[port], p.name)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package gonmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCustomProbes(t *testing.T) {
+	content := `# 自定义工控协议
+Probe TCP ModbusDeviceId q|\x00\x01\x00\x00\x00\x05\x01\x2b\x0e\x01\x00|
+rarity 3
+ports 502
+match modbus m|^\x00\x01\x00\x00..\x01\x2b\x0e| p/Modbus TCP/
+
+match broken m|([a-z|
+rarity high
+Exclude T:9100
+`
+	probes, errs := parseCustomProbes("ics.txt", content)
+	if len(probes) != 1 || probes[0].name != "TCP_ModbusDeviceId" || len(probes[0].matchGroup) != 1 {
+		t.Fatalf("unexpected probes: %+v", probes)
+	}
+	expect := []string{"ics.txt:7:", "ics.txt:8:", "ics.txt:9:"}
+	if len(errs) != len(expect) {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	for i, err := range errs {
+		if !strings.HasPrefix(err.Error(), expect[i]) {
+			t.Errorf("error %d should start with %s, got %v", i, expect[i], err)
+		}
+	}
+
+	if _, errs = parseCustomProbes("orphan.txt", "match http m|^HTTP|\n"); len(errs) != 1 {
+		t.Fatalf("match without probe should be rejected: %v", errs)
+	}
+}
@@ -24,4 +24,20 @@ match rmi m|^\x4e\x00\x09\x31\x32\x37\x2e\x30\x2e\x30\x2e\x31\x00\x00| p/Java RM
 
 # 通用RMI响应匹配
 match rmi m|^\x4e| p/Java RMI Registry/
+
+# SSDP 发现请求
+Probe UDP SSDP q|M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: "ssdp:discover"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n|
+rarity 5
+ports 1900
+
+match ssdp m|^HTTP/1\.1 200 OK\r\n.*SERVER: ([^\r\n]+)|si p/$1/
+match ssdp m|^HTTP/1\.1 200 OK\r\n|s
+
+# TFTP 读取请求，服务端返回数据包或错误包
+Probe UDP TFTP q|\x00\x01slack.txt\x00octet\x00|
+rarity 5
+ports 69
+
+match tftp m|^\x00\x03\x00\x01|s
+match tftp m|^\x00\x05\x00.([^\x00]*)\x00|s i/$1/
 `
@@ -179,7 +179,7 @@ func tlsSendWithProxy(protocol string, netloc string, data string, duration time
 	// 封装为 TLS 连接
 	tlsConn := tls.Client(rawConn, &tls.Config{
 		InsecureSkipVerify: true, // 跳过证书验证（生产环境需要谨慎）
-		ServerName:         strings.Split(netloc, ":")[0],
+		ServerName:         serverName(netloc),
 	})
 
 	// 执行 TLS 握手
@@ -234,9 +234,44 @@ func Send(protocol string, tls bool, netloc string, data string, duration time.D
 			return tcpSendWithProxy(protocol, netloc, data, duration, size, socksAddress, auth)
 		}
 	}
+	if strings.EqualFold(protocol, "udp") {
+		return udpSend(netloc, data, duration, size)
+	}
 	if tls {
 		return tlsSend(protocol, netloc, data, duration, size)
 	} else {
 		return tcpSend(protocol, netloc, data, duration, size)
 	}
 }
+
+// UDP 每次读取一个完整的数据报，小缓冲区会导致数据报被截断
+func udpSend(netloc string, data string, duration time.Duration, size int) (string, error) {
+	conn, err := net.DialTimeout("udp", netloc, duration)
+	if err != nil {
+		return "", errors.New(err.Error() + " STEP1:CONNECT")
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(data))
+	if err != nil {
+		return "", errors.New(err.Error() + " STEP2:WRITE")
+	}
+	buf := make([]byte, 65535)
+	_ = conn.SetReadDeadline(time.Now().Add(duration))
+	length, err := conn.Read(buf)
+	if err != nil {
+		return "", errors.New(err.Error() + " STEP3:READ")
+	}
+	if length > size {
+		length = size
+	}
+	return string(buf[:length]), nil
+}
+
+// 去除端口以及 IPv6 地址的方括号
+func serverName(netloc string) string {
+	host, _, err := net.SplitHostPort(netloc)
+	if err != nil {
+		return netloc
+	}
+	return host
+}
@@ -11,10 +11,12 @@ type FingerPrint struct {
 	Hostname        string
 	OperatingSystem string
 	DeviceType      string
+	CPE             string // 多个 CPE 以逗号分隔
 	//  p/vendorproductname/
 	//	v/version/
 	//	i/info/
 	//	h/hostname/
 	//	o/operatingsystem/
 	//	d/devicetype/
+	//	cpe:/cpename/
 }
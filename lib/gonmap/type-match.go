@@ -33,6 +33,8 @@ var matchVersionInfoRegexps = map[string]*regexp.Regexp{
 	"DEVICE":      regexp.MustCompile("d/([^/]+)/"),
 }
 
+var matchCPERegexp = regexp.MustCompile(`cpe:/([^/]+)/`)
+
 var matchVersionInfoHelperRegxP = regexp.MustCompile(`\$P\((\d)\)`)
 var matchVersionInfoHelperRegx = regexp.MustCompile(`\$(\d)`)
 
@@ -66,6 +68,7 @@ func parseMatch(s string, soft bool) *match {
 		Hostname:         m.getVersionInfo(s, "HOSTNAME"),
 		OperatingSystem:  m.getVersionInfo(s, "OS"),
 		DeviceType:       m.getVersionInfo(s, "DEVICE"),
+		CPE:              m.getCPE(s),
 	}
 	return m
 }
@@ -92,6 +95,8 @@ func (m *match) getPatternRegexp(pattern string, opt string) *regexp.Regexp {
 }
 
 func (m *match) getVersionInfo(s string, regID string) string {
+	// 去除 CPE 字段，避免 cpe:/a:vendor:app/ 被误识别为 p/.../ 等字段
+	s = matchCPERegexp.ReplaceAllString(s, "")
 	if matchVersionInfoRegexps[regID].MatchString(s) {
 		return matchVersionInfoRegexps[regID].FindStringSubmatch(s)[1]
 	} else {
@@ -99,6 +104,15 @@ func (m *match) getVersionInfo(s string, regID string) string {
 	}
 }
 
+// 一条 match 可能包含多个 CPE，例如应用和操作系统
+func (m *match) getCPE(s string) string {
+	var cpes []string
+	for _, sub := range matchCPERegexp.FindAllStringSubmatch(s, -1) {
+		cpes = append(cpes, "cpe:/"+sub[1])
+	}
+	return strings.Join(cpes, ",")
+}
+
 func (m *match) makeVersionInfo(s string, f *FingerPrint) {
 	f.Info = m.makeVersionInfoSubHelper(s, m.versionInfo.Info)
 	f.DeviceType = m.makeVersionInfoSubHelper(s, m.versionInfo.DeviceType)
@@ -107,6 +121,7 @@ func (m *match) makeVersionInfo(s string, f *FingerPrint) {
 	f.ProductName = m.makeVersionInfoSubHelper(s, m.versionInfo.ProductName)
 	f.Version = m.makeVersionInfoSubHelper(s, m.versionInfo.Version)
 	f.Service = m.makeVersionInfoSubHelper(s, m.versionInfo.Service)
+	f.CPE = m.makeVersionInfoSubHelper(s, m.versionInfo.CPE)
 }
 
 func (m *match) makeVersionInfoSubHelper(s string, pattern string) string {
@@ -1,9 +1,10 @@
 package gonmap
 
 import (
-	"fmt"
+	"net"
 	"slack-wails/lib/clients"
 	"slack-wails/lib/util"
+	"strconv"
 	"strings"
 	"time"
 
@@ -341,7 +342,7 @@ func (n *Nmap) sortOfRarity(list ProbeList) ProbeList {
 
 // 工具函数
 func DnsScan(host string, port int) bool {
-	domainServer := fmt.Sprintf("%s:%d", host, port)
+	domainServer := net.JoinHostPort(host, strconv.Itoa(port))
 	c := dns.Client{
 		Timeout: 2 * time.Second,
 	}
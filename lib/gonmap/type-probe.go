@@ -2,7 +2,7 @@ package gonmap
 
 import (
 	"errors"
-	"fmt"
+	"net"
 	"regexp"
 	"slack-wails/lib/clients"
 	"slack-wails/lib/gonmap/simplenet"
@@ -36,9 +36,9 @@ type probe struct {
 }
 
 func (p *probe) scan(host string, port int, tls bool, timeout time.Duration, size int, proxy clients.Proxy) (string, bool, error) {
-	uri := fmt.Sprintf("%s:%d", host, port)
+	uri := net.JoinHostPort(host, strconv.Itoa(port))
 
-	sendRaw := strings.Replace(p.sendRaw, "{Host}", fmt.Sprintf("%s:%d", host, port), -1)
+	sendRaw := strings.Replace(p.sendRaw, "{Host}", uri, -1)
 
 	text, err := simplenet.Send(p.protocol, tls, uri, sendRaw, timeout, size, proxy)
 	if err == nil {
@@ -0,0 +1,56 @@
+package gonmap
+
+import (
+	"slack-wails/lib/clients"
+	"strings"
+	"time"
+)
+
+// 常见 UDP 服务的默认端口以及对应探针，探针来自 nmap-service-probes 与自定义探针
+var UDPDefaultPorts = []int{53, 69, 123, 137, 161, 623, 1194, 1900, 5353, 11211}
+
+// 探针有响应但未匹配到指纹时，按探针推断服务，便于后续进行爆破和漏洞扫描
+var udpProbeService = map[string]string{
+	"UDP_DNSVersionBindReq": "dns",
+	"UDP_DNSStatusRequest":  "dns",
+	"UDP_NBTStat":           "netbios-ns",
+	"UDP_NTPRequest":        "ntp",
+	"UDP_SNMPv1public":      "snmp",
+	"UDP_SNMPv3GetRequest":  "snmp",
+	"UDP_DNS-SD":            "mdns",
+	"UDP_memcached":         "memcached",
+	"UDP_OpenVPN":           "openvpn",
+	"UDP_ipmi-rmcp":         "ipmi",
+	"UDP_SSDP":              "ssdp",
+	"UDP_TFTP":              "tftp",
+}
+
+// ScanUDP 使用端口对应的 UDP 探针进行服务识别，UDP 无响应时无法区分开放与过滤，统一返回 Unknown
+func (n *Nmap) ScanUDP(ip string, port int, timeout time.Duration) (status Status, response *Response) {
+	var probes []*probe
+	for _, name := range n.portProbeMap[port] {
+		if p := n.probeNameMap[name]; p != nil && p.protocol == "UDP" {
+			probes = append(probes, p)
+		}
+	}
+	status = Unknown
+	for _, p := range probes {
+		text, _, err := p.scan(ip, port, false, timeout, 10240, clients.Proxy{})
+		if err != nil {
+			// 收到 ICMP 端口不可达说明端口关闭
+			if strings.Contains(err.Error(), "refused") {
+				return Closed, nil
+			}
+			continue
+		}
+		response = &Response{Raw: text, FingerPrint: n.getFinger(text, false, p.name)}
+		if response.FingerPrint.Service == "" {
+			response.FingerPrint.Service = udpProbeService[p.name]
+		}
+		if response.FingerPrint.Service != "" {
+			return Matched, response
+		}
+		status = NotMatched
+	}
+	return status, response
+}
@@ -3,9 +3,9 @@ package gotelnet
 import (
 	"bytes"
 	"errors"
-	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -180,7 +180,7 @@ func (c *Client) ReadContext() string {
 }
 
 func (c *Client) Netloc() string {
-	return fmt.Sprintf("%s:%d", c.IPAddr, c.Port)
+	return net.JoinHostPort(c.IPAddr, strconv.Itoa(c.Port))
 }
 
 func (c *Client) Close() {
@@ -2,6 +2,7 @@ package report
 
 import (
 	"fmt"
+	"html"
 	"slack-wails/lib/structs"
 	"slack-wails/lib/util"
 	"strings"
@@ -26,8 +27,9 @@ func reportBody(Fingerprints []structs.InfoResult, POCs []structs.VulnerabilityI
 				<span>%s</span> &nbsp;
 				<span style="color:#FF4C4C;">%s</span>
 				%s
+				%s
 			</div>`,
-			fingerprint.URL, fingerprint.URL, fingerprint.StatusCode, fingerprint.Length, fingerprint.Title, strings.Join(fingerprint.Fingerprints, ", "), showWafInfo(fingerprint.IsWAF, fingerprint.WAF))
+			fingerprint.URL, fingerprint.URL, fingerprint.StatusCode, fingerprint.Length, fingerprint.Title, strings.Join(fingerprint.Fingerprints, ", "), showWafInfo(fingerprint.IsWAF, fingerprint.WAF), showServiceInfo(fingerprint))
 	}
 	fingerprintsSection += "</div>"
 
@@ -41,6 +43,18 @@ func reportBody(Fingerprints []structs.InfoResult, POCs []structs.VulnerabilityI
 		</thead>`, index+1, poc.ID, strings.ToLower(poc.Severity), poc.Severity, util.GetBasicURL(poc.URL))
 		info := fmt.Sprintf("<b>name:</b> %s&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;<b>security:</b> %s",
 			poc.Name, poc.Severity)
+		if poc.RiskScore > 0 {
+			info += fmt.Sprintf("&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;<b>risk:</b> %.1f", poc.RiskScore)
+		}
+		if len(poc.CVE) > 0 || len(poc.CWE) > 0 {
+			info += fmt.Sprintf("<br><b>cve:</b> %s&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;<b>cwe:</b> %s", poc.CVE, poc.CWE)
+		}
+		if poc.CVSSScore > 0 {
+			info += fmt.Sprintf("<br><b>cvss:</b> %.1f %s", poc.CVSSScore, poc.CVSSVector)
+		}
+		if poc.EPSSScore > 0 {
+			info += fmt.Sprintf("<br><b>epss:</b> %.5f (percentile %.5f)", poc.EPSSScore, poc.EPSSPercentile)
+		}
 		if len(poc.Extract) > 0 {
 			info += fmt.Sprintf("<br><b>extract:</b> %s", poc.Extract)
 		}
@@ -102,6 +116,21 @@ func reportBody(Fingerprints []structs.InfoResult, POCs []structs.VulnerabilityI
 	return allContent
 }
 
+// 端口服务的产品、版本以及操作系统信息
+func showServiceInfo(result structs.InfoResult) string {
+	service := strings.TrimSpace(strings.Join([]string{result.Product, result.Version}, " "))
+	if service == "" {
+		return ""
+	}
+	if result.ExtraInfo != "" {
+		service += " (" + result.ExtraInfo + ")"
+	}
+	if result.OS != "" {
+		service += " [" + result.OS + "]"
+	}
+	return fmt.Sprintf("<span style=\"color:#DCA550;\">%s</span>", html.EscapeString(service))
+}
+
 func showWafInfo(isWaf bool, waf string) string {
 	if isWaf {
 		return fmt.Sprintf("<span style=\"#color: DCA550\">%s</span>", waf)
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"math"
+	"net/url"
+	"slack-wails/lib/structs"
+	"sort"
+	"strings"
+)
+
+// 没有 CVSS 评分时按漏洞等级给出的基础分
+var severityScore = map[string]float64{
+	"CRITICAL": 9.5,
+	"HIGH":     7.5,
+	"MEDIUM":   5.0,
+	"LOW":      2.5,
+	"INFO":     0,
+}
+
+// FindingRisk 计算单个漏洞的风险值，以 CVSS 评分为基础(缺失时使用漏洞等级)，
+// EPSS 越高说明越可能被在野利用，最多额外增加 2 分，误报以及已修复的漏洞不计入风险
+func FindingRisk(v structs.VulnerabilityInfo) float64 {
+	if v.Status == "false_positive" || v.Status == "fixed" {
+		return 0
+	}
+	base := v.CVSSScore
+	if base == 0 {
+		base = severityScore[strings.ToUpper(v.Severity)]
+	}
+	return round(math.Min(10, base+v.EPSSScore*2))
+}
+
+// 多个风险值的聚合方式：最高分加上其余风险值的十分之一，最高不超过10分
+func aggregate(scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(scores)))
+	total := scores[0]
+	for _, s := range scores[1:] {
+		total += s * 0.1
+	}
+	return round(math.Min(10, total))
+}
+
+// RiskSummary 计算每个主机以及整个任务的风险值
+func RiskSummary(pocs []structs.VulnerabilityInfo) structs.RiskSummary {
+	hostScores := make(map[string][]float64)
+	for _, poc := range pocs {
+		host := findingHost(poc.URL)
+		hostScores[host] = append(hostScores[host], FindingRisk(poc))
+	}
+	var summary structs.RiskSummary
+	var scores []float64
+	for host, s := range hostScores {
+		hr := structs.HostRisk{Host: host, Findings: len(s), Score: aggregate(s)}
+		summary.Hosts = append(summary.Hosts, hr)
+		scores = append(scores, hr.Score)
+	}
+	sort.Slice(summary.Hosts, func(i, j int) bool {
+		if summary.Hosts[i].Score == summary.Hosts[j].Score {
+			return summary.Hosts[i].Host < summary.Hosts[j].Host
+		}
+		return summary.Hosts[i].Score > summary.Hosts[j].Score
+	})
+	summary.Score = aggregate(scores)
+	return summary
+}
+
+// SortByRisk 按漏洞所在主机的风险值排序，同一主机内按漏洞风险值排序
+func SortByRisk(pocs []structs.VulnerabilityInfo) []structs.VulnerabilityInfo {
+	hostRank := make(map[string]float64)
+	for _, hr := range RiskSummary(pocs).Hosts {
+		hostRank[hr.Host] = hr.Score
+	}
+	for i := range pocs {
+		pocs[i].RiskScore = FindingRisk(pocs[i])
+	}
+	sort.SliceStable(pocs, func(i, j int) bool {
+		hi, hj := findingHost(pocs[i].URL), findingHost(pocs[j].URL)
+		if hi != hj {
+			if hostRank[hi] != hostRank[hj] {
+				return hostRank[hi] > hostRank[hj]
+			}
+			return hi < hj
+		}
+		return pocs[i].RiskScore > pocs[j].RiskScore
+	})
+	return pocs
+}
+
+func findingHost(target string) string {
+	if !strings.Contains(target, "://") {
+		target = "tcp://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return target
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func round(f float64) float64 {
+	return math.Round(f*10) / 10
+}
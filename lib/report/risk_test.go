@@ -0,0 +1,26 @@
+package report
+
+import (
+	"slack-wails/lib/structs"
+	"testing"
+)
+
+func TestRiskSummary(t *testing.T) {
+	pocs := []structs.VulnerabilityInfo{
+		{ID: "tomcat-default-login", URL: "http://10.0.0.2:8080/manager", Severity: "HIGH"},
+		{ID: "CVE-2021-44228", URL: "http://10.0.0.1/api", Severity: "CRITICAL", CVSSScore: 10, EPSSScore: 0.97},
+		{ID: "apache-detect", URL: "http://10.0.0.1/", Severity: "INFO"},
+		{ID: "CVE-2017-12615", URL: "http://10.0.0.2:8080/", Severity: "HIGH", Status: "false_positive"},
+	}
+	summary := RiskSummary(pocs)
+	if len(summary.Hosts) != 2 || summary.Hosts[0].Host != "10.0.0.1" || summary.Hosts[0].Score != 10 {
+		t.Fatalf("unexpected host risk: %+v", summary.Hosts)
+	}
+	if summary.Hosts[1].Score != 7.5 {
+		t.Fatalf("false positive should not add risk: %+v", summary.Hosts[1])
+	}
+	sorted := SortByRisk(pocs)
+	if sorted[0].ID != "CVE-2021-44228" || sorted[1].ID != "apache-detect" {
+		t.Fatalf("unexpected order: %s, %s", sorted[0].ID, sorted[1].ID)
+	}
+}
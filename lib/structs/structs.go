@@ -147,6 +147,14 @@ type SubdomainOption struct {
 	DnsServers          []string
 }
 
+// 凭据库状态
+type VaultStatus struct {
+	Locked         bool   // 设置了主密码且尚未解锁
+	MasterPassword bool   // 是否设置了主密码
+	Keyring        string // 数据密钥保存的位置：keychain、secret-service、dpapi、file 或 master password
+	Error          string // 打开凭据库时的错误
+}
+
 type DatabaseConnection struct {
 	Nanoid     string
 	Scheme     string
@@ -154,7 +162,7 @@ type DatabaseConnection struct {
 	Port       int
 	Username   string
 	Password   string
-	ServerName string // Oracle 的服务名称
+	ServerName string // Oracle 的服务名称，Kingbase 的默认数据库
 	Notes      string
 }
 
@@ -164,6 +172,52 @@ type RowData struct {
 	RowsCount int
 }
 
+// 数据库管理中执行的查询
+type QueryOption struct {
+	Database string // 执行前切换到的数据库，Oracle 为模式，为空时使用当前数据库
+	Query    string // MongoDB 为 JSON 格式的数据库命令
+	Limit    int    // 最多返回的行数，0 为默认值
+	Timeout  int    // 超时时间(秒)，0 为默认值
+}
+
+type QueryResult struct {
+	Columns   []string
+	Rows      [][]interface{} // 结果通过 dbQueryRows 事件分批推送，ExecuteQuery 的返回值中为空
+	RowsCount int             // 返回的行数
+	Affected  int64           // 非查询语句影响的行数
+	Truncated bool            // 结果超过 Limit 被截断
+	Elapsed   int64           // 毫秒
+	Error     string
+}
+
+// 查询结果分批推送到前端
+type QueryRows struct {
+	QueryId string
+	Rows    [][]interface{}
+}
+
+type QueryHistory struct {
+	Id       int
+	Scheme   string
+	Host     string
+	Database string
+	Query    string
+	Rows     int
+	Elapsed  int64
+	Error    string
+	Time     string
+}
+
+// 疑似包含敏感信息的字段
+type SensitiveColumn struct {
+	Database string
+	Table    string
+	Column   string
+	Type     string // phone、idcard、email、password
+	Source   string // name 为字段名匹配，value 为样本数据匹配
+	Sample   string
+}
+
 type WebscanOptions struct {
 	Target                []string
 	TcpTarget             map[string][]string // tcp层的目标，兼容nuclei可以扫描
@@ -487,18 +541,52 @@ type FofaUserInfo struct {
 }
 
 type VulnerabilityInfo struct {
-	TaskId       string // 任务ID
-	ID           string
-	Name         string
-	Description  string
-	Reference    string
-	Type         string
-	Severity     string
-	URL          string
-	Request      string
-	Response     string
-	ResponseTime string
-	Extract      string
+	TaskId         string // 任务ID
+	ID             string
+	Name           string
+	Description    string
+	Reference      string
+	Type           string
+	Severity       string
+	URL            string
+	Request        string
+	Response       string
+	ResponseTime   string
+	Extract        string
+	MatcherName    string // 命中的匹配器名称，同一模板的不同匹配器作为不同的漏洞
+	Fingerprint    string // 模板ID + 主机 + 匹配器名称生成的唯一标识，用于跨任务去重
+	Status         string // new / confirmed / false_positive / fixed
+	Notes          string // 研判备注
+	VerifyResult   string // 最近一次复测结果
+	VerifyTime     string // 最近一次复测时间
+	CVE            string // 多个CVE以逗号分隔
+	CWE            string // 多个CWE以逗号分隔
+	CVSSScore      float64
+	CVSSVector     string
+	EPSSScore      float64
+	EPSSPercentile float64
+	RiskScore      float64 // 根据 CVSS、EPSS 以及研判状态计算的风险值，范围 0-10
+}
+
+// 主机维度的风险评分
+type HostRisk struct {
+	Host     string
+	Score    float64
+	Findings int
+}
+
+// 任务维度的风险评分，主机按风险值从高到低排序
+type RiskSummary struct {
+	Score float64
+	Hosts []HostRisk
+}
+
+// 单个漏洞的复测记录
+type FindingVerification struct {
+	Fingerprint string
+	Result      string // vulnerable / not_vulnerable / error
+	Msg         string
+	VerifyTime  string
 }
 
 type NucleiOption struct {
@@ -512,6 +600,95 @@ type NucleiOption struct {
 	Proxy                 string
 }
 
+// 端口扫描选项
+type PortscanOption struct {
+	Thread          int
+	Timeout         int      // 服务识别超时时间(秒)
+	SynScan         bool     // 具备 root 权限时先使用 SYN 扫描发现开放端口
+	Rate            int      // SYN 扫描每秒发包数
+	Retries         int      // SYN 扫描未响应端口的重传次数
+	Timing          int      // 时序模板 1-5，参考 nmap 的 -T 参数，0 为使用固定超时
+	DetectMiddlebox bool     // 检测全端口开放的主机(CDN、防火墙)
+	FilterMiddlebox bool     // 过滤全端口开放主机上未识别到服务的端口
+	UDP             bool     // 是否进行 UDP 服务探测
+	UDPPorts        []int    // 为空时使用常见 UDP 服务端口
+	Targets         []string // 未展开的目标(CIDR、范围、域名、ASN)，不为空时按需迭代，忽略 ips 参数
+	ExcludeFile     string   // 排除目标文件，每行一个
+	Randomize       bool     // 打乱目标顺序，分散对同一网段的压力
+}
+
+// 端口暴破参数
+type BruteOption struct {
+	Threads       int      // 单个主机的并发数，0 为使用协议默认值
+	Delay         int      // 每次尝试后的等待时间(毫秒)
+	Jitter        int      // 在 Delay 基础上随机增加的等待时间(毫秒)
+	MaxAttempts   int      // 单个账号的最大尝试次数，避免触发账号锁定，0 为不限制
+	StopOnSuccess string   // host 主机成功后停止(默认)，user 账号成功后停止尝试该账号，none 尝试全部组合
+	SkipEvidence  []string // 登录成功后不收集证据信息的协议
+	// 账号组合 user:pass，设置后按组合尝试，不再使用用户名和密码字典的笛卡尔积
+	Credentials    []string
+	KeyDir         string   // SSH 私钥目录，设置后额外使用私钥认证
+	KeyPassphrases []string // 加密私钥的密码字典
+}
+
+// 批量暴破参数
+type BatchCrackOption struct {
+	Thread          int      // 同时暴破的主机数
+	BuiltinUsername bool     // 使用各协议的内置用户名字典
+	BuiltinPassword bool     // 使用内置密码字典
+	Usernames       []string // 追加到每个协议的用户名
+	Passwords       []string // 追加的密码
+	Brute           BruteOption
+}
+
+// 导入完整请求进行 DAST 模糊测试
+type FuzzOption struct {
+	InputFile       string   // Burp XML、HAR、OpenAPI/Swagger 以及 jsonl/yaml 请求文件
+	Format          string   // 为空时根据文件自动识别
+	APIs            []string // jsfind 发现的接口
+	Method          string   // jsfind 接口的请求方法
+	TemplateFiles   []string
+	TemplateFolders []string
+	CustomHeaders   string
+	Proxy           string
+}
+
+// Redis 利用参数，Confirm 为假时只返回将要执行的操作，不修改目标
+type RedisExpOption struct {
+	Host       string // ip:port
+	Password   string
+	Mode       string // sshkey、crontab、webshell、module
+	Dir        string // 写入目录，为空时使用各模式的默认目录
+	Filename   string
+	Content    string // 公钥、计划任务命令或 webshell 内容
+	LHost      string // module 模式下伪造主节点的监听地址，需要目标可以访问
+	LPort      int
+	ModuleFile string // module 模式下加载的 Redis 模块文件
+	Command    string // 模块加载后通过 system.exec 执行的命令
+	Confirm    bool
+}
+
+type NucleiTemplate struct {
+	ID          string
+	Name        string
+	Author      string
+	Severity    string
+	Description string
+	Tags        []string
+	Protocol    string
+	CVE         []string
+	Path        string // 模板文件路径
+	Enabled     bool   // 禁用的模板不会参与扫描
+	Signed      bool   // 模板中是否包含签名
+}
+
+// AliveHost 存活主机及其探测方式
+type AliveHost struct {
+	Host   string
+	Method string  // icmp、ping、tcp、arp、udp
+	RTT    float64 // 毫秒
+}
+
 type InfoResult struct {
 	TaskId       string // 任务ID
 	URL          string // 网站链接
@@ -526,6 +703,46 @@ type InfoResult struct {
 	WAF          string
 	Detect       string
 	Screenshot   string // 截图图片路径
+	Middlebox    bool   // 主机全端口开放，端口状态可能由CDN或防火墙代为响应
+	Product      string // 以下为端口扫描识别到的服务信息
+	Version      string
+	ExtraInfo    string
+	DeviceType   string
+	OS           string
+	CPE          string
+	TLS          bool // 端口使用 TLS 加密
+}
+
+// TLS 端口的证书信息
+type CertInfo struct {
+	TaskId             string
+	Host               string
+	Port               int
+	SubjectCN          string
+	SubjectDN          string
+	IssuerCN           string
+	IssuerDN           string
+	SANs               []string // 证书中的 DNS 与 IP 备用名称
+	Domains            []string // CN 与 SAN 中去重后的域名
+	NotBefore          string
+	NotAfter           string
+	SHA256             string // 证书指纹
+	SerialNumber       string
+	SignatureAlgorithm string
+	KeyAlgorithm       string
+	KeyBits            int
+	Expired            bool // 已过期或尚未生效
+	SelfSigned         bool
+	Weak               bool // 弱密钥或弱签名算法
+	Issues             []string
+}
+
+// 从证书中发现的域名，Targets 可直接用于网站扫描，通配符域名用于子域名枚举
+type CertDomain struct {
+	Domain   string
+	Wildcard bool
+	Hosts    []string // 出现该域名证书的 host:port
+	Targets  []string
 }
 
 type WebReport struct {
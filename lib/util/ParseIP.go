@@ -1,120 +1,39 @@
 package util
 
 import (
+	"errors"
 	"net"
-
 	"strconv"
-	"strings"
 )
 
-func ParseIPs(ipList []string) (ips []string) {
-	var excludeip []string
-	for _, line := range ipList {
-		if strings.HasPrefix(line, "!") {
-			line = strings.TrimLeft(line, "!")
-			excludeip = append(excludeip, ParseIP(line)...)
-		} else {
-			ips = append(ips, ParseIP(line)...)
-		}
-	}
-	for _, ep := range excludeip {
-		ips = RemoveElement(ips, ep)
-	}
-	return ips
-}
-
-func ParseIP(ipString string) []string {
-	var result []string
-	if strings.Contains(ipString, "-") {
-		result = append(result, parseIP1(ipString)...)
-	} else if strings.Contains(ipString, ",") {
-		ipArray := strings.Split(ipString, ",")
-		for _, ip := range ipArray {
-			if strings.Contains(ip, "/") {
-				parsedIPs, err := parseCIDR(ip)
-				if err != nil {
-					continue
-				}
-				result = append(result, parsedIPs...)
-			} else {
-				result = append(result, ip)
-			}
-		}
-	} else if strings.Contains(ipString, "/") {
-		parsedIPs, err := parseCIDR(ipString)
-		if err == nil {
-			result = append(result, parsedIPs...)
-		}
-	} else {
-		result = append(result, ipString)
-	}
-	return result
-}
-
-func parseCIDR(cidr string) ([]string, error) {
-	_, ipNet, err := net.ParseCIDR(cidr)
+// ParseIPs 展开目标列表，以 ! 开头的目标会被排除，大网段请使用 TargetIterator 按需迭代。
+// 任意一个目标格式错误时返回错误，不会静默跳过
+func ParseIPs(ipList []string) ([]string, error) {
+	it, err := NewTargetIterator(ipList, "", false)
 	if err != nil {
 		return nil, err
 	}
 	var ips []string
-	for ip := ipNet.IP.Mask(ipNet.Mask); ipNet.Contains(ip); inc(ip) {
-		ips = append(ips, ip.String())
+	for ip, ok := it.Next(); ok; ip, ok = it.Next() {
+		ips = append(ips, ip)
 	}
-
 	return ips, nil
 }
 
-func inc(ip net.IP) {
-	for j := len(ip) - 1; j >= 0; j-- {
-		ip[j]++
-		if ip[j] > 0 {
-			break
-		}
-	}
+// ParseIP 展开单个目标，支持的格式与 TargetIterator 相同
+func ParseIP(target string) ([]string, error) {
+	return ParseIPs([]string{target})
 }
 
-// 解析ip段: 192.168.111.1-255
-// 192.168.111.1-192.168.112.255
-func parseIP1(ip string) []string {
-	IPRange := strings.Split(ip, "-")
-	testIP := net.ParseIP(IPRange[0])
-	var AllIP []string
-	if len(IPRange[1]) < 4 {
-		Range, err := strconv.Atoi(IPRange[1])
-		if testIP == nil || Range > 255 || err != nil {
-			return nil
-		}
-		SplitIP := strings.Split(IPRange[0], ".")
-		ip1, err1 := strconv.Atoi(SplitIP[3])
-		ip2, err2 := strconv.Atoi(IPRange[1])
-		PrefixIP := strings.Join(SplitIP[0:3], ".")
-		if ip1 > ip2 || err1 != nil || err2 != nil {
-			return nil
-		}
-		for i := ip1; i <= ip2; i++ {
-			AllIP = append(AllIP, PrefixIP+"."+strconv.Itoa(i))
-		}
-	} else {
-		SplitIP1 := strings.Split(IPRange[0], ".")
-		SplitIP2 := strings.Split(IPRange[1], ".")
-		if len(SplitIP1) != 4 || len(SplitIP2) != 4 {
-			return nil
-		}
-		start, end := [4]int{}, [4]int{}
-		for i := 0; i < 4; i++ {
-			ip1, err1 := strconv.Atoi(SplitIP1[i])
-			ip2, err2 := strconv.Atoi(SplitIP2[i])
-			if ip1 > ip2 || err1 != nil || err2 != nil {
-				return nil
-			}
-			start[i], end[i] = ip1, ip2
-		}
-		startNum := start[0]<<24 | start[1]<<16 | start[2]<<8 | start[3]
-		endNum := end[0]<<24 | end[1]<<16 | end[2]<<8 | end[3]
-		for num := startNum; num <= endNum; num++ {
-			ip := strconv.Itoa((num>>24)&0xff) + "." + strconv.Itoa((num>>16)&0xff) + "." + strconv.Itoa((num>>8)&0xff) + "." + strconv.Itoa((num)&0xff)
-			AllIP = append(AllIP, ip)
-		}
+// SplitHostPort 解析 host:port 以及 IPv6 的 [addr]:port 格式
+func SplitHostPort(target string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return "", 0, errors.New("invalid port: " + portStr)
 	}
-	return AllIP
+	return host, port, nil
 }
@@ -0,0 +1,42 @@
+package util
+
+import "testing"
+
+func TestParseIPv6(t *testing.T) {
+	if ips, err := ParseIP("2001:db8::/126"); err != nil || len(ips) != 4 || ips[3] != "2001:db8::3" {
+		t.Fatalf("unexpected cidr result: %v, %v", ips, err)
+	}
+	if _, err := ParseIP("2001:db8::/64"); err == nil {
+		t.Fatal("large ipv6 cidr should be rejected")
+	}
+	if ips, err := ParseIP("2001:db8::1-a"); err != nil || len(ips) != 10 || ips[9] != "2001:db8::a" {
+		t.Fatalf("unexpected range result: %v, %v", ips, err)
+	}
+	if ips, _ := ParseIP("[::1]"); len(ips) != 1 || ips[0] != "::1" {
+		t.Fatalf("unexpected bracketed result: %v", ips)
+	}
+}
+
+func TestParseIPsInvalidTarget(t *testing.T) {
+	// 任意一行格式错误时返回错误，不能静默返回空结果
+	if ips, err := ParseIPs([]string{"10.0.0.1", "10.0.0.9-1"}); err == nil {
+		t.Fatalf("expected error, got %v", ips)
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	cases := map[string]string{
+		"[2001:db8::1]:8080": "2001:db8::1",
+		"192.168.1.1:22":     "192.168.1.1",
+		"example.com:443":    "example.com",
+	}
+	for target, expect := range cases {
+		host, _, err := SplitHostPort(target)
+		if err != nil || host != expect {
+			t.Errorf("%s: got %q, %v", target, host, err)
+		}
+	}
+	if _, _, err := SplitHostPort("2001:db8::1:80"); err == nil {
+		t.Error("unbracketed ipv6 with port should be rejected")
+	}
+}
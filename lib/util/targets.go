@@ -0,0 +1,337 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var regASN = regexp.MustCompile(`(?i)^AS(\d+)$`)
+
+// 一段连续的地址，或者无法展开的域名
+type targetSegment struct {
+	host  string
+	start net.IP // 16 字节格式
+	count uint64
+}
+
+type ipRange struct {
+	start, end net.IP // 16 字节格式，包含首尾
+}
+
+// TargetIterator 按需生成目标地址，不会一次性展开整个网段，
+// 支持 CIDR、IP 范围、域名、ASN 以及排除列表，可以打乱顺序以分散对同一网段的压力
+type TargetIterator struct {
+	segments     []targetSegment
+	offsets      []uint64 // 每段第一个地址的序号
+	total        uint64
+	exclude      []ipRange
+	excludeHosts map[string]struct{}
+	shuffle      bool
+
+	mutex sync.Mutex
+	mask  uint64 // 乱序时使用的线性同余生成器，模数为不小于 total 的 2 的幂
+	a, c  uint64
+	x     uint64
+	steps uint64
+}
+
+// NewTargetIterator 以 ! 开头的目标以及 excludeFile 中的目标会被排除
+func NewTargetIterator(lines []string, excludeFile string, shuffle bool) (*TargetIterator, error) {
+	it := &TargetIterator{excludeHosts: make(map[string]struct{}), shuffle: shuffle}
+	var excludes []string
+	for _, line := range lines {
+		for _, target := range strings.Split(line, ",") {
+			target = strings.TrimSpace(target)
+			if target == "" {
+				continue
+			}
+			if strings.HasPrefix(target, "!") {
+				excludes = append(excludes, strings.TrimLeft(target, "!"))
+				continue
+			}
+			segments, err := parseTargetSegments(target)
+			if err != nil {
+				return nil, err
+			}
+			for _, seg := range segments {
+				it.offsets = append(it.offsets, it.total)
+				it.segments = append(it.segments, seg)
+				it.total += seg.count
+			}
+		}
+	}
+	if excludeFile != "" {
+		fileLines, err := ParseFile(excludeFile)
+		if err != nil {
+			return nil, err
+		}
+		excludes = append(excludes, fileLines...)
+	}
+	for _, line := range excludes {
+		for _, target := range strings.Split(line, ",") {
+			target = strings.TrimSpace(target)
+			if target == "" || strings.HasPrefix(target, "#") {
+				continue
+			}
+			segments, err := parseTargetSegments(target)
+			if err != nil {
+				return nil, err
+			}
+			for _, seg := range segments {
+				if seg.host != "" {
+					it.excludeHosts[strings.ToLower(seg.host)] = struct{}{}
+				} else {
+					it.exclude = append(it.exclude, ipRange{start: seg.start, end: addIP(seg.start, seg.count-1)})
+				}
+			}
+		}
+	}
+	it.exclude = mergeRanges(it.exclude)
+	it.Reset()
+	return it, nil
+}
+
+// Count 返回排除前的目标数量
+func (it *TargetIterator) Count() uint64 {
+	return it.total
+}
+
+// Reset 从头开始迭代，乱序模式下会重新生成顺序
+func (it *TargetIterator) Reset() {
+	it.mutex.Lock()
+	defer it.mutex.Unlock()
+	it.steps = 0
+	it.x = 0
+	if !it.shuffle || it.total == 0 {
+		return
+	}
+	it.mask = 1<<bits.Len64(it.total-1) - 1
+	// a ≡ 1 (mod 4) 且 c 为奇数时，生成器在 2 的幂的模数下周期完整，每个序号恰好出现一次
+	it.a = rand.Uint64()&^3 | 1
+	it.c = rand.Uint64() | 1
+	it.x = rand.Uint64() & it.mask
+}
+
+// Next 返回下一个目标，迭代结束时返回 false
+func (it *TargetIterator) Next() (string, bool) {
+	it.mutex.Lock()
+	defer it.mutex.Unlock()
+	for {
+		var index uint64
+		if it.shuffle {
+			if it.total == 0 || it.steps > it.mask {
+				return "", false
+			}
+			index = it.x
+			it.x = (it.a*it.x + it.c) & it.mask
+		} else {
+			if it.steps >= it.total {
+				return "", false
+			}
+			index = it.steps
+		}
+		it.steps++
+		if index >= it.total {
+			continue
+		}
+		if target, ok := it.target(index); ok {
+			return target, true
+		}
+	}
+}
+
+// 根据序号计算目标，被排除时返回 false
+func (it *TargetIterator) target(index uint64) (string, bool) {
+	i := sort.Search(len(it.offsets), func(i int) bool { return it.offsets[i] > index }) - 1
+	seg := it.segments[i]
+	if seg.host != "" {
+		_, excluded := it.excludeHosts[strings.ToLower(seg.host)]
+		return seg.host, !excluded
+	}
+	ip := addIP(seg.start, index-it.offsets[i])
+	if it.excluded(ip) {
+		return "", false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String(), true
+	}
+	return ip.String(), true
+}
+
+func (it *TargetIterator) excluded(ip net.IP) bool {
+	i := sort.Search(len(it.exclude), func(i int) bool { return bytes.Compare(it.exclude[i].end, ip) >= 0 })
+	return i < len(it.exclude) && bytes.Compare(it.exclude[i].start, ip) <= 0
+}
+
+// 将单个目标解析为地址段，ASN 会展开为其宣告的所有 IPv4 网段
+func parseTargetSegments(target string) ([]targetSegment, error) {
+	if m := regASN.FindStringSubmatch(target); m != nil {
+		prefixes, err := LookupASNPrefixes(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("lookup %s: %w", target, err)
+		}
+		var segments []targetSegment
+		for _, prefix := range prefixes {
+			seg, err := parseTargetRange(prefix)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, seg...)
+		}
+		return segments, nil
+	}
+	return parseTargetRange(target)
+}
+
+// 非法的 CIDR 与地址范围返回错误，避免目标被静默跳过
+func parseTargetRange(target string) ([]targetSegment, error) {
+	target = strings.Trim(target, "[]")
+	if strings.Contains(target, "/") {
+		_, ipNet, err := net.ParseCIDR(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %s: %w", target, err)
+		}
+		ones, bits := ipNet.Mask.Size()
+		if bits == 128 && bits-ones > 16 {
+			return nil, fmt.Errorf("ipv6 cidr %s is too large, prefix must be /112 or longer", target)
+		}
+		return []targetSegment{{start: ipNet.IP.To16(), count: 1 << (bits - ones)}}, nil
+	}
+	if startStr, endStr, ok := strings.Cut(target, "-"); ok {
+		if start, end, ok := parseRangeBounds(startStr, endStr); ok {
+			return []targetSegment{{start: start, count: ipDistance(start, end) + 1}}, nil
+		}
+	}
+	if ip := net.ParseIP(target); ip != nil {
+		return []targetSegment{{start: ip.To16(), count: 1}}, nil
+	}
+	// 域名等无法展开的目标原样保留，但带有 - 的非法地址范围不作为域名
+	if strings.Contains(target, "-") && net.ParseIP(strings.SplitN(target, "-", 2)[0]) != nil {
+		return nil, fmt.Errorf("invalid ip range %s", target)
+	}
+	return []targetSegment{{host: target, count: 1}}, nil
+}
+
+// IPv6 地址空间过大，单个网段或范围最多展开的地址数量
+const maxIPv6Hosts = 65536
+
+// 解析范围的首尾地址，结束地址可以简写为最后一段: 192.168.1.1-255、2001:db8::1-ff
+func parseRangeBounds(startStr, endStr string) (net.IP, net.IP, bool) {
+	start := net.ParseIP(startStr)
+	if start == nil {
+		return nil, nil, false
+	}
+	end := net.ParseIP(endStr)
+	if end == nil {
+		end = make(net.IP, net.IPv6len)
+		copy(end, start.To16())
+		if start.To4() != nil {
+			last, err := strconv.ParseUint(endStr, 10, 8)
+			if err != nil {
+				return nil, nil, false
+			}
+			end[15] = byte(last)
+		} else {
+			last, err := strconv.ParseUint(endStr, 16, 16)
+			if err != nil {
+				return nil, nil, false
+			}
+			end[14], end[15] = byte(last>>8), byte(last)
+		}
+	}
+	start, end = start.To16(), end.To16()
+	if (start.To4() == nil) != (end.To4() == nil) || bytes.Compare(start, end) > 0 {
+		return nil, nil, false
+	}
+	if start.To4() == nil && ipDistance(start, end) >= maxIPv6Hosts {
+		return nil, nil, false
+	}
+	return start, end, true
+}
+
+func ipDistance(start, end net.IP) uint64 {
+	diff := new(big.Int).Sub(new(big.Int).SetBytes(end), new(big.Int).SetBytes(start))
+	if !diff.IsUint64() {
+		return 0
+	}
+	return diff.Uint64()
+}
+
+// 返回 ip + n，ip 为 16 字节格式
+func addIP(ip net.IP, n uint64) net.IP {
+	result := make(net.IP, net.IPv6len)
+	copy(result, ip.To16())
+	carry := n
+	for i := len(result) - 1; i >= 0 && carry > 0; i-- {
+		sum := uint64(result[i]) + carry&0xff
+		result[i] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+	return result
+}
+
+// 排序并合并重叠的排除范围，便于二分查找
+func mergeRanges(ranges []ipRange) []ipRange {
+	sort.Slice(ranges, func(i, j int) bool { return bytes.Compare(ranges[i].start, ranges[j].start) < 0 })
+	var merged []ipRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && bytes.Compare(r.start, addIP(merged[n-1].end, 1)) <= 0 {
+			if bytes.Compare(r.end, merged[n-1].end) > 0 {
+				merged[n-1].end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// LookupASNPrefixes 通过 RADb 的 whois 服务查询 ASN 宣告的 IPv4 网段，
+// IPv6 网段通常过大无法扫描，不做处理
+func LookupASNPrefixes(asn string) ([]string, error) {
+	conn, err := net.DialTimeout("tcp", "whois.radb.net:43", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if _, err = fmt.Fprintf(conn, "-i origin AS%s\r\n", asn); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.ReadFrom(conn)
+	prefixes := parseWhoisRoutes(buf.String())
+	if len(prefixes) == 0 {
+		return nil, errors.New("no route found")
+	}
+	return prefixes, nil
+}
+
+func parseWhoisRoutes(text string) []string {
+	var prefixes []string
+	seen := make(map[string]struct{})
+	for _, line := range strings.Split(text, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(key) != "route" {
+			continue
+		}
+		prefix := strings.TrimSpace(value)
+		if _, ok := seen[prefix]; ok {
+			continue
+		}
+		seen[prefix] = struct{}{}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
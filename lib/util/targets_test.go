@@ -0,0 +1,71 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTargetIterator(t *testing.T) {
+	excludeFile := filepath.Join(t.TempDir(), "exclude.txt")
+	os.WriteFile(excludeFile, []byte("# 注释\n10.0.0.8-10.0.0.15\nexample.com\n"), 0644)
+	it, err := NewTargetIterator([]string{"10.0.0.0/24", "!10.0.0.0/29", "example.com,scanme.org", "2001:db8::1-4"}, excludeFile, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.Count() != 256+2+4 {
+		t.Fatalf("unexpected count: %d", it.Count())
+	}
+	seen := make(map[string]int)
+	for target, ok := it.Next(); ok; target, ok = it.Next() {
+		seen[target]++
+	}
+	// 排除 10.0.0.0-15 以及 example.com
+	if len(seen) != 240+1+4 {
+		t.Fatalf("unexpected targets: %d", len(seen))
+	}
+	for target, n := range seen {
+		if n != 1 {
+			t.Errorf("%s appeared %d times", target, n)
+		}
+	}
+	for _, target := range []string{"10.0.0.3", "10.0.0.15", "example.com"} {
+		if seen[target] != 0 {
+			t.Errorf("%s should be excluded", target)
+		}
+	}
+	if seen["10.0.0.16"] != 1 || seen["scanme.org"] != 1 || seen["2001:db8::4"] != 1 {
+		t.Error("missing expected targets")
+	}
+}
+
+func TestTargetIteratorLargeRange(t *testing.T) {
+	it, err := NewTargetIterator([]string{"10.0.0.0/8"}, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.Count() != 1<<24 {
+		t.Fatalf("unexpected count: %d", it.Count())
+	}
+	first, _ := it.Next()
+	second, _ := it.Next()
+	if first == second {
+		t.Fatal("iterator should not repeat targets")
+	}
+}
+
+func TestParseWhoisRoutes(t *testing.T) {
+	text := "route:          1.1.1.0/24\norigin:         AS13335\n\nroute6:         2606:4700::/32\nroute:          1.0.0.0/24\nroute:          1.1.1.0/24\n"
+	prefixes := parseWhoisRoutes(text)
+	if len(prefixes) != 2 || prefixes[0] != "1.1.1.0/24" || prefixes[1] != "1.0.0.0/24" {
+		t.Fatalf("unexpected prefixes: %v", prefixes)
+	}
+}
+
+func TestTargetIteratorInvalidTarget(t *testing.T) {
+	for _, target := range []string{"10.0.0.0/33", "2001:db8::/64", "10.0.0.9-1", "10.0.0.1-abc"} {
+		if _, err := NewTargetIterator([]string{target}, "", false); err == nil {
+			t.Errorf("expected error for %s", target)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"runtime"
 
 	"strings"
 	"time"
@@ -20,6 +21,15 @@ func HomeDir() string {
 	return s
 }
 
+// IsRoot Windows 下能打开物理磁盘说明具有管理员权限，其他系统判断 uid 是否为 0
+func IsRoot() bool {
+	if runtime.GOOS == "windows" {
+		_, err := os.Open("\\\\.\\PHYSICALDRIVE0")
+		return err == nil
+	}
+	return os.Getuid() == 0
+}
+
 func ReverseString(s string) string {
 	runes := []rune(s)
 	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
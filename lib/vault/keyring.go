@@ -0,0 +1,100 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 系统密钥环中保存数据密钥使用的服务名和账号
+const (
+	keyringService = "slack-wails"
+	keyringAccount = "vault"
+)
+
+var errKeyNotFound = errors.New("key not found")
+
+// 保存数据密钥的位置
+type keyring interface {
+	Name() string
+	Get() ([]byte, error)
+	Set(key []byte) error
+	Delete() error // 密钥不存在时不返回错误
+}
+
+// 文件密钥环，数据密钥以 base64 保存在权限为 0600 的文件中
+type fileKeyring struct {
+	path string
+}
+
+func (k fileKeyring) Name() string {
+	return "file"
+}
+
+func (k fileKeyring) Get() ([]byte, error) {
+	content, err := os.ReadFile(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+}
+
+func (k fileKeyring) Set(key []byte) error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(k.path, []byte(base64.StdEncoding.EncodeToString(key)), 0600)
+}
+
+func (k fileKeyring) Delete() error {
+	if err := os.Remove(k.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// 优先使用系统密钥环，不可用时使用文件。读取时两处都会尝试，避免密钥环暂时不可用时重新生成密钥
+type fallbackKeyring struct {
+	primary  keyring
+	fallback keyring
+}
+
+func (k fallbackKeyring) Name() string {
+	if _, err := k.primary.Get(); err == nil {
+		return k.primary.Name()
+	}
+	return k.fallback.Name()
+}
+
+func (k fallbackKeyring) Get() ([]byte, error) {
+	key, err := k.primary.Get()
+	if err == nil {
+		return key, nil
+	}
+	return k.fallback.Get()
+}
+
+func (k fallbackKeyring) Set(key []byte) error {
+	if err := k.primary.Set(key); err != nil {
+		return k.fallback.Set(key)
+	}
+	// 已经保存到系统密钥环，删除旧的密钥文件
+	return k.fallback.Delete()
+}
+
+// 两处都会尝试删除。系统密钥环不可用时（例如没有 D-Bus 会话）数据密钥保存在文件中，
+// 此时系统密钥环同样无法读取，忽略其删除错误，避免密钥文件残留
+func (k fallbackKeyring) Delete() error {
+	primaryErr := k.primary.Delete()
+	if primaryErr != nil {
+		if _, err := k.primary.Get(); err != nil {
+			primaryErr = nil
+		}
+	}
+	return errors.Join(primaryErr, k.fallback.Delete())
+}
@@ -0,0 +1,50 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// security 命令在钥匙串中找不到项时的退出码
+const securityNotFound = 44
+
+func newKeyring(keyFile string) keyring {
+	return fallbackKeyring{primary: macKeyring{}, fallback: fileKeyring{path: keyFile}}
+}
+
+// macOS 钥匙串，通过 security 命令读写
+type macKeyring struct{}
+
+func (macKeyring) Name() string {
+	return "keychain"
+}
+
+func (macKeyring) Get() ([]byte, error) {
+	output, err := exec.Command("security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w").Output()
+	if isExitCode(err, securityNotFound) {
+		return nil, errKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(output)))
+}
+
+func (macKeyring) Set(key []byte) error {
+	return exec.Command("security", "add-generic-password", "-U", "-s", keyringService, "-a", keyringAccount, "-w", base64.StdEncoding.EncodeToString(key)).Run()
+}
+
+func (macKeyring) Delete() error {
+	err := exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", keyringAccount).Run()
+	if isExitCode(err, securityNotFound) {
+		return nil
+	}
+	return err
+}
+
+func isExitCode(err error, code int) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == code
+}
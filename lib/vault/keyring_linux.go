@@ -0,0 +1,48 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+func newKeyring(keyFile string) keyring {
+	return fallbackKeyring{primary: secretToolKeyring{}, fallback: fileKeyring{path: keyFile}}
+}
+
+// Secret Service 密钥环，通过 libsecret 的 secret-tool 命令读写，没有安装或没有桌面会话时使用文件
+type secretToolKeyring struct{}
+
+func (secretToolKeyring) Name() string {
+	return "secret-service"
+}
+
+func (secretToolKeyring) Get() ([]byte, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, err
+	}
+	output, err := exec.Command("secret-tool", "lookup", "service", keyringService, "account", keyringAccount).Output()
+	// 找不到时退出码为 1 且没有输出
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 && len(output) == 0 {
+		return nil, errKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(output)))
+}
+
+func (secretToolKeyring) Set(key []byte) error {
+	cmd := exec.Command("secret-tool", "store", "--label=Slack vault key", "service", keyringService, "account", keyringAccount)
+	cmd.Stdin = strings.NewReader(base64.StdEncoding.EncodeToString(key))
+	return cmd.Run()
+}
+
+func (secretToolKeyring) Delete() error {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil
+	}
+	return exec.Command("secret-tool", "clear", "service", keyringService, "account", keyringAccount).Run()
+}
@@ -0,0 +1,7 @@
+//go:build !darwin && !linux && !windows
+
+package vault
+
+func newKeyring(keyFile string) keyring {
+	return fileKeyring{path: keyFile}
+}
@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+func newKeyring(keyFile string) keyring {
+	return dpapiKeyring{path: keyFile}
+}
+
+// 使用 DPAPI 以当前用户身份加密后保存在文件中，其他用户和其他机器无法解密
+type dpapiKeyring struct {
+	path string
+}
+
+func (k dpapiKeyring) Name() string {
+	return "dpapi"
+}
+
+func (k dpapiKeyring) Get() ([]byte, error) {
+	content, err := os.ReadFile(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	protected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, err
+	}
+	var out windows.DataBlob
+	if err = windows.CryptUnprotectData(newBlob(protected), nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return append([]byte(nil), unsafe.Slice(out.Data, out.Size)...), nil
+}
+
+func (k dpapiKeyring) Set(key []byte) error {
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(newBlob(key), nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(k.path, []byte(base64.StdEncoding.EncodeToString(unsafe.Slice(out.Data, out.Size))), 0600)
+}
+
+func (k dpapiKeyring) Delete() error {
+	return fileKeyring{path: k.path}.Delete()
+}
+
+func newBlob(data []byte) *windows.DataBlob {
+	if len(data) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+}
@@ -0,0 +1,349 @@
+// Package vault 使用 AES-GCM 加密保存在 config.db 和 config.json 中的凭据。
+// 数据密钥默认保存在系统密钥环中，没有可用的密钥环时保存在权限为 0600 的文件中；
+// 设置主密码后数据密钥只以主密码加密的形式保存在数据库中，每次启动需要解锁。
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密后的值的前缀，没有前缀的值视为明文
+const encryptedPrefix = "enc:v1:"
+
+// vault_meta 中保存的项
+const (
+	metaMaster = "master" // 主密码加密后的数据密钥，格式为 base64(salt):密文
+	metaCheck  = "check"  // 用于校验数据密钥是否正确的密文
+)
+
+const checkValue = "slack-vault"
+
+var (
+	ErrLocked        = errors.New("凭据库已锁定，请先输入主密码解锁")
+	ErrWrongPassword = errors.New("主密码错误")
+	ErrKeyMismatch   = errors.New("数据密钥与已加密的数据不匹配")
+)
+
+type Vault struct {
+	db      *sql.DB
+	keyring keyring
+	mu      sync.RWMutex
+	key     []byte // 解锁后的数据密钥
+}
+
+var defaultVault atomic.Pointer[Vault]
+
+// SetDefault 设置其他模块通过 Default 获取的凭据库
+func SetDefault(v *Vault) {
+	defaultVault.Store(v)
+}
+
+// Default 返回程序启动时打开的凭据库，没有打开时返回 nil
+func Default() *Vault {
+	return defaultVault.Load()
+}
+
+// Open 创建凭据库需要的表，没有设置主密码时从密钥环读取数据密钥，首次使用时生成。
+// keyFile 为没有可用的系统密钥环时保存数据密钥的文件
+func Open(db *sql.DB, keyFile string) (*Vault, error) {
+	return open(db, newKeyring(keyFile))
+}
+
+func open(db *sql.DB, kr keyring) (*Vault, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS vault_meta ( name TEXT PRIMARY KEY, value TEXT );
+		CREATE TABLE IF NOT EXISTS secrets ( name TEXT PRIMARY KEY, value TEXT );
+	`)
+	if err != nil {
+		return nil, err
+	}
+	v := &Vault{db: db, keyring: kr}
+	if v.HasMasterPassword() {
+		return v, nil
+	}
+	key, err := kr.Get()
+	if errors.Is(err, errKeyNotFound) {
+		// 已经有加密数据时不能重新生成，否则之前的数据都无法解密
+		if _, ok := v.meta(metaCheck); ok {
+			return v, errors.New("找不到数据密钥，已加密的凭据无法解密")
+		}
+		key = make([]byte, 32)
+		if _, err = rand.Read(key); err != nil {
+			return v, err
+		}
+		if err = kr.Set(key); err != nil {
+			return v, err
+		}
+	} else if err != nil {
+		return v, err
+	}
+	return v, v.setKey(key)
+}
+
+// 校验并保存数据密钥，首次使用时写入校验值
+func (v *Vault) setKey(key []byte) error {
+	if check, ok := v.meta(metaCheck); ok {
+		plain, err := decrypt(key, check)
+		if err != nil || plain != checkValue {
+			return ErrKeyMismatch
+		}
+	} else {
+		check, err := encrypt(key, checkValue)
+		if err != nil {
+			return err
+		}
+		if err = v.setMeta(metaCheck, check); err != nil {
+			return err
+		}
+	}
+	v.mu.Lock()
+	v.key = key
+	v.mu.Unlock()
+	return nil
+}
+
+// KeyringName 返回保存数据密钥的位置
+func (v *Vault) KeyringName() string {
+	if v.HasMasterPassword() {
+		return "master password"
+	}
+	return v.keyring.Name()
+}
+
+func (v *Vault) Locked() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.key == nil
+}
+
+func (v *Vault) HasMasterPassword() bool {
+	_, ok := v.meta(metaMaster)
+	return ok
+}
+
+// Unlock 使用主密码解密数据密钥
+func (v *Vault) Unlock(password string) error {
+	master, ok := v.meta(metaMaster)
+	if !ok {
+		return errors.New("没有设置主密码")
+	}
+	salt, wrapped, found := strings.Cut(master, ":")
+	if !found {
+		return errors.New("主密码数据格式错误")
+	}
+	kek, err := deriveKey(password, salt)
+	if err != nil {
+		return err
+	}
+	encoded, err := decrypt(kek, wrapped)
+	if err != nil {
+		return ErrWrongPassword
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	return v.setKey(key)
+}
+
+// Lock 清除内存中的数据密钥，只在设置了主密码时有效
+func (v *Vault) Lock() {
+	if !v.HasMasterPassword() {
+		return
+	}
+	v.mu.Lock()
+	v.key = nil
+	v.mu.Unlock()
+}
+
+// SetMasterPassword 设置或修改主密码，需要先解锁。password 为空时取消主密码，数据密钥重新保存到密钥环
+func (v *Vault) SetMasterPassword(password string) error {
+	v.mu.RLock()
+	key := v.key
+	v.mu.RUnlock()
+	if key == nil {
+		return ErrLocked
+	}
+	if password == "" {
+		if err := v.keyring.Set(key); err != nil {
+			return err
+		}
+		_, err := v.db.Exec("DELETE FROM vault_meta WHERE name = ?", metaMaster)
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	encodedSalt := base64.StdEncoding.EncodeToString(salt)
+	kek, err := deriveKey(password, encodedSalt)
+	if err != nil {
+		return err
+	}
+	wrapped, err := encrypt(kek, base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		return err
+	}
+	previous, hadMaster := v.meta(metaMaster)
+	if err = v.setMeta(metaMaster, encodedSalt+":"+wrapped); err != nil {
+		return err
+	}
+	// 主密码保存成功后才删除密钥环中的数据密钥，删除失败时恢复原来的主密码设置，
+	// 避免提示已由主密码保护而数据密钥仍然明文保存
+	if err = v.keyring.Delete(); err != nil {
+		if hadMaster {
+			v.setMeta(metaMaster, previous)
+		} else {
+			v.db.Exec("DELETE FROM vault_meta WHERE name = ?", metaMaster)
+		}
+		return err
+	}
+	return nil
+}
+
+// Encrypt 加密一个值，空值和已经加密的值原样返回
+func (v *Vault) Encrypt(plain string) (string, error) {
+	if plain == "" || IsEncrypted(plain) {
+		return plain, nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.key == nil {
+		return "", ErrLocked
+	}
+	return encrypt(v.key, plain)
+}
+
+// Decrypt 解密一个值，没有加密前缀的值视为旧版本保存的明文原样返回
+func (v *Vault) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.key == nil {
+		return "", ErrLocked
+	}
+	return decrypt(v.key, value)
+}
+
+// Secret 返回指定名称的凭据，不存在时返回空字符串
+func (v *Vault) Secret(name string) (string, error) {
+	var value string
+	err := v.db.QueryRow("SELECT value FROM secrets WHERE name = ?", name).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return v.Decrypt(value)
+}
+
+// SetSecret 加密保存凭据，value 为空时删除
+func (v *Vault) SetSecret(name, value string) error {
+	if value == "" {
+		_, err := v.db.Exec("DELETE FROM secrets WHERE name = ?", name)
+		return err
+	}
+	encrypted, err := v.Encrypt(value)
+	if err != nil {
+		return err
+	}
+	_, err = v.db.Exec("INSERT OR REPLACE INTO secrets (name, value) VALUES (?, ?)", name, encrypted)
+	return err
+}
+
+// SecretNames 返回所有凭据的名称
+func (v *Vault) SecretNames() ([]string, error) {
+	rows, err := v.db.Query("SELECT name FROM secrets ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return names, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+func (v *Vault) meta(name string) (string, bool) {
+	var value string
+	if err := v.db.QueryRow("SELECT value FROM vault_meta WHERE name = ?", name).Scan(&value); err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+func (v *Vault) setMeta(name, value string) error {
+	_, err := v.db.Exec("INSERT OR REPLACE INTO vault_meta (name, value) VALUES (?, ?)", name, value)
+	return err
+}
+
+// 使用 scrypt 从主密码派生密钥加密密钥
+func deriveKey(password, encodedSalt string) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return nil, err
+	}
+	return scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+}
+
+func encrypt(key []byte, plain string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decrypt(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("密文长度错误")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestVault(t *testing.T) (*Vault, *sql.DB, fileKeyring) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "config.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	kr := fileKeyring{path: filepath.Join(dir, "vault.key")}
+	v, err := open(db, kr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, db, kr
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	v, db, kr := openTestVault(t)
+	encrypted, err := v.Encrypt("p@ssw0rd")
+	if err != nil || !IsEncrypted(encrypted) {
+		t.Fatalf("unexpected value %q: %v", encrypted, err)
+	}
+	// 旧版本保存的明文原样返回，已加密的值不会重复加密
+	if plain, _ := v.Decrypt("plaintext"); plain != "plaintext" {
+		t.Fatalf("got %q", plain)
+	}
+	if again, _ := v.Encrypt(encrypted); again != encrypted {
+		t.Fatal("encrypted value was encrypted again")
+	}
+	// 重新打开后使用密钥文件中的数据密钥
+	reopened, err := open(db, kr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := reopened.Decrypt(encrypted); err != nil || plain != "p@ssw0rd" {
+		t.Fatalf("got %q: %v", plain, err)
+	}
+}
+
+func TestMasterPassword(t *testing.T) {
+	v, db, kr := openTestVault(t)
+	if err := v.SetSecret("space.fofakey", "secret-key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetMasterPassword("master"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kr.Get(); err != errKeyNotFound {
+		t.Fatalf("data key should be removed from keyring: %v", err)
+	}
+	locked, err := open(db, kr)
+	if err != nil || !locked.Locked() {
+		t.Fatalf("vault should be locked: %v", err)
+	}
+	if _, err = locked.Secret("space.fofakey"); err != ErrLocked {
+		t.Fatalf("got %v", err)
+	}
+	if err = locked.Unlock("wrong"); err != ErrWrongPassword {
+		t.Fatalf("got %v", err)
+	}
+	if err = locked.Unlock("master"); err != nil {
+		t.Fatal(err)
+	}
+	if secret, _ := locked.Secret("space.fofakey"); secret != "secret-key" {
+		t.Fatalf("got %q", secret)
+	}
+	// 取消主密码后数据密钥重新保存到密钥环
+	if err = locked.SetMasterPassword(""); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := open(db, kr)
+	if err != nil || reopened.Locked() {
+		t.Fatalf("vault should be unlocked: %v", err)
+	}
+}
+
+func TestMissingKey(t *testing.T) {
+	v, db, kr := openTestVault(t)
+	v.SetSecret("proxy.password", "secret")
+	kr.Delete()
+	// 已经有加密数据时不能生成新的数据密钥
+	if _, err := open(db, kr); err == nil {
+		t.Fatal("expected error when data key is missing")
+	}
+}
+
+// 模拟系统密钥环，readable 为假时模拟没有 D-Bus 会话等无法访问的情况
+type stubKeyring struct {
+	readable bool
+	key      []byte
+}
+
+func (k *stubKeyring) Name() string { return "stub" }
+
+func (k *stubKeyring) Get() ([]byte, error) {
+	if !k.readable {
+		return nil, errors.New("keyring unavailable")
+	}
+	return k.key, nil
+}
+
+func (k *stubKeyring) Set(key []byte) error { return errors.New("keyring unavailable") }
+
+func (k *stubKeyring) Delete() error { return errors.New("keyring unavailable") }
+
+func TestFallbackKeyringDelete(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "config.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	file := fileKeyring{path: filepath.Join(dir, "vault.key")}
+	primary := &stubKeyring{}
+	v, err := open(db, fallbackKeyring{primary: primary, fallback: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 系统密钥环无法访问时数据密钥保存在文件中，设置主密码后需要删除密钥文件
+	if err = v.SetMasterPassword("master"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(file.path); !os.IsNotExist(err) {
+		t.Fatalf("key file should be removed: %v", err)
+	}
+	// 系统密钥环中的数据密钥无法删除时恢复原来的设置
+	if err = v.SetMasterPassword(""); err != nil {
+		t.Fatal(err)
+	}
+	primary.readable, primary.key = true, []byte("stale")
+	if err = v.SetMasterPassword("master"); err == nil || v.HasMasterPassword() {
+		t.Fatalf("master password should be rolled back: %v", err)
+	}
+}
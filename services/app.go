@@ -6,6 +6,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -24,10 +25,10 @@ import (
 	"slack-wails/lib/clients"
 	"slack-wails/lib/control"
 	"slack-wails/lib/gologger"
+	"slack-wails/lib/gonmap"
 	"slack-wails/lib/netutil"
 	"slack-wails/lib/structs"
 	"slack-wails/lib/util"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -45,6 +46,9 @@ type App struct {
 	templateDir      string
 	defaultPath      string
 	cyberCherDir     string
+	templateManager  *webscan.TemplateManager
+	probeDir         string   // 用户自定义的 gonmap 探针文件目录
+	probeErrors      []string // 自定义探针文件的解析错误
 }
 
 // NewApp creates a new App application struct
@@ -58,6 +62,8 @@ func NewApp() *App {
 		templateDir:      home + "/slack/config/pocs",
 		defaultPath:      home + "/slack/",
 		cyberCherDir:     filepath.Join(home, "slack", "CyberChef"),
+		templateManager:  webscan.NewTemplateManager(home+"/slack/config/pocs", home+"/slack/config/pocs-disabled", home+"/slack/keys"),
+		probeDir:         home + "/slack/config/probes",
 	}
 }
 
@@ -65,6 +71,15 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx
+	for _, err := range gonmap.LoadCustomProbeDir(a.probeDir) {
+		a.probeErrors = append(a.probeErrors, err.Error())
+		gologger.DualLog(ctx, gologger.Level_ERROR, fmt.Sprintf("[gonmap] load custom probe failed: %v", err))
+	}
+}
+
+// CustomProbeErrors 返回启动时加载自定义探针文件产生的错误
+func (a *App) CustomProbeErrors() []string {
+	return a.probeErrors
 }
 
 // 返回 true 将导致应用程序继续，false 将继续正常关闭
@@ -314,33 +329,96 @@ func (a *App) HostAlive(targets []string, Ping bool) []string {
 	return portscan.CheckLive(a.ctx, targets, Ping)
 }
 
+// DiscoverHosts 按顺序使用多种方式探测存活主机，methods 为空时使用 ICMP
+func (a *App) DiscoverHosts(targets []string, methods []string) []structs.AliveHost {
+	ctrlCtx, _ := control.GetScanContext(control.Portscan) // 标识任务
+	return portscan.NewHostDiscovery(methods...).Run(a.ctx, ctrlCtx, targets)
+}
+
 func (a *App) SpaceGetPort(ip string) []float64 {
 	return space.GetShodanAllPort(a.ctx, ip)
 }
 
 func (a *App) NewTcpScanner(taskId string, specialTargets []string, ips []string, ports []int, thread, timeout int, proxy clients.Proxy) {
+	a.NewTcpScannerWithOption(taskId, specialTargets, ips, ports, structs.PortscanOption{
+		Thread:          thread,
+		Timeout:         timeout,
+		Rate:            1000,
+		Retries:         1,
+		Timing:          3,
+		DetectMiddlebox: true,
+	}, proxy)
+}
+
+// 开启 SYN 扫描且具备 root 权限、未使用代理时，先进行 SYN 扫描，仅将开放端口交给 gonmap 识别服务
+func (a *App) NewTcpScannerWithOption(taskId string, specialTargets []string, ips []string, ports []int, option structs.PortscanOption, proxy clients.Proxy) {
 	ctrlCtx, _ := control.GetScanContext(control.Portscan) // 标识任务
+	if len(option.Targets) == 0 {
+		option.Targets = ips
+	}
+	iterator, err := util.NewTargetIterator(option.Targets, option.ExcludeFile, option.Randomize)
+	if err != nil {
+		gologger.Error(a.ctx, fmt.Sprintf("[portscan] parse targets err: %v", err))
+		return
+	}
 	addresses := make(chan portscan.Address)
 
 	go func() {
 		defer close(addresses)
-		// Generate addresses from ips and ports
-		for _, ip := range ips {
+		// Generate addresses from targets and ports
+		for ip, ok := iterator.Next(); ok && ctrlCtx.Err() == nil; ip, ok = iterator.Next() {
 			for _, port := range ports {
 				addresses <- portscan.Address{IP: ip, Port: port}
 			}
 		}
 		// Generate addresses from special targets
 		for _, target := range specialTargets {
-			temp := strings.Split(target, ":")
-			port, err := strconv.Atoi(temp[1]) // Skip if port conversion fails
+			host, port, err := util.SplitHostPort(target) // 支持 IPv6 的 [addr]:port 格式
 			if err != nil {
 				continue
 			}
-			addresses <- portscan.Address{IP: temp[0], Port: port}
+			addresses <- portscan.Address{IP: host, Port: port}
 		}
 	}()
-	portscan.TcpScan(a.ctx, ctrlCtx, taskId, addresses, thread, timeout, proxy)
+	var targets <-chan portscan.Address = addresses
+	if option.SynScan && !proxy.Enabled && portscan.SynAvailable() {
+		scanner, err := portscan.NewSynScanner(option.Rate, option.Retries, 0)
+		if err != nil {
+			gologger.Warning(a.ctx, fmt.Sprintf("[portscan] syn scan is unavailable, fallback to connect scan: %v", err))
+		} else {
+			gologger.Info(a.ctx, fmt.Sprintf("[portscan] syn scan is running, rate: %d pps", option.Rate))
+			targets = scanner.Scan(a.ctx, ctrlCtx, addresses)
+		}
+	}
+	var timing *portscan.HostTiming
+	if option.Timing > 0 {
+		timing = portscan.NewHostTiming(option.Timing)
+	}
+	var middlebox *portscan.MiddleboxDetector
+	if option.DetectMiddlebox {
+		middlebox = portscan.NewMiddleboxDetector(5, 0)
+	}
+	portscan.TcpScan(a.ctx, ctrlCtx, taskId, targets, option.Thread, option.Timeout, timing, middlebox, option.FilterMiddlebox, proxy)
+
+	// UDP 无法通过代理转发，开启代理时跳过
+	if option.UDP && !proxy.Enabled && ctrlCtx.Err() == nil {
+		udpPorts := option.UDPPorts
+		if len(udpPorts) == 0 {
+			udpPorts = gonmap.UDPDefaultPorts
+		}
+		gologger.Info(a.ctx, fmt.Sprintf("[portscan] udp scan is running, ports: %v", udpPorts))
+		udpAddresses := make(chan portscan.Address)
+		iterator.Reset()
+		go func() {
+			defer close(udpAddresses)
+			for ip, ok := iterator.Next(); ok && ctrlCtx.Err() == nil; ip, ok = iterator.Next() {
+				for _, port := range udpPorts {
+					udpAddresses <- portscan.Address{IP: ip, Port: port}
+				}
+			}
+		}()
+		portscan.UdpScan(a.ctx, ctrlCtx, taskId, udpAddresses, option.Thread, option.Timeout)
+	}
 }
 
 // 端口暴破
@@ -349,6 +427,12 @@ func (a *App) NewCrackScanenr(taskId, host string, usernames, passwords []string
 	portscan.Runner(a.ctx, ctrlCtx, taskId, host, usernames, passwords)
 }
 
+// 端口暴破，可以指定并发数、尝试间隔、单账号最大尝试次数以及成功后的停止策略
+func (a *App) NewCrackScannerWithOption(taskId, host string, usernames, passwords []string, option structs.BruteOption) {
+	ctrlCtx, _ := control.GetScanContext(control.Crack) // 标识任务
+	portscan.RunnerWithOption(a.ctx, ctrlCtx, taskId, host, usernames, passwords, option)
+}
+
 // fofa
 
 func (a *App) FofaTips(query string) *structs.TipsResult {
@@ -469,10 +553,120 @@ func (a *App) NewWebScanner(taskId string, options structs.WebscanOptions, proxy
 	webscan.IsRunning = false
 }
 
+// 导入 Burp/HAR/OpenAPI 请求或 jsfind 接口，使用 DAST 模板进行模糊测试
+func (a *App) NewFuzzScanner(taskId string, option structs.FuzzOption, proxy clients.Proxy) {
+	ctrlCtx, cancel := control.GetScanContext(control.Webscan) // 标识任务
+	defer cancel()
+	webscan.IsRunning = true
+	gologger.Info(a.ctx, "Init nuclei engine, fuzzing scan is running ...")
+	if len(option.TemplateFolders) == 0 {
+		option.TemplateFolders = []string{a.templateDir}
+	}
+	option.Proxy = clients.GetRawProxy(proxy)
+	webscan.NewNucleiFuzzEngine(a.ctx, ctrlCtx, taskId, option)
+	gologger.Info(a.ctx, "Fuzzing scan has ended")
+	webscan.IsRunning = false
+}
+
+// 对单个漏洞进行复测，nuclei 漏洞使用对应模板重新扫描，弱口令与未授权使用端口爆破模块验证
+func (a *App) VerifyFinding(vuln structs.VulnerabilityInfo, proxy clients.Proxy) structs.FindingVerification {
+	result := structs.FindingVerification{
+		Fingerprint: vuln.Fingerprint,
+		VerifyTime:  time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if result.Fingerprint == "" {
+		result.Fingerprint = webscan.VulnerabilityFingerprint(vuln)
+	}
+	var vulnerable bool
+	var err error
+	if portscan.IsBuiltinFinding(vuln.ID) {
+		vulnerable, err = portscan.VerifyFinding(vuln.ID, vuln.URL, vuln.Extract)
+		// 只有认证失败视为漏洞已不存在，连接失败、超时作为复测出错
+		if errors.Is(err, portscan.ErrAuthFailed) {
+			result.Msg = err.Error()
+			err = nil
+		}
+	} else {
+		templatePath, ok := a.templateManager.FindByID(vuln.ID)
+		if !ok {
+			result.Result = webscan.VerifyResultError
+			result.Msg = fmt.Sprintf("template %s not found", vuln.ID)
+			return result
+		}
+		vulnerable, err = webscan.RecheckFinding(a.ctx, structs.NucleiOption{
+			URL:          vuln.URL,
+			TemplateFile: []string{templatePath},
+			Proxy:        clients.GetRawProxy(proxy),
+		})
+	}
+	switch {
+	case err != nil:
+		result.Result = webscan.VerifyResultError
+		result.Msg = err.Error()
+	case vulnerable:
+		result.Result = webscan.VerifyResultVulnerable
+	default:
+		result.Result = webscan.VerifyResultNotVulnerable
+	}
+	gologger.Info(a.ctx, fmt.Sprintf("[verify] %s %s: %s", vuln.ID, vuln.URL, result.Result))
+	return result
+}
+
 func (a *App) GetFingerPocMap() map[string][]string {
 	return webscan.WorkFlowDB
 }
 
+// nuclei 模板管理
+
+func (a *App) IndexTemplates() []structs.NucleiTemplate {
+	return a.templateManager.Index()
+}
+
+func (a *App) SearchTemplates(query string) []structs.NucleiTemplate {
+	return a.templateManager.Search(query)
+}
+
+func (a *App) ValidateTemplate(path string) structs.Status {
+	if err := a.templateManager.Validate(path); err != nil {
+		return structs.Status{Error: true, Msg: err.Error()}
+	}
+	return structs.Status{Error: false, Msg: "Template is valid"}
+}
+
+func (a *App) DisableTemplate(path string) bool {
+	if err := a.templateManager.Disable(path); err != nil {
+		gologger.Error(a.ctx, fmt.Sprintf("[nuclei] disable template %s err: %v", path, err))
+		return false
+	}
+	return true
+}
+
+func (a *App) EnableTemplate(path string) bool {
+	if err := a.templateManager.Enable(path); err != nil {
+		gologger.Error(a.ctx, fmt.Sprintf("[nuclei] enable template %s err: %v", path, err))
+		return false
+	}
+	return true
+}
+
+func (a *App) VerifyTemplate(path string) structs.Status {
+	verified, err := a.templateManager.Verify(path)
+	if err != nil {
+		return structs.Status{Error: true, Msg: err.Error()}
+	}
+	if !verified {
+		return structs.Status{Error: true, Msg: "Template is not signed or tampered"}
+	}
+	return structs.Status{Error: false, Msg: "Template signature is valid"}
+}
+
+func (a *App) SignTemplate(path string) structs.Status {
+	if err := a.templateManager.Sign(path); err != nil {
+		return structs.Status{Error: true, Msg: err.Error()}
+	}
+	return structs.Status{Error: false, Msg: "Template signed successfully"}
+}
+
 // hunter
 
 func (a *App) HunterTips(query string) *structs.HunterTips {
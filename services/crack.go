@@ -0,0 +1,44 @@
+package services
+
+import (
+	"fmt"
+	"slack-wails/core/portscan"
+	"slack-wails/lib/control"
+	"slack-wails/lib/structs"
+	"slack-wails/lib/util"
+)
+
+// NewBatchCrackScanner 对端口扫描结果批量暴破，targets 为空时使用任务中保存的指纹结果，
+// 发现的漏洞通过 nucleiResult 事件推送，与其他漏洞一样由前端保存到该任务中
+func (d *Database) NewBatchCrackScanner(taskId string, targets []structs.InfoResult, option structs.BatchCrackOption) {
+	ctrlCtx, _ := control.GetScanContext(control.Crack) // 标识任务
+	if len(targets) == 0 {
+		targets = d.RetrieveFingerscanResults(taskId)
+	}
+	portscan.BatchRunner(d.ctx, ctrlCtx, taskId, targets, option.Thread, func(scheme string) ([]string, []string) {
+		return crackDict(scheme, option)
+	}, option.Brute)
+}
+
+// 读取 ~/slack/portburte 下的字典，文件不存在时使用内置字典，内置密码额外包含空密码
+func crackDict(scheme string, option structs.BatchCrackOption) ([]string, []string) {
+	var usernames, passwords []string
+	if option.BuiltinUsername {
+		users, err := util.ParseFile(fmt.Sprintf("%s/slack/portburte/username/%s.txt", util.HomeDir(), scheme))
+		if err != nil {
+			users = Userdict[scheme]
+		}
+		usernames = append(usernames, users...)
+	}
+	usernames = append(usernames, option.Usernames...)
+	if option.BuiltinPassword {
+		pass, err := util.ParseFile(util.HomeDir() + "/slack/portburte/password/password.txt")
+		if err != nil {
+			pass = Passwords
+		}
+		passwords = append(passwords, pass...)
+		passwords = append(passwords, "")
+	}
+	passwords = append(passwords, option.Passwords...)
+	return util.RemoveDuplicates(usernames), util.RemoveDuplicates(passwords)
+}
@@ -9,6 +9,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slack-wails/core/portscan"
+	"slack-wails/core/webscan"
 	"slack-wails/lib/fileutil"
 	"slack-wails/lib/gologger"
 	"slack-wails/lib/report"
@@ -16,8 +18,10 @@ import (
 	"slack-wails/lib/util"
 	"strings"
 	"sync"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -28,10 +32,22 @@ type Database struct {
 	OtherDatabase *sql.DB                     // 数据库信息采集时的连接池
 	MongoClient   *mongo.Client               // mongodb连接池
 	PostgresInfo  *structs.DatabaseConnection // 用于临时存储postgres数据库连接信息，方便其他方法调用
+	Connection    *structs.DatabaseConnection // 数据库管理中当前连接的信息
+	vaultErr      error                       // 打开凭据库时的错误
+	findingLock   sync.Mutex                  // 保证漏洞查重与写入的原子性
 }
 
 func (d *Database) Startup(ctx context.Context) {
 	d.ctx = ctx
+	// 端口扫描获取的证书由后端保存，用于汇总证书中的域名
+	runtime.EventsOn(ctx, "tlsCertificate", func(data ...interface{}) {
+		if len(data) == 0 {
+			return
+		}
+		if cert, ok := data[0].(*structs.CertInfo); ok {
+			d.AddCertificateResult(*cert)
+		}
+	})
 }
 
 func NewDatabase() *Database {
@@ -49,9 +65,11 @@ func NewDatabase() *Database {
 			DB: nil,
 		}
 	}
-	return &Database{
+	d := &Database{
 		DB: db,
 	}
+	openVault(d)
+	return d
 }
 
 // SQLite 检查字段是否已存在
@@ -90,8 +108,11 @@ func (d *Database) CreateTable() bool {
         CREATE TABLE IF NOT EXISTS dirsearch ( path TEXT, times INTEGER );
         CREATE TABLE IF NOT EXISTS dbManager ( nanoid TEXT, scheme TEXT, host TEXT, port INTEGER, username TEXT, password TEXT, notes TEXT );
         CREATE TABLE IF NOT EXISTS scanTask ( task_id TEXT PRIMARY KEY, task_name TEXT, targets TEXT, failed INTEGER, vulnerability INTEGER );
-        CREATE TABLE IF NOT EXISTS FingerprintInfo ( task_id TEXT, url TEXT, status INTEGER, length INTEGER, title TEXT, detect TEXT, is_waf INTEGER, waf TEXT, fingerprints TEXT, screenshot TEXT, host TEXT, scheme TEXT, port INTEGER );
-        CREATE TABLE IF NOT EXISTS VulnerabilityInfo ( task_id TEXT, template_id TEXT, vuln_name TEXT, protocol TEXT, severity TEXT, vuln_url TEXT, extract TEXT, request TEXT, response TEXT, description TEXT, reference TEXT, response_time TEXT );
+        CREATE TABLE IF NOT EXISTS FingerprintInfo ( task_id TEXT, url TEXT, status INTEGER, length INTEGER, title TEXT, detect TEXT, is_waf INTEGER, waf TEXT, fingerprints TEXT, screenshot TEXT, host TEXT, scheme TEXT, port INTEGER, is_middlebox INTEGER, product TEXT, version TEXT, extra_info TEXT, device_type TEXT, os TEXT, cpe TEXT );
+        CREATE TABLE IF NOT EXISTS VulnerabilityInfo ( task_id TEXT, template_id TEXT, vuln_name TEXT, protocol TEXT, severity TEXT, vuln_url TEXT, extract TEXT, request TEXT, response TEXT, description TEXT, reference TEXT, response_time TEXT, fingerprint TEXT, status TEXT, notes TEXT, verify_result TEXT, verify_time TEXT, cve TEXT, cwe TEXT, cvss_score REAL, cvss_vector TEXT, epss_score REAL, epss_percentile REAL, matcher_name TEXT );
+        CREATE TABLE IF NOT EXISTS CertificateInfo ( task_id TEXT, host TEXT, port INTEGER, subject_cn TEXT, subject_dn TEXT, issuer_cn TEXT, issuer_dn TEXT, sans TEXT, domains TEXT, not_before TEXT, not_after TEXT, sha256 TEXT, serial_number TEXT, signature_algorithm TEXT, key_algorithm TEXT, key_bits INTEGER, expired INTEGER, self_signed INTEGER, weak INTEGER, issues TEXT );
+        CREATE TABLE IF NOT EXISTS FindingTriage ( fingerprint TEXT PRIMARY KEY, status TEXT, notes TEXT, update_time TEXT );
+        CREATE TABLE IF NOT EXISTS dbQueryHistory ( id INTEGER PRIMARY KEY AUTOINCREMENT, scheme TEXT, host TEXT, database TEXT, query TEXT, rows INTEGER, elapsed INTEGER, error TEXT, create_time TEXT );
     `)
 	if err != nil {
 		gologger.Debug(d.ctx, fmt.Sprintf("[sqlite] create table: %s", err))
@@ -122,6 +143,40 @@ func (d *Database) CreateTable() bool {
 			return false
 		}
 	}
+	if !columnExists(d.DB, "FingerprintInfo", "is_middlebox") {
+		_, err := d.DB.Exec(`ALTER TABLE FingerprintInfo ADD COLUMN is_middlebox INTEGER`)
+		if err != nil {
+			return false
+		}
+	}
+	for _, column := range []string{"product", "version", "extra_info", "device_type", "os", "cpe"} {
+		if !columnExists(d.DB, "FingerprintInfo", column) {
+			_, err := d.DB.Exec(`ALTER TABLE FingerprintInfo ADD COLUMN ` + column + ` TEXT`)
+			if err != nil {
+				return false
+			}
+		}
+	}
+	for _, column := range []string{"fingerprint", "status", "notes", "verify_result", "verify_time", "matcher_name"} {
+		if !columnExists(d.DB, "VulnerabilityInfo", column) {
+			_, err := d.DB.Exec(`ALTER TABLE VulnerabilityInfo ADD COLUMN ` + column + ` TEXT`)
+			if err != nil {
+				return false
+			}
+		}
+	}
+	// 漏洞分类信息
+	for column, columnType := range map[string]string{"cve": "TEXT", "cwe": "TEXT", "cvss_score": "REAL", "cvss_vector": "TEXT", "epss_score": "REAL", "epss_percentile": "REAL"} {
+		if !columnExists(d.DB, "VulnerabilityInfo", column) {
+			_, err := d.DB.Exec(`ALTER TABLE VulnerabilityInfo ADD COLUMN ` + column + ` ` + columnType)
+			if err != nil {
+				return false
+			}
+		}
+	}
+	d.backfillFindingFingerprint()
+	d.loadFindingTriage()
+	d.migrateSecrets()
 	return err == nil
 }
 
@@ -173,6 +228,7 @@ func (d *Database) SaveWindowsScreenSize(width, height int) bool {
 	return d.ExecSqlStatement("UPDATE windows_size SET width = ?, height = ? WHERE id = 1", width, height)
 }
 
+// SelectAllAgentPool 返回解密后的代理地址，凭据库锁定时无法解密的地址会被跳过
 func (d *Database) SelectAllAgentPool() (hosts []string) {
 	var host string
 	rows, err := d.DB.Query("SELECT hosts FROM agent_pool")
@@ -181,19 +237,45 @@ func (d *Database) SelectAllAgentPool() (hosts []string) {
 	}
 	for rows.Next() {
 		rows.Scan(&host)
-		hosts = append(hosts, host)
+		if host, err = openSecret(host); err == nil {
+			hosts = append(hosts, host)
+		}
 	}
 	return hosts
 }
 
 func (d *Database) InsertAgentPool(host string) bool {
+	encrypted, err := sealSecret(host)
+	if err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[vault] 保存代理失败: %v", err))
+		return false
+	}
 	insertStmt := "INSERT INTO agent_pool(hosts) VALUES(?)"
-	return d.ExecSqlStatement(insertStmt, host)
+	return d.ExecSqlStatement(insertStmt, encrypted)
 }
 
+// 加密后的值每次都不同，需要解密后比较
 func (d *Database) DeleteAgentPool(host string) bool {
-	deleteStmt := "DELETE FROM agent_pool WHERE hosts = ?"
-	return d.ExecSqlStatement(deleteStmt, host)
+	rows, err := d.DB.Query("SELECT rowid, hosts FROM agent_pool")
+	if err != nil {
+		return false
+	}
+	var rowids []int64
+	for rows.Next() {
+		var rowid int64
+		var value string
+		rows.Scan(&rowid, &value)
+		if plain, err := openSecret(value); err == nil && plain == host || value == host {
+			rowids = append(rowids, rowid)
+		}
+	}
+	rows.Close()
+	for _, rowid := range rowids {
+		if !d.ExecSqlStatement("DELETE FROM agent_pool WHERE rowid = ?", rowid) {
+			return false
+		}
+	}
+	return true
 }
 
 func (d *Database) DeleteAllAgentPool() bool {
@@ -358,6 +440,7 @@ func (d *Database) RemoveScanTask(taskid string) bool {
 	if isSuccess {
 		d.ExecSqlStatement("DELETE FROM FingerprintInfo WHERE task_id = ?", taskid)
 		d.ExecSqlStatement("DELETE FROM VulnerabilityInfo WHERE task_id = ?", taskid)
+		d.ExecSqlStatement("DELETE FROM CertificateInfo WHERE task_id = ?", taskid)
 	}
 	return isSuccess
 }
@@ -370,7 +453,7 @@ func (d *Database) RenameScanTask(taskid, taskname string) bool {
 
 // 根据taskid检索指纹扫描的结果
 func (d *Database) RetrieveFingerscanResults(taskid string) []structs.InfoResult {
-	rows, err := d.DB.Query("SELECT * FROM FingerprintInfo WHERE task_id = ?;", taskid)
+	rows, err := d.DB.Query("SELECT task_id, url, status, length, title, detect, is_waf, waf, fingerprints, screenshot, host, scheme, port, COALESCE(is_middlebox, 0), COALESCE(product, ''), COALESCE(version, ''), COALESCE(extra_info, ''), COALESCE(device_type, ''), COALESCE(os, ''), COALESCE(cpe, '') FROM FingerprintInfo WHERE task_id = ?;", taskid)
 	if err != nil {
 		gologger.Debug(d.ctx, err)
 		return []structs.InfoResult{}
@@ -384,7 +467,7 @@ func (d *Database) RetrieveFingerscanResults(taskid string) []structs.InfoResult
 		var host *string // 使用指针来处理可能的 NULL 值
 		var scheme *string
 		var port *int
-		err = rows.Scan(&task_id, &result.URL, &result.StatusCode, &result.Length, &result.Title, &result.Detect, &result.IsWAF, &result.WAF, &fingerprintsStr, &result.Screenshot, &host, &scheme, &port)
+		err = rows.Scan(&task_id, &result.URL, &result.StatusCode, &result.Length, &result.Title, &result.Detect, &result.IsWAF, &result.WAF, &fingerprintsStr, &result.Screenshot, &host, &scheme, &port, &result.Middlebox, &result.Product, &result.Version, &result.ExtraInfo, &result.DeviceType, &result.OS, &result.CPE)
 		if err != nil {
 			gologger.Debug(d.ctx, err)
 			continue
@@ -414,7 +497,7 @@ func (d *Database) RetrieveFingerscanResults(taskid string) []structs.InfoResult
 
 // 根据taskid检索漏洞扫描记录
 func (d *Database) RetrievePocscanResults(taskid string) []structs.VulnerabilityInfo {
-	rows, err := d.DB.Query("SELECT * FROM VulnerabilityInfo WHERE task_id = ?", taskid)
+	rows, err := d.DB.Query("SELECT task_id, template_id, vuln_name, protocol, severity, vuln_url, extract, request, response, description, reference, response_time, fingerprint, status, notes, verify_result, verify_time, COALESCE(cve, ''), COALESCE(cwe, ''), COALESCE(cvss_score, 0), COALESCE(cvss_vector, ''), COALESCE(epss_score, 0), COALESCE(epss_percentile, 0), COALESCE(matcher_name, '') FROM VulnerabilityInfo WHERE task_id = ?", taskid)
 	if err != nil {
 		return []structs.VulnerabilityInfo{}
 	}
@@ -422,9 +505,8 @@ func (d *Database) RetrievePocscanResults(taskid string) []structs.Vulnerability
 	var results []structs.VulnerabilityInfo
 	for rows.Next() {
 		var result structs.VulnerabilityInfo
-		var task_id string
-		var responseTime *string // 使用指针来处理可能的 NULL 值
-		err = rows.Scan(&task_id, &result.ID, &result.Name, &result.Type, &result.Severity, &result.URL, &result.Extract, &result.Request, &result.Response, &result.Description, &result.Reference, &responseTime)
+		var responseTime, fingerprint, status, notes, verifyResult, verifyTime *string // 使用指针来处理可能的 NULL 值
+		err = rows.Scan(&result.TaskId, &result.ID, &result.Name, &result.Type, &result.Severity, &result.URL, &result.Extract, &result.Request, &result.Response, &result.Description, &result.Reference, &responseTime, &fingerprint, &status, &notes, &verifyResult, &verifyTime, &result.CVE, &result.CWE, &result.CVSSScore, &result.CVSSVector, &result.EPSSScore, &result.EPSSPercentile, &result.MatcherName)
 		if err != nil {
 			gologger.Debug(d.ctx, err)
 			continue
@@ -432,6 +514,23 @@ func (d *Database) RetrievePocscanResults(taskid string) []structs.Vulnerability
 		if responseTime != nil {
 			result.ResponseTime = *responseTime // 只有在 responseTime 不为 NULL 时才赋值
 		}
+		// 旧版本的记录没有指纹，按模板和地址补全
+		if fingerprint != nil && *fingerprint != "" {
+			result.Fingerprint = *fingerprint
+		} else {
+			result.Fingerprint = webscan.VulnerabilityFingerprint(result)
+		}
+		result.Status = webscan.FindingStatusNew
+		if status != nil && *status != "" {
+			result.Status = *status
+		}
+		if notes != nil {
+			result.Notes = *notes
+		}
+		if verifyResult != nil && verifyTime != nil {
+			result.VerifyResult, result.VerifyTime = *verifyResult, *verifyTime
+		}
+		result.RiskScore = report.FindingRisk(result)
 		results = append(results, result)
 	}
 	return results
@@ -439,14 +538,84 @@ func (d *Database) RetrievePocscanResults(taskid string) []structs.Vulnerability
 
 // 添加指纹扫描结果
 func (d *Database) AddFingerscanResult(result structs.InfoResult) bool {
-	insertStmt := "INSERT INTO FingerprintInfo (task_id, url, status, length, title, detect, is_waf, waf, fingerprints, screenshot, host, scheme, port) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
-	return d.ExecSqlStatement(insertStmt, result.TaskId, result.URL, result.StatusCode, result.Length, result.Title, result.Detect, result.IsWAF, result.WAF, strings.Join(result.Fingerprints, ","), result.Screenshot, result.Host, result.Scheme, result.Port)
+	insertStmt := "INSERT INTO FingerprintInfo (task_id, url, status, length, title, detect, is_waf, waf, fingerprints, screenshot, host, scheme, port, is_middlebox, product, version, extra_info, device_type, os, cpe) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	return d.ExecSqlStatement(insertStmt, result.TaskId, result.URL, result.StatusCode, result.Length, result.Title, result.Detect, result.IsWAF, result.WAF, strings.Join(result.Fingerprints, ","), result.Screenshot, result.Host, result.Scheme, result.Port, result.Middlebox, result.Product, result.Version, result.ExtraInfo, result.DeviceType, result.OS, result.CPE)
+}
+
+// 添加证书信息，同一任务中相同端口的证书只保留一条
+func (d *Database) AddCertificateResult(cert structs.CertInfo) bool {
+	var count int
+	d.DB.QueryRow("SELECT COUNT(*) FROM CertificateInfo WHERE task_id = ? AND host = ? AND port = ?", cert.TaskId, cert.Host, cert.Port).Scan(&count)
+	if count > 0 {
+		return true
+	}
+	insertStmt := "INSERT INTO CertificateInfo (task_id, host, port, subject_cn, subject_dn, issuer_cn, issuer_dn, sans, domains, not_before, not_after, sha256, serial_number, signature_algorithm, key_algorithm, key_bits, expired, self_signed, weak, issues) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	return d.ExecSqlStatement(insertStmt, cert.TaskId, cert.Host, cert.Port, cert.SubjectCN, cert.SubjectDN, cert.IssuerCN, cert.IssuerDN, strings.Join(cert.SANs, ","), strings.Join(cert.Domains, ","), cert.NotBefore, cert.NotAfter, cert.SHA256, cert.SerialNumber, cert.SignatureAlgorithm, cert.KeyAlgorithm, cert.KeyBits, cert.Expired, cert.SelfSigned, cert.Weak, strings.Join(cert.Issues, ","))
+}
+
+// 根据taskid检索证书信息
+func (d *Database) RetrieveCertificates(taskid string) []structs.CertInfo {
+	rows, err := d.DB.Query("SELECT task_id, host, port, subject_cn, subject_dn, issuer_cn, issuer_dn, sans, domains, not_before, not_after, sha256, serial_number, signature_algorithm, key_algorithm, key_bits, expired, self_signed, weak, issues FROM CertificateInfo WHERE task_id = ?", taskid)
+	if err != nil {
+		gologger.Debug(d.ctx, err)
+		return []structs.CertInfo{}
+	}
+	defer rows.Close()
+	results := []structs.CertInfo{}
+	for rows.Next() {
+		var cert structs.CertInfo
+		var sans, domains, issues string
+		err = rows.Scan(&cert.TaskId, &cert.Host, &cert.Port, &cert.SubjectCN, &cert.SubjectDN, &cert.IssuerCN, &cert.IssuerDN, &sans, &domains, &cert.NotBefore, &cert.NotAfter, &cert.SHA256, &cert.SerialNumber, &cert.SignatureAlgorithm, &cert.KeyAlgorithm, &cert.KeyBits, &cert.Expired, &cert.SelfSigned, &cert.Weak, &issues)
+		if err != nil {
+			gologger.Debug(d.ctx, err)
+			continue
+		}
+		cert.SANs = splitNonEmpty(sans)
+		cert.Domains = splitNonEmpty(domains)
+		cert.Issues = splitNonEmpty(issues)
+		results = append(results, cert)
+	}
+	return results
 }
 
-// 添加漏洞扫描结果
+// 根据taskid汇总证书中发现的域名，作为子域名枚举和网站扫描的候选目标
+func (d *Database) RetrieveCertificateDomains(taskid string) []structs.CertDomain {
+	return portscan.CertificateDomains(d.RetrieveCertificates(taskid))
+}
+
+func splitNonEmpty(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+// 添加漏洞扫描结果，同一任务中重复的漏洞只保留一条，已研判过的漏洞沿用之前的状态与备注
 func (d *Database) AddPocscanResult(result structs.VulnerabilityInfo) bool {
-	insertStmt := "INSERT INTO VulnerabilityInfo (task_id, template_id, vuln_name, protocol, severity, vuln_url, extract, request, response, description, reference, response_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
-	return d.ExecSqlStatement(insertStmt, result.TaskId, result.ID, result.Name, result.Type, result.Severity, result.URL, result.Extract, result.Request, result.Response, result.Description, result.Reference, result.ResponseTime)
+	if result.Fingerprint == "" {
+		result.Fingerprint = webscan.VulnerabilityFingerprint(result)
+	}
+	// 前端并发保存结果，查重与写入需要在同一把锁内完成
+	d.findingLock.Lock()
+	defer d.findingLock.Unlock()
+	var count int
+	d.DB.QueryRow("SELECT COUNT(*) FROM VulnerabilityInfo WHERE task_id = ? AND fingerprint = ?", result.TaskId, result.Fingerprint).Scan(&count)
+	if count > 0 {
+		return true
+	}
+	result.Status = webscan.FindingStatusNew
+	var status, notes string
+	if err := d.DB.QueryRow("SELECT status, notes FROM FindingTriage WHERE fingerprint = ?", result.Fingerprint).Scan(&status, &notes); err == nil {
+		result.Notes = notes
+		// 已修复的漏洞再次出现时重新标记为新漏洞
+		if status == webscan.FindingStatusFixed {
+			d.UpdateFindingStatus(result.Fingerprint, webscan.FindingStatusNew, notes)
+		} else {
+			result.Status = status
+		}
+	}
+	insertStmt := "INSERT INTO VulnerabilityInfo (task_id, template_id, vuln_name, protocol, severity, vuln_url, extract, request, response, description, reference, response_time, fingerprint, status, notes, cve, cwe, cvss_score, cvss_vector, epss_score, epss_percentile, matcher_name) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	return d.ExecSqlStatement(insertStmt, result.TaskId, result.ID, result.Name, result.Type, result.Severity, result.URL, result.Extract, result.Request, result.Response, result.Description, result.Reference, result.ResponseTime, result.Fingerprint, result.Status, result.Notes, result.CVE, result.CWE, result.CVSSScore, result.CVSSVector, result.EPSSScore, result.EPSSPercentile, result.MatcherName)
 }
 
 // 移除某个漏洞
@@ -455,6 +624,83 @@ func (d *Database) RemovePocscanResult(taskid, template_id, vuln_url string) boo
 	return d.ExecSqlStatement(deleteStmt, taskid, template_id, vuln_url)
 }
 
+// 更新漏洞研判状态以及备注，会同步到所有任务中相同指纹的漏洞
+func (d *Database) UpdateFindingStatus(fingerprint, status, notes string) bool {
+	if !webscan.IsValidFindingStatus(status) {
+		gologger.Error(d.ctx, fmt.Sprintf("invalid finding status: %s", status))
+		return false
+	}
+	upsertStmt := "INSERT INTO FindingTriage (fingerprint, status, notes, update_time) VALUES (?, ?, ?, ?) ON CONFLICT(fingerprint) DO UPDATE SET status = excluded.status, notes = excluded.notes, update_time = excluded.update_time"
+	if !d.ExecSqlStatement(upsertStmt, fingerprint, status, notes, time.Now().Format("2006-01-02 15:04:05")) {
+		return false
+	}
+	webscan.SetFindingStatus(fingerprint, status)
+	return d.ExecSqlStatement("UPDATE VulnerabilityInfo SET status = ?, notes = ? WHERE fingerprint = ?", status, notes, fingerprint)
+}
+
+// 记录漏洞的复测结果以及复测时间
+func (d *Database) SaveFindingVerification(v structs.FindingVerification) bool {
+	return d.ExecSqlStatement("UPDATE VulnerabilityInfo SET verify_result = ?, verify_time = ? WHERE fingerprint = ?", v.Result, v.VerifyTime, v.Fingerprint)
+}
+
+// 旧版本的漏洞记录没有指纹，按扫描时相同的方式补全，便于研判与复测
+func (d *Database) backfillFindingFingerprint() {
+	rows, err := d.DB.Query("SELECT rowid, COALESCE(template_id, ''), COALESCE(vuln_url, ''), COALESCE(extract, ''), COALESCE(matcher_name, '') FROM VulnerabilityInfo WHERE fingerprint IS NULL OR fingerprint = ''")
+	if err != nil {
+		return
+	}
+	fingerprints := make(map[int64]string)
+	for rows.Next() {
+		var rowid int64
+		var result structs.VulnerabilityInfo
+		if rows.Scan(&rowid, &result.ID, &result.URL, &result.Extract, &result.MatcherName) == nil {
+			fingerprints[rowid] = webscan.VulnerabilityFingerprint(result)
+		}
+	}
+	rows.Close()
+	for rowid, fingerprint := range fingerprints {
+		d.ExecSqlStatement("UPDATE VulnerabilityInfo SET fingerprint = ? WHERE rowid = ?", fingerprint, rowid)
+	}
+}
+
+// 将研判结果加载到扫描引擎中，误报漏洞不再上报
+func (d *Database) loadFindingTriage() {
+	rows, err := d.DB.Query("SELECT fingerprint, status FROM FindingTriage")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var fingerprint, status string
+		if rows.Scan(&fingerprint, &status) == nil {
+			webscan.SetFindingStatus(fingerprint, status)
+		}
+	}
+}
+
+// 计算所选任务中每个主机以及整体的风险评分
+func (d *Database) RetrieveRiskSummary(taskids []string) structs.RiskSummary {
+	var pocsResults []structs.VulnerabilityInfo
+	for _, taskid := range taskids {
+		pocsResults = append(pocsResults, d.RetrievePocscanResults(taskid)...)
+	}
+	return report.RiskSummary(dedupFindings(pocsResults))
+}
+
+// 多个任务的漏洞按指纹去重，保留首次出现的记录
+func dedupFindings(results []structs.VulnerabilityInfo) []structs.VulnerabilityInfo {
+	seen := make(map[string]bool)
+	var unique []structs.VulnerabilityInfo
+	for _, result := range results {
+		if seen[result.Fingerprint] {
+			continue
+		}
+		seen[result.Fingerprint] = true
+		unique = append(unique, result)
+	}
+	return unique
+}
+
 // 移除某组指纹信息，用于删除探测http的基本状态，后续会由指纹探测重新写入
 func (d *Database) RemoveFingerprintResult(taskid string, link []string) bool {
 	// 如果链接列表为空，直接返回 false 表示操作未执行
@@ -497,7 +743,7 @@ func (d *Database) ExportWebReportWithJson(reportpath string, tasks []structs.Ta
 	result := structs.WebReport{
 		Targets:      strings.Join(targets, "\n"),
 		Fingerprints: fingerprintsResults,
-		POCs:         pocsResults,
+		POCs:         report.SortByRisk(dedupFindings(pocsResults)),
 	}
 	return fileutil.SaveJsonWithFormat(d.ctx, reportpath, result)
 }
@@ -522,7 +768,7 @@ func (d *Database) ExportWebReportWithHtml(reportpath string, taskids []string)
 		fingerprintsResults = append(fingerprintsResults, fingerprintsResult...)
 		pocsResults = append(pocsResults, pocsResult...)
 	}
-	return os.WriteFile(reportpath, []byte(report.GenerateReport(fingerprintsResults, pocsResults)), 0644) == nil
+	return os.WriteFile(reportpath, []byte(report.GenerateReport(fingerprintsResults, report.SortByRisk(dedupFindings(pocsResults)))), 0644) == nil
 }
 
 // 导出EXCEL报告
@@ -553,7 +799,7 @@ func (d *Database) ExportWebReportWithExcel(reportpath string, tasks []structs.T
 	// 添加"Fingerprints"工作表
 	fingerprintsSheet := "Fingerprints"
 	f.NewSheet(fingerprintsSheet)
-	fingerprintsHeader := []string{"URL", "Scheme", "Host", "Port", "StatusCode", "Length", "Title", "Fingerprints", "IsWAF", "WAF", "Detect", "Screenshot"}
+	fingerprintsHeader := []string{"URL", "Scheme", "Host", "Port", "StatusCode", "Length", "Title", "Fingerprints", "IsWAF", "WAF", "Detect", "Screenshot", "Product", "Version", "ExtraInfo", "DeviceType", "OS", "CPE"}
 	for i, header := range fingerprintsHeader {
 		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("%s1", string(rune('A'+i))), header)
 	}
@@ -570,16 +816,22 @@ func (d *Database) ExportWebReportWithExcel(reportpath string, tasks []structs.T
 		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("J%d", i+2), result.WAF)
 		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("K%d", i+2), result.Detect)
 		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("L%d", i+2), result.Screenshot)
+		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("M%d", i+2), result.Product)
+		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("N%d", i+2), result.Version)
+		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("O%d", i+2), result.ExtraInfo)
+		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("P%d", i+2), result.DeviceType)
+		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("Q%d", i+2), result.OS)
+		f.SetCellValue(fingerprintsSheet, fmt.Sprintf("R%d", i+2), result.CPE)
 	}
 
 	// 添加"POCs"工作表
 	pocsSheet := "POCs"
 	f.NewSheet(pocsSheet)
-	pocsHeader := []string{"ID", "Name", "Description", "Reference", "Type", "Severity", "URL", "Request", "Response", "ResponseTime", "Extract"}
+	pocsHeader := []string{"ID", "Name", "Description", "Reference", "Type", "Severity", "URL", "Request", "Response", "ResponseTime", "Extract", "Status", "Notes", "CVE", "CWE", "CVSS", "CVSSVector", "EPSS", "RiskScore"}
 	for i, header := range pocsHeader {
 		f.SetCellValue(pocsSheet, fmt.Sprintf("%s1", string(rune('A'+i))), header)
 	}
-	for i, result := range pocsResults {
+	for i, result := range report.SortByRisk(dedupFindings(pocsResults)) {
 		f.SetCellValue(pocsSheet, fmt.Sprintf("A%d", i+2), result.ID)
 		f.SetCellValue(pocsSheet, fmt.Sprintf("B%d", i+2), result.Name)
 		f.SetCellValue(pocsSheet, fmt.Sprintf("C%d", i+2), result.Description)
@@ -591,6 +843,14 @@ func (d *Database) ExportWebReportWithExcel(reportpath string, tasks []structs.T
 		f.SetCellValue(pocsSheet, fmt.Sprintf("I%d", i+2), result.Response)
 		f.SetCellValue(pocsSheet, fmt.Sprintf("J%d", i+2), result.ResponseTime)
 		f.SetCellValue(pocsSheet, fmt.Sprintf("K%d", i+2), result.Extract)
+		f.SetCellValue(pocsSheet, fmt.Sprintf("L%d", i+2), result.Status)
+		f.SetCellValue(pocsSheet, fmt.Sprintf("M%d", i+2), result.Notes)
+		f.SetCellValue(pocsSheet, fmt.Sprintf("N%d", i+2), result.CVE)
+		f.SetCellValue(pocsSheet, fmt.Sprintf("O%d", i+2), result.CWE)
+		f.SetCellValue(pocsSheet, fmt.Sprintf("P%d", i+2), result.CVSSScore)
+		f.SetCellValue(pocsSheet, fmt.Sprintf("Q%d", i+2), result.CVSSVector)
+		f.SetCellValue(pocsSheet, fmt.Sprintf("R%d", i+2), result.EPSSScore)
+		f.SetCellValue(pocsSheet, fmt.Sprintf("S%d", i+2), result.RiskScore)
 	}
 
 	// 保存Excel文件
@@ -0,0 +1,726 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"slack-wails/core/jsfind"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"slack-wails/lib/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultQueryLimit   = 1000
+	defaultQueryTimeout = 60
+	queryBatchSize      = 200 // 每批推送到前端的行数
+	sensitiveSampleRows = 20  // 敏感字段检测时每张表抽样的行数
+)
+
+// 返回结果集的只读语句，导出时可以重新执行，WITH 语句还需要检查是否修改数据
+var readOnlyStatementPrefixes = []string{"select", "show", "with", "desc", "describe", "explain", "pragma", "values"}
+
+// 存储过程可能返回结果集，但重新执行会产生副作用，不允许导出
+var procedureStatementPrefixes = []string{"exec", "execute", "call", "sp_"}
+
+// PostgreSQL 的 WITH 子句中可以包含 DELETE ... RETURNING 等修改数据的语句，包含这些关键字时不视为只读
+var dataModifyingKeywords = regexp.MustCompile(`(?i)\b(insert|update|delete|merge|truncate|drop|alter|create)\b`)
+
+// 字段名匹配的敏感信息类型
+var sensitiveColumnNames = []struct {
+	Type    string
+	Pattern *regexp.Regexp
+}{
+	{"password", regexp.MustCompile(`(?i)^pass$|passw|pwd|secret|token|salt|密码`)},
+	{"phone", regexp.MustCompile(`(?i)phone|mobile|(^|_)tel($|_)|手机|电话`)},
+	{"idcard", regexp.MustCompile(`(?i)id_?card|id_?number|identity|sfz|身份证`)},
+	{"email", regexp.MustCompile(`(?i)e_?mail|邮箱`)},
+}
+
+// 样本数据匹配的敏感信息类型，手机号、身份证和邮箱复用 jsfind 的规则，密码匹配常见的哈希格式
+var sensitiveValuePatterns = []struct {
+	Type    string
+	Pattern *regexp.Regexp
+}{
+	{"phone", jsfind.Phone},
+	{"idcard", jsfind.IDCard},
+	{"email", jsfind.Email},
+	{"password", regexp.MustCompile(`^(\$2[aby]\$\d{2}\$.{53}|[0-9a-fA-F]{32}|[0-9a-fA-F]{40}|[0-9a-fA-F]{64})$`)},
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// ExecuteQuery 在当前连接上执行语句，所有数据库的结果都按批次通过 dbQueryRows 事件推送，返回值只包含列名和统计信息，
+// 最多返回 Limit 行并记录到查询历史
+func (d *Database) ExecuteQuery(queryId string, option structs.QueryOption) structs.QueryResult {
+	start := time.Now()
+	result, err := d.executeQuery(queryId, option)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Elapsed = time.Since(start).Milliseconds()
+	rows := result.RowsCount
+	if result.Affected > 0 {
+		rows = int(result.Affected)
+	}
+	d.addQueryHistory(option, rows, result.Elapsed, result.Error)
+	return result
+}
+
+func (d *Database) executeQuery(queryId string, option structs.QueryOption) (result structs.QueryResult, err error) {
+	if d.Connection == nil {
+		return result, errors.New("未连接数据库")
+	}
+	ctx, cancel := queryContext(option.Timeout)
+	defer cancel()
+	if option.Limit <= 0 {
+		option.Limit = defaultQueryLimit
+	}
+	stream := &rowStream{d: d, queryId: queryId, limit: option.Limit}
+	// MongoDB、Redis 等一次返回全部结果，同样按 Limit 截断后分批推送
+	if d.Connection.Scheme == "mongodb" || util.ArrayContains(d.Connection.Scheme, directSchemes) {
+		if d.Connection.Scheme == "mongodb" {
+			result, err = d.runMongoCommand(ctx, option)
+		} else {
+			result, err = d.runDirectQuery(ctx, option)
+		}
+		for _, row := range result.Rows {
+			if !stream.add(row) {
+				result.Truncated = true
+				break
+			}
+		}
+		stream.flush()
+		result.Rows, result.RowsCount = nil, stream.count
+		return result, err
+	}
+	conn, closeConn, err := d.openQueryer(ctx, option.Database)
+	if err != nil {
+		return result, err
+	}
+	defer closeConn()
+	if !isQueryStatement(option.Query) {
+		res, err := conn.ExecContext(ctx, option.Query)
+		if err != nil {
+			return result, err
+		}
+		result.Affected, _ = res.RowsAffected()
+		return result, nil
+	}
+	rows, err := conn.QueryContext(ctx, option.Query)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	result.Columns, result.Truncated, err = scanRows(rows, option.Limit, func(row []interface{}) error {
+		stream.add(row)
+		return nil
+	})
+	stream.flush()
+	result.RowsCount = stream.count
+	return result, err
+}
+
+// 查询结果按批次推送到前端，最多推送 limit 行
+type rowStream struct {
+	d       *Database
+	queryId string
+	limit   int
+	batch   [][]interface{}
+	count   int
+}
+
+// add 达到 limit 后返回 false，不再推送
+func (s *rowStream) add(row []interface{}) bool {
+	if s.count >= s.limit {
+		return false
+	}
+	s.count++
+	if s.batch = append(s.batch, row); len(s.batch) == queryBatchSize {
+		s.flush()
+	}
+	return true
+}
+
+func (s *rowStream) flush() {
+	if len(s.batch) > 0 {
+		runtime.EventsEmit(s.d.ctx, "dbQueryRows", structs.QueryRows{QueryId: s.queryId, Rows: s.batch})
+		s.batch = nil
+	}
+}
+
+func queryContext(timeout int) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = defaultQueryTimeout
+	}
+	return context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+}
+
+// 返回结果集的语句，其余语句使用 Exec 执行并返回影响的行数
+func isQueryStatement(query string) bool {
+	return hasStatementPrefix(query, readOnlyStatementPrefixes) || hasStatementPrefix(query, procedureStatementPrefixes)
+}
+
+func isReadOnlyStatement(query string) bool {
+	if hasStatementPrefix(query, []string{"with"}) && dataModifyingKeywords.MatchString(query) {
+		return false
+	}
+	return hasStatementPrefix(query, readOnlyStatementPrefixes)
+}
+
+func hasStatementPrefix(query string, prefixes []string) bool {
+	query = strings.ToLower(strings.TrimLeft(query, " \t\r\n("))
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(query, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 返回执行语句的连接，database 不为空时先切换数据库，postgres 和 kingbase 需要单独连接目标数据库
+func (d *Database) openQueryer(ctx context.Context, database string) (queryer, func(), error) {
+	info := d.Connection
+	if info.Scheme == "postgres" || info.Scheme == "kingbase" {
+		if database == "" && info.Scheme == "postgres" {
+			database = "postgres"
+		}
+		db, err := sql.Open("postgres", postgresDSN(*info, database))
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, func() { db.Close() }, nil
+	}
+	if d.OtherDatabase == nil {
+		return nil, nil, errors.New("未连接数据库")
+	}
+	conn, err := d.OtherDatabase.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if database != "" {
+		var use string
+		switch info.Scheme {
+		case "mysql":
+			use = "USE " + quoteIdent("`", "`", database)
+		case "mssql":
+			use = "USE " + quoteIdent("[", "]", database)
+		case "oracle":
+			use = "ALTER SESSION SET CURRENT_SCHEMA = " + quoteIdent(`"`, `"`, database)
+		case "dm":
+			use = "SET SCHEMA " + quoteIdent(`"`, `"`, database)
+		}
+		// sqlite 的表名已经带有数据库名，不需要切换
+		if use != "" {
+			if _, err = conn.ExecContext(ctx, use); err != nil {
+				conn.Close()
+				return nil, nil, err
+			}
+		}
+	}
+	return conn, func() { conn.Close() }, nil
+}
+
+func quoteIdent(open, close, name string) string {
+	return open + strings.ReplaceAll(name, close, close+close) + close
+}
+
+// 读取结果集，limit 小于等于 0 时不限制行数，返回列名和结果是否被截断
+func scanRows(rows *sql.Rows, limit int, handle func(row []interface{}) error) ([]string, bool, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, false, err
+	}
+	count := 0
+	for rows.Next() {
+		if limit > 0 && count >= limit {
+			return columns, true, nil
+		}
+		values := make([]interface{}, len(columns))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		if err = rows.Scan(values...); err != nil {
+			return columns, false, err
+		}
+		row := make([]interface{}, len(columns))
+		for i, v := range values {
+			row[i] = normalizeValue(*(v.(*interface{})))
+		}
+		if err = handle(row); err != nil {
+			return columns, false, err
+		}
+		count++
+	}
+	return columns, false, rows.Err()
+}
+
+// 转换为前端和导出文件可以直接展示的值，[]byte 默认会被编码为 base64
+func normalizeValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case []byte:
+		return string(value)
+	case time.Time:
+		return value.Format("2006-01-02 15:04:05")
+	case primitive.ObjectID:
+		return value.Hex()
+	case bson.D, bson.A, bson.M:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	}
+	return v
+}
+
+// MongoDB 执行 JSON 格式的数据库命令，例如 {"find": "users", "limit": 10}
+func (d *Database) runMongoCommand(ctx context.Context, option structs.QueryOption) (result structs.QueryResult, err error) {
+	if d.MongoClient == nil {
+		return result, errors.New("未连接数据库")
+	}
+	var command bson.D
+	if err = bson.UnmarshalExtJSON([]byte(option.Query), false, &command); err != nil {
+		return result, fmt.Errorf("命令必须为 JSON 格式: %v", err)
+	}
+	database := option.Database
+	if database == "" {
+		database = "admin"
+	}
+	var doc bson.M
+	if err = d.MongoClient.Database(database).RunCommand(ctx, command).Decode(&doc); err != nil {
+		return result, err
+	}
+	data, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return result, err
+	}
+	result.Columns = []string{"result"}
+	result.Rows = [][]interface{}{{string(data)}}
+	return result, nil
+}
+
+// FetchTablePage 分页获取表数据，page 从 1 开始，RowsCount 为表的总行数
+func (d *Database) FetchTablePage(database, table string, page, pageSize int) structs.RowData {
+	if d.Connection == nil {
+		return structs.RowData{}
+	}
+	ctx, cancel := queryContext(0)
+	defer cancel()
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	data, err := d.fetchPage(ctx, database, table, pageSize, max(page-1, 0)*pageSize)
+	if err != nil {
+		gologger.Debug(d.ctx, fmt.Sprintf("[%s] 查询表数据失败: %v", d.Connection.Scheme, err))
+		return data
+	}
+	if util.ArrayContains(d.Connection.Scheme, directSchemes) {
+		// 总数已经在 fetchPage 中获取
+		return data
+	}
+	if d.Connection.Scheme == "mongodb" {
+		count, _ := d.MongoClient.Database(database).Collection(table).CountDocuments(ctx, bson.D{})
+		data.RowsCount = int(count)
+		return data
+	}
+	conn, closeConn, err := d.openQueryer(ctx, database)
+	if err != nil {
+		return data
+	}
+	defer closeConn()
+	rows, err := conn.QueryContext(ctx, "SELECT COUNT(*) FROM "+qualifiedTable(d.Connection.Scheme, database, table))
+	if err != nil {
+		gologger.Debug(d.ctx, fmt.Sprintf("[%s] 获取总行数失败: %v", d.Connection.Scheme, err))
+		return data
+	}
+	defer rows.Close()
+	if rows.Next() {
+		rows.Scan(&data.RowsCount)
+	}
+	return data
+}
+
+func (d *Database) fetchPage(ctx context.Context, database, table string, limit, offset int) (structs.RowData, error) {
+	scheme := d.Connection.Scheme
+	if scheme == "mongodb" {
+		return d.fetchMongoPage(ctx, database, table, limit, offset)
+	}
+	if util.ArrayContains(scheme, directSchemes) {
+		return d.fetchDirectPage(ctx, database, table, limit, offset)
+	}
+	conn, closeConn, err := d.openQueryer(ctx, database)
+	if err != nil {
+		return structs.RowData{}, err
+	}
+	defer closeConn()
+	rows, err := conn.QueryContext(ctx, pageQuery(scheme, qualifiedTable(scheme, database, table), limit, offset))
+	if err != nil {
+		return structs.RowData{}, err
+	}
+	defer rows.Close()
+	var data structs.RowData
+	data.Columns, _, err = scanRows(rows, limit, func(row []interface{}) error {
+		data.Rows = append(data.Rows, row)
+		return nil
+	})
+	return data, err
+}
+
+// postgres 和 kingbase 的连接已经切换到目标数据库，只需要表名
+func qualifiedTable(scheme, database, table string) string {
+	switch scheme {
+	case "mysql":
+		return quoteIdent("`", "`", database) + "." + quoteIdent("`", "`", table)
+	case "mssql":
+		return quoteIdent("[", "]", database) + ".dbo." + quoteIdent("[", "]", table)
+	case "oracle", "dm", "sqlite":
+		return quoteIdent(`"`, `"`, database) + "." + quoteIdent(`"`, `"`, table)
+	}
+	return quoteIdent(`"`, `"`, table)
+}
+
+// Oracle 的 OFFSET FETCH 需要 12c 及以上版本
+func pageQuery(scheme, table string, limit, offset int) string {
+	switch scheme {
+	case "mssql":
+		return fmt.Sprintf("SELECT * FROM %s ORDER BY (SELECT NULL) OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", table, offset, limit)
+	case "oracle":
+		return fmt.Sprintf("SELECT * FROM %s OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", table, offset, limit)
+	}
+	return fmt.Sprintf("SELECT * FROM %s LIMIT %d OFFSET %d", table, limit, offset)
+}
+
+// 文档的字段可能不同，列为所有文档字段的并集
+func (d *Database) fetchMongoPage(ctx context.Context, database, collection string, limit, offset int) (structs.RowData, error) {
+	var data structs.RowData
+	if d.MongoClient == nil {
+		return data, errors.New("未连接数据库")
+	}
+	cursor, err := d.MongoClient.Database(database).Collection(collection).Find(ctx, bson.D{}, options.Find().SetSkip(int64(offset)).SetLimit(int64(limit)))
+	if err != nil {
+		return data, err
+	}
+	var docs []bson.D
+	if err = cursor.All(ctx, &docs); err != nil {
+		return data, err
+	}
+	index := make(map[string]int)
+	for _, doc := range docs {
+		for _, e := range doc {
+			if _, ok := index[e.Key]; !ok {
+				index[e.Key] = len(data.Columns)
+				data.Columns = append(data.Columns, e.Key)
+			}
+		}
+	}
+	for _, doc := range docs {
+		row := make([]interface{}, len(data.Columns))
+		for _, e := range doc {
+			row[index[e.Key]] = normalizeValue(e.Value)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	return data, nil
+}
+
+// ExportQueryResult 重新执行只读查询并将全部结果导出为 csv、json 或 xlsx，不受 Limit 限制
+func (d *Database) ExportQueryResult(filepath, format string, option structs.QueryOption) bool {
+	err := d.exportQueryResult(filepath, format, option)
+	if err != nil {
+		gologger.Error(d.ctx, fmt.Sprintf("[database] 导出查询结果失败: %v", err))
+		return false
+	}
+	return true
+}
+
+func (d *Database) exportQueryResult(filepath, format string, option structs.QueryOption) error {
+	if d.Connection == nil {
+		return errors.New("未连接数据库")
+	}
+	if d.Connection.Scheme != "mongodb" && !isReadOnlyStatement(option.Query) {
+		return errors.New("只能导出只读查询语句的结果，存储过程和修改数据的 WITH 语句不会重新执行")
+	}
+	writer, err := newRowWriter(filepath, format)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := queryContext(option.Timeout)
+	defer cancel()
+	if d.Connection.Scheme == "mongodb" {
+		result, err := d.runMongoCommand(ctx, option)
+		if err != nil {
+			writer.Close()
+			return err
+		}
+		writer.WriteHeader(result.Columns)
+		for _, row := range result.Rows {
+			writer.WriteRow(row)
+		}
+		return writer.Close()
+	}
+	conn, closeConn, err := d.openQueryer(ctx, option.Database)
+	if err != nil {
+		writer.Close()
+		return err
+	}
+	defer closeConn()
+	rows, err := conn.QueryContext(ctx, option.Query)
+	if err != nil {
+		writer.Close()
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err == nil {
+		err = writer.WriteHeader(columns)
+	}
+	if err == nil {
+		_, _, err = scanRows(rows, 0, writer.WriteRow)
+	}
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// 逐行写入导出文件，避免大结果集占用过多内存
+type rowWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(row []interface{}) error
+	Close() error
+}
+
+func newRowWriter(filepath, format string) (rowWriter, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		file, err := os.Create(filepath)
+		if err != nil {
+			return nil, err
+		}
+		// 写入 BOM 以便 Excel 正确识别中文
+		file.WriteString("\xEF\xBB\xBF")
+		return &csvRowWriter{file: file, writer: csv.NewWriter(file)}, nil
+	case "json":
+		file, err := os.Create(filepath)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonRowWriter{file: file}, nil
+	case "xlsx":
+		f := excelize.NewFile()
+		sw, err := f.NewStreamWriter("Sheet1")
+		if err != nil {
+			return nil, err
+		}
+		return &xlsxRowWriter{file: f, writer: sw, path: filepath}, nil
+	}
+	return nil, fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+type csvRowWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func (w *csvRowWriter) WriteHeader(columns []string) error {
+	return w.writer.Write(columns)
+}
+
+func (w *csvRowWriter) WriteRow(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, v := range row {
+		if v != nil {
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvRowWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// 导出为对象数组，键为列名
+type jsonRowWriter struct {
+	file    *os.File
+	columns []string
+	count   int
+}
+
+func (w *jsonRowWriter) WriteHeader(columns []string) error {
+	w.columns = columns
+	_, err := w.file.WriteString("[")
+	return err
+}
+
+func (w *jsonRowWriter) WriteRow(row []interface{}) error {
+	object := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		object[w.columns[i]] = v
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	if w.count > 0 {
+		w.file.WriteString(",")
+	}
+	w.count++
+	_, err = w.file.Write(append([]byte("\n  "), data...))
+	return err
+}
+
+func (w *jsonRowWriter) Close() error {
+	w.file.WriteString("\n]\n")
+	return w.file.Close()
+}
+
+type xlsxRowWriter struct {
+	file   *excelize.File
+	writer *excelize.StreamWriter
+	path   string
+	row    int
+}
+
+func (w *xlsxRowWriter) WriteHeader(columns []string) error {
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	return w.WriteRow(header)
+}
+
+func (w *xlsxRowWriter) WriteRow(row []interface{}) error {
+	w.row++
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	return w.writer.SetRow(cell, row)
+}
+
+func (w *xlsxRowWriter) Close() error {
+	defer w.file.Close()
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	return w.file.SaveAs(w.path)
+}
+
+// FindSensitiveColumns 检查字段名并抽样检查数据，找出疑似包含手机号、身份证、邮箱和密码的字段，
+// tables 为数据库名到表名的映射
+func (d *Database) FindSensitiveColumns(tables map[string][]string) []structs.SensitiveColumn {
+	var columns []structs.SensitiveColumn
+	if d.Connection == nil {
+		return columns
+	}
+	for database, names := range tables {
+		for _, table := range names {
+			ctx, cancel := queryContext(0)
+			data, err := d.fetchPage(ctx, database, table, sensitiveSampleRows, 0)
+			cancel()
+			if err != nil {
+				gologger.Debug(d.ctx, fmt.Sprintf("[%s] 抽样表 %s.%s 失败: %v", d.Connection.Scheme, database, table, err))
+				continue
+			}
+			for i, column := range data.Columns {
+				values := make([]string, 0, len(data.Rows))
+				for _, row := range data.Rows {
+					if row[i] != nil {
+						values = append(values, fmt.Sprint(row[i]))
+					}
+				}
+				if found, ok := sensitiveColumn(column, values); ok {
+					found.Database, found.Table = database, table
+					columns = append(columns, found)
+				}
+			}
+		}
+	}
+	gologger.Info(d.ctx, fmt.Sprintf("[database] 发现 %d 个疑似敏感字段", len(columns)))
+	return columns
+}
+
+// 优先根据样本数据判断类型，样本不匹配时根据字段名判断
+func sensitiveColumn(column string, values []string) (structs.SensitiveColumn, bool) {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		for _, p := range sensitiveValuePatterns {
+			if p.Pattern.MatchString(value) {
+				return structs.SensitiveColumn{Column: column, Type: p.Type, Source: "value", Sample: value}, true
+			}
+		}
+	}
+	for _, p := range sensitiveColumnNames {
+		if p.Pattern.MatchString(column) {
+			found := structs.SensitiveColumn{Column: column, Type: p.Type, Source: "name"}
+			if len(values) > 0 {
+				found.Sample = values[0]
+			}
+			return found, true
+		}
+	}
+	return structs.SensitiveColumn{}, false
+}
+
+func (d *Database) addQueryHistory(option structs.QueryOption, rows int, elapsed int64, errMsg string) {
+	if d.Connection == nil || strings.TrimSpace(option.Query) == "" {
+		return
+	}
+	d.ExecSqlStatement("INSERT INTO dbQueryHistory (scheme, host, database, query, rows, elapsed, error, create_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		d.Connection.Scheme, net.JoinHostPort(d.Connection.Host, strconv.Itoa(d.Connection.Port)), option.Database, option.Query, rows, elapsed, errMsg, time.Now().Format("2006-01-02 15:04:05"))
+}
+
+// RetrieveQueryHistory 按时间倒序返回查询历史，limit 小于等于 0 时返回全部
+func (d *Database) RetrieveQueryHistory(limit int) []structs.QueryHistory {
+	var history []structs.QueryHistory
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := d.DB.Query("SELECT id, scheme, host, database, query, rows, elapsed, error, create_time FROM dbQueryHistory ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		gologger.Debug(d.ctx, err)
+		return history
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var h structs.QueryHistory
+		if err = rows.Scan(&h.Id, &h.Scheme, &h.Host, &h.Database, &h.Query, &h.Rows, &h.Elapsed, &h.Error, &h.Time); err != nil {
+			continue
+		}
+		history = append(history, h)
+	}
+	return history
+}
+
+func (d *Database) RemoveQueryHistory(id int) bool {
+	return d.ExecSqlStatement("DELETE FROM dbQueryHistory WHERE id = ?", id)
+}
+
+func (d *Database) ClearQueryHistory() bool {
+	return d.ExecSqlStatement("DELETE FROM dbQueryHistory")
+}
@@ -0,0 +1,83 @@
+package services
+
+import "testing"
+
+func TestIsQueryStatement(t *testing.T) {
+	for _, tc := range []struct {
+		sql      string
+		query    bool
+		readOnly bool
+	}{
+		{"SELECT * FROM users", true, true},
+		{"  (select 1)", true, true},
+		{"\nWITH t AS (SELECT 1) SELECT * FROM t", true, true},
+		{"WITH d AS (DELETE FROM logs RETURNING *) SELECT * FROM d", true, false},
+		{"show databases", true, true},
+		{"EXEC sp_helpdb", true, false},
+		{"call refresh_stats()", true, false},
+		{"sp_who", true, false},
+		{"UPDATE users SET name = 'a'", false, false},
+		{"delete from users", false, false},
+	} {
+		if got := isQueryStatement(tc.sql); got != tc.query {
+			t.Errorf("isQueryStatement(%q) = %v", tc.sql, got)
+		}
+		if got := isReadOnlyStatement(tc.sql); got != tc.readOnly {
+			t.Errorf("isReadOnlyStatement(%q) = %v", tc.sql, got)
+		}
+	}
+}
+
+func TestQualifiedTable(t *testing.T) {
+	for _, tc := range []struct {
+		scheme, database, table, want string
+	}{
+		{"mysql", "app", "user`s", "`app`.`user``s`"},
+		{"mssql", "app", "users", "[app].dbo.[users]"},
+		{"oracle", "SCOTT", "EMP", `"SCOTT"."EMP"`},
+		{"sqlite", "main", `a"b`, `"main"."a""b"`},
+		{"postgres", "app", "users", `"users"`},
+	} {
+		if got := qualifiedTable(tc.scheme, tc.database, tc.table); got != tc.want {
+			t.Errorf("qualifiedTable(%s) = %s, want %s", tc.scheme, got, tc.want)
+		}
+	}
+}
+
+func TestPageQuery(t *testing.T) {
+	for _, tc := range []struct {
+		scheme, want string
+	}{
+		{"mssql", "SELECT * FROM t ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{"oracle", "SELECT * FROM t OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{"mysql", "SELECT * FROM t LIMIT 10 OFFSET 20"},
+		{"postgres", "SELECT * FROM t LIMIT 10 OFFSET 20"},
+	} {
+		if got := pageQuery(tc.scheme, "t", 10, 20); got != tc.want {
+			t.Errorf("pageQuery(%s) = %s", tc.scheme, got)
+		}
+	}
+}
+
+func TestSensitiveColumn(t *testing.T) {
+	for _, tc := range []struct {
+		column string
+		values []string
+		typ    string
+		source string
+	}{
+		// 样本数据优先于字段名
+		{"remark", []string{"13812345678"}, "phone", "value"},
+		{"contact", []string{"admin@example.com"}, "email", "value"},
+		{"hash", []string{"5f4dcc3b5aa765d61d8327deb882cf99"}, "password", "value"},
+		{"user_pwd", []string{"plain"}, "password", "name"},
+		{"id_card", nil, "idcard", "name"},
+		{"mobile", []string{"unknown"}, "phone", "name"},
+		{"nickname", []string{"alice"}, "", ""},
+	} {
+		found, ok := sensitiveColumn(tc.column, tc.values)
+		if ok != (tc.typ != "") || found.Type != tc.typ || found.Source != tc.source {
+			t.Errorf("sensitiveColumn(%s) = %+v, %v", tc.column, found, ok)
+		}
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"slack-wails/core/exp/finereport"
 	"slack-wails/core/exp/hikvision"
 	"slack-wails/core/exp/nacos"
+	"slack-wails/core/exp/redis"
+	"slack-wails/core/portscan"
 	"slack-wails/lib/clients"
+	"slack-wails/lib/structs"
 	"strings"
 )
 
@@ -65,3 +68,21 @@ func (e *Exp) CameraCrackPassword(url, username string, passwordList []string) s
 func (e *Exp) FineReportChannelDeserialize(url, cmd string, proxy clients.Proxy) string {
 	return finereport.ChannelDeserialize(url, cmd, clients.NewRestyClientWithProxy(nil, true, proxy))
 }
+
+// redis
+
+// RedisExploit 通过 CONFIG SET 写文件或主从复制加载模块，option.Confirm 为假时只返回将要执行的操作
+func (e *Exp) RedisExploit(option structs.RedisExpOption) string {
+	return redis.Exploit(option)
+}
+
+// smb
+
+// SmbShareAccess 枚举账号可以访问的共享，checkWrite 为真时在非管理共享中创建并删除临时文件检查写权限
+func (e *Exp) SmbShareAccess(host, user, pass string, checkWrite bool) string {
+	shares, err := portscan.SmbShares(host, user, pass, checkWrite)
+	if err != nil {
+		return err.Error()
+	}
+	return strings.Join(shares, "\n")
+}
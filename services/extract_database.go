@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
 	"slack-wails/core/portscan"
 	"slack-wails/lib/gologger"
 	"slack-wails/lib/structs"
 	"slack-wails/lib/util"
+	"strconv"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -29,6 +31,10 @@ func (d *Database) GetAllDatabaseConnections() (dcs []structs.DatabaseConnection
 		if err != nil {
 			return
 		}
+		// 凭据库锁定时保留密文，修改连接时会原样保存
+		if plain, err := openSecret(password); err == nil {
+			password = plain
+		}
 		dcs = append(dcs, structs.DatabaseConnection{
 			Nanoid:   nanoid,
 			Scheme:   scheme,
@@ -43,6 +49,12 @@ func (d *Database) GetAllDatabaseConnections() (dcs []structs.DatabaseConnection
 }
 
 func (d *Database) AddConnection(info structs.DatabaseConnection) bool {
+	password, err := sealSecret(info.Password)
+	if err != nil {
+		d.showErrorMessage(err.Error())
+		return false
+	}
+	info.Password = password
 	return d.ExecSqlStatement("INSERT INTO dbManager (nanoid, scheme, host, port, username, password, notes) VALUES (?, ?, ?, ?, ?, ?, ?)", info.Nanoid, info.Scheme, info.Host, info.Port, info.Username, info.Password, info.Notes)
 }
 
@@ -51,6 +63,12 @@ func (d *Database) RemoveConnection(nanoid string) bool {
 }
 
 func (d *Database) UpdateConnection(info structs.DatabaseConnection) bool {
+	password, err := sealSecret(info.Password)
+	if err != nil {
+		d.showErrorMessage(err.Error())
+		return false
+	}
+	info.Password = password
 	return d.ExecSqlStatement("UPDATE dbManager SET scheme = ?, host = ?, port = ? , username = ?, password = ?, notes = ? WHERE nanoid = ?", info.Scheme, info.Host, info.Port, info.Username, info.Password, info.Notes, info.Nanoid)
 }
 
@@ -60,7 +78,11 @@ func (d *Database) ConnectDatabase(info structs.DatabaseConnection) bool {
 		err            error
 		dataSourceName string
 	)
-	host := fmt.Sprintf("%s:%d", info.Host, info.Port)
+	host := net.JoinHostPort(info.Host, strconv.Itoa(info.Port))
+	if info.Password, err = openSecret(info.Password); err != nil {
+		d.showErrorMessage(err.Error())
+		return false
+	}
 
 	// Determine connection based on the scheme
 	switch info.Scheme {
@@ -84,8 +106,28 @@ func (d *Database) ConnectDatabase(info structs.DatabaseConnection) bool {
 				d.showErrorMessage(err.Error())
 				return false
 			}
+			d.Connection = &info
 			return true
 		}
+	case "sqlite":
+		// sqlite 的 Host 为数据库文件路径
+		dataSourceName, err = sqliteDSN(info.Host)
+		if err == nil {
+			flag, err = sqlPing("sqlite3", dataSourceName)
+		}
+	case "kingbase":
+		dataSourceName = postgresDSN(info, "")
+		flag, err = sqlPing("postgres", dataSourceName)
+	case "dm":
+		dataSourceName = dmDSN(info)
+		flag, err = sqlPing("dm", dataSourceName)
+	case "redis", "clickhouse", "elasticsearch":
+		if err = checkDirectConnection(info); err != nil {
+			d.showErrorMessage(err.Error())
+			return false
+		}
+		d.Connection = &info
+		return true
 	default:
 		return false
 	}
@@ -97,11 +139,12 @@ func (d *Database) ConnectDatabase(info structs.DatabaseConnection) bool {
 	}
 
 	// Connect to other databases
-	d.OtherDatabase, err = sql.Open(info.Scheme, dataSourceName)
+	d.OtherDatabase, err = sql.Open(sqlDriverName(info.Scheme), dataSourceName)
 	if err != nil {
 		d.showErrorMessage("认证正确，但无法连接数据库")
 		return false
 	}
+	d.Connection = &info
 	return true
 }
 
@@ -161,6 +204,10 @@ func (d *Database) FetchDatabaseinfoFromMongodb() map[string][]string {
 }
 
 func (d *Database) DisconnectDatabase(scheme string) bool {
+	d.Connection = nil
+	if util.ArrayContains(scheme, directSchemes) {
+		return true
+	}
 	if scheme == "mongodb" {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -0,0 +1,594 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slack-wails/core/portscan"
+	"slack-wails/lib/clients"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	redisMaxKeys  = 1000 // 每个 db 最多列出的键数量
+	redisScanSize = 200
+)
+
+// 不使用 database/sql 连接池的数据库，每次操作时重新连接
+var directSchemes = []string{"redis", "clickhouse", "elasticsearch"}
+
+// 检查认证信息是否可以连接
+func checkDirectConnection(info structs.DatabaseConnection) error {
+	host := net.JoinHostPort(info.Host, strconv.Itoa(info.Port))
+	switch info.Scheme {
+	case "redis":
+		client, err := dialRedis(info)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		_, err = client.Do("PING")
+		return err
+	case "clickhouse":
+		_, err := portscan.ClickhouseConn(host, info.Username, info.Password)
+		return err
+	case "elasticsearch":
+		_, err := portscan.ElasticsearchConn(host, info.Username, info.Password)
+		return err
+	}
+	return fmt.Errorf("不支持的数据库类型: %s", info.Scheme)
+}
+
+// 在当前连接上执行命令，Redis 为命令行，ClickHouse 为 SQL，Elasticsearch 第一行为请求方法和路径，其余行为请求体
+func (d *Database) runDirectQuery(ctx context.Context, option structs.QueryOption) (structs.QueryResult, error) {
+	switch d.Connection.Scheme {
+	case "redis":
+		return runRedisCommand(*d.Connection, option)
+	case "clickhouse":
+		return clickhouseQuery(ctx, *d.Connection, option.Database, option.Query, option.Limit)
+	}
+	return elasticsearchCommand(ctx, *d.Connection, option.Query)
+}
+
+// 分页获取键、表或索引的数据，同时返回总数
+func (d *Database) fetchDirectPage(ctx context.Context, database, table string, limit, offset int) (structs.RowData, error) {
+	switch d.Connection.Scheme {
+	case "redis":
+		return fetchRedisKey(*d.Connection, database, table, limit, offset)
+	case "clickhouse":
+		return fetchClickhousePage(ctx, *d.Connection, database, table, limit, offset)
+	}
+	return fetchElasticsearchPage(ctx, *d.Connection, table, limit, offset)
+}
+
+// redis
+
+// 连接 Redis，设置了用户名时使用 Redis 6 的 ACL 认证，旧版本不支持时只使用密码认证
+func dialRedis(info structs.DatabaseConnection) (*portscan.RedisClient, error) {
+	client, err := portscan.NewRedisClient(net.JoinHostPort(info.Host, strconv.Itoa(info.Port)), "", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if info.Password == "" {
+		return client, nil
+	}
+	if info.Username != "" {
+		_, err = client.Do("AUTH", info.Username, info.Password)
+		if err == nil || !strings.Contains(err.Error(), "wrong number of arguments") {
+			return client, closeOnError(client, err)
+		}
+	}
+	_, err = client.Do("AUTH", info.Password)
+	return client, closeOnError(client, err)
+}
+
+func closeOnError(client *portscan.RedisClient, err error) error {
+	if err != nil {
+		client.Close()
+	}
+	return err
+}
+
+// 切换到 db0 这样的数据库名称对应的库
+func selectRedisDB(client *portscan.RedisClient, database string) error {
+	if database == "" {
+		return nil
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(database, "db"))
+	if err != nil {
+		return fmt.Errorf("无效的数据库名称: %s", database)
+	}
+	_, err = client.Do("SELECT", strconv.Itoa(index))
+	return err
+}
+
+// FetchDatabaseinfoFromRedis 根据 INFO keyspace 列出有数据的库，键名作为表名，每个库最多列出 1000 个键
+func (d *Database) FetchDatabaseinfoFromRedis() map[string][]string {
+	var databasesInfo = make(map[string][]string)
+	if d.Connection == nil {
+		return databasesInfo
+	}
+	client, err := dialRedis(*d.Connection)
+	if err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[redis] 连接失败: %v", err))
+		return databasesInfo
+	}
+	defer client.Close()
+	keyspace, err := client.Do("INFO", "keyspace")
+	if err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[redis] 查询数据库失败: %v", err))
+		return databasesInfo
+	}
+	for _, line := range strings.Split(keyspace, "\n") {
+		database, _, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found || !strings.HasPrefix(database, "db") {
+			continue
+		}
+		client.SetTimeout(30 * time.Second)
+		keys, err := scanRedisKeys(client, database)
+		if err != nil {
+			gologger.Warning(d.ctx, fmt.Sprintf("[redis] %s 获取键名失败: %v", database, err))
+			continue
+		}
+		databasesInfo[database] = keys
+	}
+	return databasesInfo
+}
+
+func scanRedisKeys(client *portscan.RedisClient, database string) ([]string, error) {
+	if err := selectRedisDB(client, database); err != nil {
+		return nil, err
+	}
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := client.DoList("SCAN", cursor, "COUNT", strconv.Itoa(redisScanSize))
+		if err != nil {
+			return keys, err
+		}
+		if len(reply) == 0 {
+			break
+		}
+		cursor = reply[0]
+		keys = append(keys, reply[1:]...)
+		if cursor == "0" || len(keys) >= redisMaxKeys {
+			break
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// FetchTableInfoFromRedis 根据键的类型返回前几个元素和元素总数
+func (d *Database) FetchTableInfoFromRedis(dbName, key string) structs.RowData {
+	if d.Connection == nil {
+		return structs.RowData{}
+	}
+	data, err := fetchRedisKey(*d.Connection, dbName, key, 3, 0)
+	if err != nil {
+		gologger.Debug(d.ctx, fmt.Sprintf("[redis] 查询键 %s 失败: %v", key, err))
+	}
+	return data
+}
+
+func fetchRedisKey(info structs.DatabaseConnection, database, key string, limit, offset int) (data structs.RowData, err error) {
+	client, err := dialRedis(info)
+	if err != nil {
+		return data, err
+	}
+	defer client.Close()
+	if err = selectRedisDB(client, database); err != nil {
+		return data, err
+	}
+	keyType, err := client.Do("TYPE", key)
+	if err != nil {
+		return data, err
+	}
+	stop := strconv.Itoa(offset + limit - 1)
+	var items []string
+	var count string
+	switch keyType {
+	case "string":
+		value, err := client.Do("GET", key)
+		data.Columns = []string{"key", "value"}
+		data.Rows = [][]interface{}{{key, value}}
+		data.RowsCount = 1
+		return data, err
+	case "list":
+		data.Columns = []string{"index", "value"}
+		if items, err = client.DoList("LRANGE", key, strconv.Itoa(offset), stop); err != nil {
+			return data, err
+		}
+		for i, item := range items {
+			data.Rows = append(data.Rows, []interface{}{offset + i, item})
+		}
+		count, err = client.Do("LLEN", key)
+	case "hash":
+		data.Columns = []string{"field", "value"}
+		if items, err = scanRedisMembers(client, "HSCAN", key, 2*(offset+limit)); err != nil {
+			return data, err
+		}
+		items = redisPage(items, offset, limit, 2)
+		for i := 0; i+1 < len(items); i += 2 {
+			data.Rows = append(data.Rows, []interface{}{items[i], items[i+1]})
+		}
+		count, err = client.Do("HLEN", key)
+	case "set":
+		data.Columns = []string{"member"}
+		if items, err = scanRedisMembers(client, "SSCAN", key, offset+limit); err != nil {
+			return data, err
+		}
+		for _, item := range redisPage(items, offset, limit, 1) {
+			data.Rows = append(data.Rows, []interface{}{item})
+		}
+		count, err = client.Do("SCARD", key)
+	case "zset":
+		data.Columns = []string{"member", "score"}
+		if items, err = client.DoList("ZRANGE", key, strconv.Itoa(offset), stop, "WITHSCORES"); err != nil {
+			return data, err
+		}
+		for i := 0; i+1 < len(items); i += 2 {
+			data.Rows = append(data.Rows, []interface{}{items[i], items[i+1]})
+		}
+		count, err = client.Do("ZCARD", key)
+	case "none":
+		return data, fmt.Errorf("键 %s 不存在", key)
+	default:
+		// stream 等类型只展示类型，可以在控制台中使用对应命令查看
+		data.Columns = []string{"key", "type"}
+		data.Rows = [][]interface{}{{key, keyType}}
+		data.RowsCount = 1
+		return data, nil
+	}
+	data.RowsCount, _ = strconv.Atoi(count)
+	return data, err
+}
+
+// 截取 SCAN 结果中的一页，width 为每个元素占用的项数，hash 为字段和值两项
+func redisPage(items []string, offset, limit, width int) []string {
+	start := min(offset*width, len(items))
+	end := min((offset+limit)*width, len(items))
+	return items[start:end]
+}
+
+// 使用 HSCAN 或 SSCAN 遍历元素，获取到 need 个后停止
+func scanRedisMembers(client *portscan.RedisClient, command, key string, need int) ([]string, error) {
+	var items []string
+	cursor := "0"
+	for {
+		reply, err := client.DoList(command, key, cursor, "COUNT", strconv.Itoa(redisScanSize))
+		if err != nil {
+			return items, err
+		}
+		if len(reply) == 0 {
+			return items, nil
+		}
+		cursor = reply[0]
+		items = append(items, reply[1:]...)
+		if cursor == "0" || len(items) >= need {
+			return items, nil
+		}
+	}
+}
+
+// 命令按空白分割为参数，数组结果每个元素一行
+func runRedisCommand(info structs.DatabaseConnection, option structs.QueryOption) (result structs.QueryResult, err error) {
+	args := strings.Fields(option.Query)
+	if len(args) == 0 {
+		return result, errors.New("命令不能为空")
+	}
+	client, err := dialRedis(info)
+	if err != nil {
+		return result, err
+	}
+	defer client.Close()
+	timeout := option.Timeout
+	if timeout <= 0 {
+		timeout = defaultQueryTimeout
+	}
+	client.SetTimeout(time.Duration(timeout) * time.Second)
+	if err = selectRedisDB(client, option.Database); err != nil {
+		return result, err
+	}
+	items, err := client.DoList(args...)
+	if err != nil {
+		return result, err
+	}
+	result.Columns = []string{"result"}
+	for _, item := range items {
+		result.Rows = append(result.Rows, []interface{}{item})
+	}
+	return result, nil
+}
+
+// clickhouse
+
+// 使用 HTTP 接口访问 ClickHouse 和 Elasticsearch，user 为空时不携带认证信息
+func httpDatabaseRequest(ctx context.Context, info structs.DatabaseConnection) (*resty.Request, string, error) {
+	base, err := portscan.HttpBaseURL(net.JoinHostPort(info.Host, strconv.Itoa(info.Port)))
+	if err != nil {
+		return nil, "", err
+	}
+	req := clients.NewRestyClient(nil, false).R().SetContext(ctx)
+	if info.Username != "" {
+		req.SetBasicAuth(info.Username, info.Password)
+	}
+	return req, base, nil
+}
+
+func checkHttpResponse(resp *resty.Response) error {
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return fmt.Errorf("status code %d: %s", resp.StatusCode(), strings.TrimSpace(string(resp.Body())))
+	}
+	return nil
+}
+
+// 通过 HTTP 接口执行 SQL，没有指定 FORMAT 时结果为 JSONCompact 格式并转换为表格，其他格式作为一列返回。
+// limit 大于 0 时由服务端在超过 limit 行后停止读取，多返回一行用于判断结果是否被截断
+func clickhouseQuery(ctx context.Context, info structs.DatabaseConnection, database, query string, limit int) (result structs.QueryResult, err error) {
+	params := url.Values{"default_format": {"JSONCompact"}}
+	if database != "" {
+		params.Set("database", database)
+	}
+	if limit > 0 {
+		params.Set("max_result_rows", strconv.Itoa(limit+1))
+		params.Set("result_overflow_mode", "break")
+	}
+	resp, err := clickhousePost(ctx, info, params, query)
+	// readonly=1 的账号不能修改设置，去掉限制后重试，由调用方截断结果
+	if err != nil && limit > 0 && strings.Contains(err.Error(), "readonly mode") {
+		params.Del("max_result_rows")
+		params.Del("result_overflow_mode")
+		resp, err = clickhousePost(ctx, info, params, query)
+	}
+	if err != nil {
+		return result, err
+	}
+	body := resp.Body()
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return result, nil
+	}
+	var compact struct {
+		Meta []struct {
+			Name string `json:"name"`
+		} `json:"meta"`
+		Data [][]interface{} `json:"data"`
+	}
+	if err = json.Unmarshal(body, &compact); err != nil || compact.Meta == nil {
+		result.Columns = []string{"result"}
+		result.Rows = [][]interface{}{{string(body)}}
+		return result, nil
+	}
+	for _, column := range compact.Meta {
+		result.Columns = append(result.Columns, column.Name)
+	}
+	result.Rows = compact.Data
+	return result, nil
+}
+
+func clickhousePost(ctx context.Context, info structs.DatabaseConnection, params url.Values, query string) (*resty.Response, error) {
+	req, base, err := httpDatabaseRequest(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := req.SetBody(query).Post(base + "/?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return resp, checkHttpResponse(resp)
+}
+
+func clickhouseIdent(name string) string {
+	return quoteIdent("`", "`", name)
+}
+
+// FetchDatabaseinfoFromClickhouse 获取除系统库以外的数据库和表
+func (d *Database) FetchDatabaseinfoFromClickhouse() map[string][]string {
+	var databasesInfo = make(map[string][]string)
+	if d.Connection == nil {
+		return databasesInfo
+	}
+	ctx, cancel := queryContext(0)
+	defer cancel()
+	result, err := clickhouseQuery(ctx, *d.Connection, "", "SELECT database, name FROM system.tables WHERE database NOT IN ('system', 'INFORMATION_SCHEMA', 'information_schema') ORDER BY database, name", 0)
+	if err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[clickhouse] 查询数据库失败: %v", err))
+		return databasesInfo
+	}
+	for _, row := range result.Rows {
+		if len(row) == 2 {
+			database := fmt.Sprint(row[0])
+			databasesInfo[database] = append(databasesInfo[database], fmt.Sprint(row[1]))
+		}
+	}
+	return databasesInfo
+}
+
+func (d *Database) FetchTableInfoFromClickhouse(dbName, tableName string) structs.RowData {
+	if d.Connection == nil {
+		return structs.RowData{}
+	}
+	ctx, cancel := queryContext(0)
+	defer cancel()
+	data, err := fetchClickhousePage(ctx, *d.Connection, dbName, tableName, 3, 0)
+	if err != nil {
+		gologger.Debug(d.ctx, fmt.Sprintf("[clickhouse] 查询表数据失败: %v", err))
+	}
+	return data
+}
+
+func fetchClickhousePage(ctx context.Context, info structs.DatabaseConnection, database, table string, limit, offset int) (structs.RowData, error) {
+	qualified := clickhouseIdent(database) + "." + clickhouseIdent(table)
+	result, err := clickhouseQuery(ctx, info, database, fmt.Sprintf("SELECT * FROM %s LIMIT %d OFFSET %d", qualified, limit, offset), 0)
+	if err != nil {
+		return structs.RowData{}, err
+	}
+	data := structs.RowData{Columns: result.Columns, Rows: result.Rows}
+	count, err := clickhouseQuery(ctx, info, database, "SELECT count() FROM "+qualified, 0)
+	if err == nil && len(count.Rows) > 0 && len(count.Rows[0]) > 0 {
+		// UInt64 在 JSON 中默认以字符串返回
+		data.RowsCount, _ = strconv.Atoi(fmt.Sprint(count.Rows[0][0]))
+	}
+	return data, nil
+}
+
+// elasticsearch
+
+// 所有索引放在同一个库中
+const elasticsearchDatabase = "indices"
+
+// FetchDatabaseinfoFromElasticsearch 列出所有索引，索引名作为表名
+func (d *Database) FetchDatabaseinfoFromElasticsearch() map[string][]string {
+	var databasesInfo = make(map[string][]string)
+	if d.Connection == nil {
+		return databasesInfo
+	}
+	ctx, cancel := queryContext(0)
+	defer cancel()
+	req, base, err := httpDatabaseRequest(ctx, *d.Connection)
+	if err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[elasticsearch] 连接失败: %v", err))
+		return databasesInfo
+	}
+	resp, err := req.Get(base + "/_cat/indices?format=json&h=index&s=index")
+	if err == nil {
+		err = checkHttpResponse(resp)
+	}
+	if err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[elasticsearch] 查询索引失败: %v", err))
+		return databasesInfo
+	}
+	var indices []struct {
+		Index string `json:"index"`
+	}
+	if err = json.Unmarshal(resp.Body(), &indices); err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[elasticsearch] 解析索引失败: %v", err))
+		return databasesInfo
+	}
+	names := make([]string, 0, len(indices))
+	for _, index := range indices {
+		names = append(names, index.Index)
+	}
+	databasesInfo[elasticsearchDatabase] = names
+	return databasesInfo
+}
+
+func (d *Database) FetchTableInfoFromElasticsearch(dbName, index string) structs.RowData {
+	if d.Connection == nil {
+		return structs.RowData{}
+	}
+	ctx, cancel := queryContext(0)
+	defer cancel()
+	data, err := fetchElasticsearchPage(ctx, *d.Connection, index, 3, 0)
+	if err != nil {
+		gologger.Debug(d.ctx, fmt.Sprintf("[elasticsearch] 查询索引 %s 失败: %v", index, err))
+	}
+	return data
+}
+
+// 文档的字段可能不同，列为 _id 和所有文档字段的并集，嵌套的字段转换为 JSON
+func fetchElasticsearchPage(ctx context.Context, info structs.DatabaseConnection, index string, limit, offset int) (data structs.RowData, err error) {
+	req, base, err := httpDatabaseRequest(ctx, info)
+	if err != nil {
+		return data, err
+	}
+	resp, err := req.SetHeader("Content-Type", "application/json").
+		SetBody(map[string]interface{}{"from": offset, "size": limit, "track_total_hits": true}).
+		Post(base + "/" + url.PathEscape(index) + "/_search")
+	if err != nil {
+		return data, err
+	}
+	if err = checkHttpResponse(resp); err != nil {
+		return data, err
+	}
+	var search struct {
+		Hits struct {
+			Total json.RawMessage `json:"total"`
+			Hits  []struct {
+				ID     string                 `json:"_id"`
+				Source map[string]interface{} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err = json.Unmarshal(resp.Body(), &search); err != nil {
+		return data, err
+	}
+	data.RowsCount = elasticsearchTotal(search.Hits.Total)
+	data.Columns = []string{"_id"}
+	columnIndex := map[string]int{"_id": 0}
+	for _, hit := range search.Hits.Hits {
+		fields := make([]string, 0, len(hit.Source))
+		for field := range hit.Source {
+			if _, ok := columnIndex[field]; !ok {
+				fields = append(fields, field)
+			}
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			columnIndex[field] = len(data.Columns)
+			data.Columns = append(data.Columns, field)
+		}
+	}
+	for _, hit := range search.Hits.Hits {
+		row := make([]interface{}, len(data.Columns))
+		row[0] = hit.ID
+		for field, value := range hit.Source {
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				encoded, _ := json.Marshal(value)
+				value = string(encoded)
+			}
+			row[columnIndex[field]] = value
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	return data, nil
+}
+
+// 7.x 之前 hits.total 为数字，之后为 {"value": n}
+func elasticsearchTotal(raw json.RawMessage) int {
+	var total struct {
+		Value int `json:"value"`
+	}
+	if json.Unmarshal(raw, &total) != nil {
+		json.Unmarshal(raw, &total.Value)
+	}
+	return total.Value
+}
+
+// 第一行为请求方法和路径，例如 GET /_cluster/health，其余行为 JSON 请求体
+func elasticsearchCommand(ctx context.Context, info structs.DatabaseConnection, query string) (result structs.QueryResult, err error) {
+	line, body, _ := strings.Cut(strings.TrimSpace(query), "\n")
+	method, path, found := strings.Cut(strings.TrimSpace(line), " ")
+	if !found {
+		return result, errors.New("第一行需要为请求方法和路径，例如 GET /_cluster/health")
+	}
+	req, base, err := httpDatabaseRequest(ctx, info)
+	if err != nil {
+		return result, err
+	}
+	if body = strings.TrimSpace(body); body != "" {
+		req.SetHeader("Content-Type", "application/json").SetBody(body)
+	}
+	resp, err := req.Execute(strings.ToUpper(method), base+"/"+strings.TrimLeft(strings.TrimSpace(path), "/"))
+	if err != nil {
+		return result, err
+	}
+	if err = checkHttpResponse(resp); err != nil {
+		return result, err
+	}
+	result.Columns = []string{"result"}
+	result.Rows = [][]interface{}{{string(resp.Body())}}
+	return result, nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedisPage(t *testing.T) {
+	hash := []string{"f1", "v1", "f2", "v2", "f3", "v3"}
+	set := []string{"a", "b", "c"}
+	for _, tc := range []struct {
+		items                []string
+		offset, limit, width int
+		want                 string
+	}{
+		{hash, 0, 2, 2, "f1 v1 f2 v2"},
+		{hash, 1, 5, 2, "f2 v2 f3 v3"},
+		{hash, 3, 2, 2, ""},
+		{set, 1, 1, 1, "b"},
+		{set, 2, 10, 1, "c"},
+		{set, 5, 10, 1, ""},
+	} {
+		if got := strings.Join(redisPage(tc.items, tc.offset, tc.limit, tc.width), " "); got != tc.want {
+			t.Errorf("redisPage(%v, %d, %d) = %q, want %q", tc.items, tc.offset, tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestElasticsearchTotal(t *testing.T) {
+	for raw, want := range map[string]int{
+		`42`:                                  42,
+		`{"value": 10000, "relation": "gte"}`: 10000,
+		`null`:                                0,
+	} {
+		if got := elasticsearchTotal(json.RawMessage(raw)); got != want {
+			t.Errorf("elasticsearchTotal(%s) = %d, want %d", raw, got, want)
+		}
+	}
+}
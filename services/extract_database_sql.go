@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"slack-wails/lib/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 数据库类型对应的 database/sql 驱动名称，Kingbase 兼容 PostgreSQL 协议
+var sqlDrivers = map[string]string{
+	"sqlite":   "sqlite3",
+	"kingbase": "postgres",
+}
+
+func sqlDriverName(scheme string) string {
+	if driver, ok := sqlDrivers[scheme]; ok {
+		return driver
+	}
+	return scheme
+}
+
+// 检查能否连接数据库，驱动未注册时返回错误。达梦驱动 gitee.com/chunanyong/dm 没有发布到 Go 模块代理，需要单独引入后才会注册 dm 驱动
+func sqlPing(driver, dataSourceName string) (bool, error) {
+	if !util.ArrayContains(driver, sql.Drivers()) {
+		return false, fmt.Errorf("未引入 %s 数据库驱动", driver)
+	}
+	db, err := sql.Open(driver, dataSourceName)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = db.PingContext(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sqlite
+
+// 只打开已存在的文件，避免路径错误时创建空数据库
+func sqliteDSN(path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return "file:" + path + "?mode=rw", nil
+}
+
+// FetchDatabaseinfoFromSqlite 获取 main 以及 ATTACH 的数据库中的表和视图
+func (d *Database) FetchDatabaseinfoFromSqlite() map[string][]string {
+	var databasesInfo = make(map[string][]string)
+	if d.OtherDatabase == nil {
+		return databasesInfo
+	}
+	rows, err := d.OtherDatabase.Query("PRAGMA database_list")
+	if err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[sqlite] 查询数据库失败: %v", err))
+		return databasesInfo
+	}
+	var databases []string
+	for rows.Next() {
+		var seq int
+		var name, file string
+		if err = rows.Scan(&seq, &name, &file); err == nil && name != "temp" {
+			databases = append(databases, name)
+		}
+	}
+	rows.Close()
+	for _, database := range databases {
+		tables, err := queryStrings(d.OtherDatabase, fmt.Sprintf("SELECT name FROM %s.sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%%' ORDER BY name", quoteIdent(`"`, `"`, database)))
+		if err != nil {
+			gologger.Warning(d.ctx, fmt.Sprintf("[sqlite] 数据库[%s]查询表名失败: %v", database, err))
+			continue
+		}
+		databasesInfo[database] = tables
+	}
+	return databasesInfo
+}
+
+func (d *Database) FetchTableInfoFromSqlite(dbName, tableName string) structs.RowData {
+	return d.FetchTablePage(dbName, tableName, 1, 3)
+}
+
+// kingbase
+
+// 没有指定数据库时使用 ServerName，都为空时连接 Kingbase 默认创建的 test 库
+func postgresDSN(info structs.DatabaseConnection, database string) string {
+	if database == "" {
+		database = info.ServerName
+	}
+	if database == "" {
+		database = "test"
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", info.Host, info.Port, info.Username, info.Password, database)
+}
+
+// FetchDatabaseinfoFromKingbase 按 postgres 的方式获取各个数据库 public 模式下的表
+func (d *Database) FetchDatabaseinfoFromKingbase() map[string][]string {
+	if d.Connection == nil {
+		return nil
+	}
+	return d.FetchDatabaseInfoFromPostgres(*d.Connection)
+}
+
+func (d *Database) FetchTableInfoFromKingbase(dbName, tableName string) structs.RowData {
+	return d.FetchTablePage(dbName, tableName, 1, 3)
+}
+
+// dm
+
+var dmSystemSchemas = []string{"SYS", "SYSAUDITOR", "SYSSSO", "CTISYS", "SYSJOB"}
+
+func dmDSN(info structs.DatabaseConnection) string {
+	u := url.URL{Scheme: "dm", User: url.UserPassword(info.Username, info.Password), Host: net.JoinHostPort(info.Host, strconv.Itoa(info.Port))}
+	return u.String()
+}
+
+// FetchDatabaseinfoFromDm 以模式作为数据库，获取除系统模式以外的表
+func (d *Database) FetchDatabaseinfoFromDm() map[string][]string {
+	var databasesInfo = make(map[string][]string)
+	if d.OtherDatabase == nil {
+		return databasesInfo
+	}
+	rows, err := d.OtherDatabase.Query("SELECT OWNER, TABLE_NAME FROM ALL_TABLES ORDER BY OWNER, TABLE_NAME")
+	if err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[dm] 查询表名失败: %v", err))
+		return databasesInfo
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var owner, table string
+		if err = rows.Scan(&owner, &table); err != nil {
+			continue
+		}
+		if util.ArrayContains(owner, dmSystemSchemas) {
+			continue
+		}
+		databasesInfo[owner] = append(databasesInfo[owner], table)
+	}
+	return databasesInfo
+}
+
+func (d *Database) FetchTableInfoFromDm(schemaName, tableName string) structs.RowData {
+	return d.FetchTablePage(schemaName, tableName, 1, 3)
+}
+
+func queryStrings(db *sql.DB, query string) ([]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return values, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// 暴破模块名称对应的数据库类型
+var crackDatabaseSchemes = map[string]string{
+	"mysql":         "mysql",
+	"mssql":         "mssql",
+	"oracle":        "oracle",
+	"postgresql":    "postgres",
+	"mongodb":       "mongodb",
+	"redis":         "redis",
+	"clickhouse":    "clickhouse",
+	"elasticsearch": "elasticsearch",
+}
+
+// AddConnectionFromFinding 将端口暴破发现的弱口令或未授权访问保存为数据库连接，
+// 弱口令的 Extract 为 user/pass，只需要密码的协议为 pass
+func (d *Database) AddConnectionFromFinding(nanoid string, finding structs.VulnerabilityInfo) bool {
+	name, kind, _ := strings.Cut(finding.ID, " ")
+	scheme, ok := crackDatabaseSchemes[name]
+	if !ok || kind != "weak password" && kind != "unauthorized" {
+		return false
+	}
+	host, portStr, err := net.SplitHostPort(finding.URL)
+	if err != nil {
+		return false
+	}
+	port, _ := strconv.Atoi(portStr)
+	info := structs.DatabaseConnection{
+		Nanoid: nanoid,
+		Scheme: scheme,
+		Host:   host,
+		Port:   port,
+		Notes:  finding.Name,
+	}
+	if kind == "weak password" {
+		if scheme == "redis" {
+			info.Password = finding.Extract
+		} else {
+			info.Username, info.Password, _ = strings.Cut(finding.Extract, "/")
+		}
+	}
+	return d.AddConnection(info)
+}
@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	rt "runtime"
+	"slack-wails/core/webscan"
 	"slack-wails/lib/fileutil"
 	"slack-wails/lib/gologger"
 	"slack-wails/lib/structs"
@@ -23,23 +24,33 @@ import (
 )
 
 var Userdict = map[string][]string{
-	"ftp":        {"ftp", "admin", "www", "web", "root", "db", "wwwroot", "data"},
-	"mysql":      {"root", "mysql"},
-	"mssql":      {"sa", "sql"},
-	"smb":        {"administrator", "admin", "guest"},
-	"rdp":        {"administrator", "admin", "guest"},
-	"postgresql": {"postgres", "admin"},
-	"ssh":        {"root", "admin"},
-	"mongodb":    {"root", "admin"},
-	"oracle":     {"sys", "system", "admin", "test", "web", "orcl"},
-	"ldap":       {"admin", "administrator", "root"},
-	"socks5":     {"admin", "administrator"},
-	"mqtt":       {"admin", "administrator"},
-	"vnc":        {"admin", "administrator", "root"},
-	"telnet":     {"root", "admin"},
-	"activemq":   {"admin", "root", "activemq", "system", "user"},
-	"kafka":      {"admin", "kafka", "root", "test"},
-	"rsync":      {"rsync", "root", "admin", "backup"},
+	"ftp":           {"ftp", "admin", "www", "web", "root", "db", "wwwroot", "data"},
+	"mysql":         {"root", "mysql"},
+	"mssql":         {"sa", "sql"},
+	"smb":           {"administrator", "admin", "guest"},
+	"rdp":           {"administrator", "admin", "guest"},
+	"postgresql":    {"postgres", "admin"},
+	"ssh":           {"root", "admin"},
+	"mongodb":       {"root", "admin"},
+	"oracle":        {"sys", "system", "admin", "test", "web", "orcl"},
+	"ldap":          {"admin", "administrator", "root"},
+	"socks5":        {"admin", "administrator"},
+	"mqtt":          {"admin", "administrator"},
+	"vnc":           {"admin", "administrator", "root"},
+	"telnet":        {"root", "admin"},
+	"activemq":      {"admin", "root", "activemq", "system", "user"},
+	"kafka":         {"admin", "kafka", "root", "test"},
+	"rsync":         {"rsync", "root", "admin", "backup"},
+	"smtp":          {"admin", "root", "postmaster", "test", "webmaster"},
+	"pop3":          {"admin", "root", "postmaster", "test", "webmaster"},
+	"imap":          {"admin", "root", "postmaster", "test", "webmaster"},
+	"winrm":         {"administrator", "admin"},
+	"elasticsearch": {"elastic", "admin", "kibana"},
+	"kibana":        {"elastic", "admin", "kibana"},
+	"rabbitmq":      {"guest", "admin", "rabbitmq"},
+	"neo4j":         {"neo4j", "admin"},
+	"cassandra":     {"cassandra", "admin"},
+	"clickhouse":    {"default", "admin", "root"},
 }
 
 var Passwords = []string{"123456", "admin", "admin123", "root", "", "pass123", "pass@123", "password", "123123", "654321", "111111", "123", "1", "admin@123", "Admin@123", "admin123!@#", "{user}", "{user}1", "{user}111", "{user}123", "{user}@123", "{user}_123", "{user}#123", "{user}@111", "{user}@2019", "{user}@123#4", "P@ssw0rd!", "P@ssw0rd", "Passw0rd", "qwe123", "12345678", "test", "test123", "123qwe", "123qwe!@#", "123456789", "123321", "666666", "a123456.", "123456~a", "123456!a", "000000", "1234567890", "8888888", "!QAZ2wsx", "1qaz2wsx", "abc123", "abc123456", "1qaz@WSX", "a11111", "a12345", "Aa1234", "Aa1234.", "Aa12345", "a123456", "a123123", "Aa123123", "Aa123456", "Aa12345.", "sysadmin", "system", "1qaz!QAZ", "2wsx@WSX", "qwe123!@#", "Aa123456!", "A123456s!", "sa123456", "1q2w3e", "Charge123", "Aa123456789"}
@@ -280,6 +291,9 @@ func (f *File) UpdatePocFile(version string) bool {
 		return false
 	}
 	os.Remove(util.HomeDir() + "/slack/config.zip")
+	// 更新后保持原有模板的禁用状态
+	tm := webscan.NewTemplateManager(defaultFile+"config/pocs", defaultFile+"config/pocs-disabled", defaultFile+"keys")
+	tm.ApplyDisabled(tm.DisabledTemplates())
 	return true
 }
 
@@ -489,20 +503,27 @@ func (f *File) RemoveFile(file string) bool {
 	return os.Remove(file) == nil
 }
 
+// SaveDataToFile 保存前端配置，代理密码和 API 密钥保存到凭据库，config.json 中不包含凭据
 func (f *File) SaveDataToFile(data interface{}) bool {
-	content, _ := json.MarshalIndent(data, "", "  ")
-	if err := os.WriteFile(f.UserHomeDir()+"/slack/config.json", content, 0777); err != nil {
+	content, _ := json.Marshal(data)
+	var config map[string]interface{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return false
+	}
+	if err := writeConfigFile(config); err != nil {
+		gologger.Warning(f.ctx, fmt.Sprintf("[vault] 保存配置失败: %v", err))
 		return false
 	}
 	return true
 }
 
+// ReadLocalStore 读取前端配置并从凭据库填充凭据
 func (f *File) ReadLocalStore() map[string]interface{} {
-	var data map[string]interface{}
-	content, _ := os.ReadFile(f.UserHomeDir() + "/slack/config.json")
-	if err := json.Unmarshal(content, &data); err != nil {
+	data, err := readConfigFile()
+	if err != nil {
 		return nil
 	}
+	loadConfigSecrets(data)
 	return data
 }
 
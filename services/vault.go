@@ -0,0 +1,279 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slack-wails/lib/gologger"
+	"slack-wails/lib/structs"
+	"slack-wails/lib/util"
+	"slack-wails/lib/vault"
+	"sync/atomic"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// config.json 中保存到凭据库的字段，凭据名称为 "<section>.<field>"，文件中只保留空字符串
+var configSecretFields = map[string][]string{
+	"proxy": {"password"},
+	"space": {"fofakey", "hunterkey", "quakekey", "chaos", "bevigil", "zoomeye", "securitytrails", "github"},
+}
+
+// 凭据库锁定时读取的配置不包含凭据，此时保存配置不能把空值当作删除
+var configSecretsHidden atomic.Bool
+
+func configFilePath() string {
+	return util.HomeDir() + "/slack/config.json"
+}
+
+// 打开 ~/slack/config.db 对应的凭据库，其他模块通过 vault.Default 获取
+func openVault(d *Database) {
+	v, err := vault.Open(d.DB, util.HomeDir()+"/slack/vault.key")
+	if v != nil {
+		vault.SetDefault(v)
+	}
+	d.vaultErr = err
+}
+
+// 加密保存到 config.db 的凭据，凭据库没有打开时保持原样
+func sealSecret(value string) (string, error) {
+	v := vault.Default()
+	if v == nil {
+		return value, nil
+	}
+	return v.Encrypt(value)
+}
+
+// 解密凭据，没有加密的旧数据原样返回
+func openSecret(value string) (string, error) {
+	v := vault.Default()
+	if v == nil {
+		return value, nil
+	}
+	return v.Decrypt(value)
+}
+
+// VaultStatus 返回凭据库是否锁定以及数据密钥保存的位置
+func (d *Database) VaultStatus() structs.VaultStatus {
+	var status structs.VaultStatus
+	if d.vaultErr != nil {
+		status.Error = d.vaultErr.Error()
+	}
+	v := vault.Default()
+	if v == nil {
+		status.Locked = true
+		return status
+	}
+	status.Locked = v.Locked()
+	status.MasterPassword = v.HasMasterPassword()
+	status.Keyring = v.KeyringName()
+	return status
+}
+
+// UnlockVault 使用主密码解锁凭据库，解锁后迁移遗留的明文凭据
+func (d *Database) UnlockVault(password string) bool {
+	v := vault.Default()
+	if v == nil {
+		return false
+	}
+	if err := v.Unlock(password); err != nil {
+		d.showErrorMessage(err.Error())
+		return false
+	}
+	d.vaultErr = nil
+	d.migrateSecrets()
+	runtime.EventsEmit(d.ctx, "vaultUnlocked")
+	return true
+}
+
+// LockVault 清除内存中的数据密钥，只在设置了主密码时有效
+func (d *Database) LockVault() bool {
+	v := vault.Default()
+	if v == nil || !v.HasMasterPassword() {
+		return false
+	}
+	v.Lock()
+	return true
+}
+
+// SetMasterPassword 设置或修改主密码，password 为空时取消主密码并将数据密钥保存到系统密钥环
+func (d *Database) SetMasterPassword(password string) bool {
+	v := vault.Default()
+	if v == nil {
+		return false
+	}
+	if err := v.SetMasterPassword(password); err != nil {
+		d.showErrorMessage(err.Error())
+		return false
+	}
+	return true
+}
+
+// GetSecret 返回指定名称的凭据，例如 space.fofakey，凭据库锁定或不存在时返回空字符串
+func (d *Database) GetSecret(name string) string {
+	v := vault.Default()
+	if v == nil {
+		return ""
+	}
+	value, err := v.Secret(name)
+	if err != nil {
+		gologger.Debug(d.ctx, fmt.Sprintf("[vault] 读取凭据 %s 失败: %v", name, err))
+	}
+	return value
+}
+
+// SetSecret 加密保存凭据，value 为空时删除
+func (d *Database) SetSecret(name, value string) bool {
+	v := vault.Default()
+	if v == nil {
+		return false
+	}
+	if err := v.SetSecret(name, value); err != nil {
+		gologger.Warning(d.ctx, fmt.Sprintf("[vault] 保存凭据 %s 失败: %v", name, err))
+		return false
+	}
+	return true
+}
+
+func (d *Database) ListSecretNames() []string {
+	v := vault.Default()
+	if v == nil {
+		return nil
+	}
+	names, _ := v.SecretNames()
+	return names
+}
+
+// 加密 dbManager 和 agent_pool 中的明文数据，并将 config.json 中的 API 密钥等凭据移到凭据库
+func (d *Database) migrateSecrets() {
+	if v := vault.Default(); v == nil || v.Locked() {
+		return
+	}
+	count := d.migrateColumn("SELECT rowid, password FROM dbManager", "UPDATE dbManager SET password = ? WHERE rowid = ?")
+	count += d.migrateColumn("SELECT rowid, hosts FROM agent_pool", "UPDATE agent_pool SET hosts = ? WHERE rowid = ?")
+	config, err := readConfigFile()
+	if err == nil && hasConfigSecrets(config) {
+		if err = writeConfigFile(config); err != nil {
+			gologger.Warning(d.ctx, fmt.Sprintf("[vault] 迁移 config.json 失败: %v", err))
+		} else {
+			count++
+		}
+	}
+	if count > 0 {
+		gologger.Info(d.ctx, fmt.Sprintf("[vault] 已加密 %d 项明文凭据", count))
+	}
+}
+
+func (d *Database) migrateColumn(query, update string) int {
+	rows, err := d.DB.Query(query)
+	if err != nil {
+		return 0
+	}
+	plain := make(map[int64]string)
+	for rows.Next() {
+		var rowid int64
+		var value string
+		if rows.Scan(&rowid, &value) == nil && value != "" && !vault.IsEncrypted(value) {
+			plain[rowid] = value
+		}
+	}
+	rows.Close()
+	count := 0
+	for rowid, value := range plain {
+		encrypted, err := sealSecret(value)
+		if err == nil && d.ExecSqlStatement(update, encrypted, rowid) {
+			count++
+		}
+	}
+	return count
+}
+
+func readConfigFile() (map[string]interface{}, error) {
+	content, err := os.ReadFile(configFilePath())
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]interface{}
+	if err = json.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// 写入 config.json 前将凭据保存到凭据库，写入的文件中不包含凭据
+func writeConfigFile(config map[string]interface{}) error {
+	secretErr := storeConfigSecrets(config)
+	content, _ := json.MarshalIndent(config, "", "  ")
+	if err := os.WriteFile(configFilePath(), content, 0600); err != nil {
+		return err
+	}
+	return secretErr
+}
+
+func hasConfigSecrets(config map[string]interface{}) bool {
+	for section, fields := range configSecretFields {
+		values, _ := config[section].(map[string]interface{})
+		for _, field := range fields {
+			if value, _ := values[field].(string); value != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 将配置中的凭据移到凭据库并清空。凭据库锁定时无法加密，新填写的凭据以明文保留在 config.json 中，
+// 解锁后由 migrateSecrets 迁移，为空的字段保留凭据库中原有的值
+func storeConfigSecrets(config map[string]interface{}) error {
+	v := vault.Default()
+	if v == nil || v.Locked() {
+		return nil
+	}
+	var lastErr error
+	for section, fields := range configSecretFields {
+		values, ok := config[section].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, field := range fields {
+			value, _ := values[field].(string)
+			if value == "" && configSecretsHidden.Load() {
+				continue
+			}
+			// 保存失败时保留明文，避免丢失凭据
+			if err := v.SetSecret(section+"."+field, value); err != nil {
+				lastErr = err
+				continue
+			}
+			values[field] = ""
+		}
+	}
+	return lastErr
+}
+
+// 从凭据库读取配置中的凭据，凭据库锁定时保持为空
+func loadConfigSecrets(config map[string]interface{}) {
+	v := vault.Default()
+	if v == nil {
+		return
+	}
+	configSecretsHidden.Store(v.Locked())
+	if v.Locked() {
+		return
+	}
+	for section, fields := range configSecretFields {
+		values, ok := config[section].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, field := range fields {
+			// 没有迁移的旧配置中的明文保持不变
+			if value, _ := values[field].(string); value != "" {
+				continue
+			}
+			if secret, err := v.Secret(section + "." + field); err == nil && secret != "" {
+				values[field] = secret
+			}
+		}
+	}
+}